@@ -6,6 +6,70 @@ type Profile struct {
 	SFW *SFW
 }
 
+// Copy writes the results of a query to a file.
+type Copy struct {
+	Query   *SFW
+	Path    string
+	Options []*Option
+}
+
+// CreateTableAs creates a table from the results of a query.
+type CreateTableAs struct {
+	Table *Relation
+	Query *SFW
+}
+
+// CreateIndex builds an index on a column of a table, like
+// CREATE INDEX by_state ON cities (State).
+type CreateIndex struct {
+	Name   string
+	Table  *Relation
+	Column *Attribute
+}
+
+// ShowTables lists the tables in the catalog, and the files in the data
+// directory that can be queried as tables.
+type ShowTables struct{}
+
+// Describe shows the columns of a table, with their types inferred from the
+// values in them, like DESCRIBE cities or SHOW COLUMNS FROM cities.
+type Describe struct {
+	Table *Relation
+}
+
+// ShowCreateTable shows the columns of a table, and the file and format it
+// is read from, as a CREATE TABLE statement.
+type ShowCreateTable struct {
+	Table *Relation
+}
+
+// Insert appends the results of a query to a table.
+type Insert struct {
+	Table *Relation
+	Query *SFW
+}
+
+// Delete removes the rows of a table that match a condition, or every row
+// when there is no condition.
+type Delete struct {
+	Table *Relation
+	Where Condition
+}
+
+// Update sets the value of columns in the rows of a table that match a
+// condition, or in every row when there is no condition.
+type Update struct {
+	Table *Relation
+	Set   []*Assignment
+	Where Condition
+}
+
+// Assignment sets a column to the value of an expression, like name = UPPER(name).
+type Assignment struct {
+	Column *Attribute
+	Value  Expression
+}
+
 type SFW struct {
 	SelList *SelList
 	From    From
@@ -21,13 +85,64 @@ type Attribute struct {
 	Qualifier string
 	Name      string
 	Alias     string
+	// Quoted is set when the name was in double quotes, so it only matches a
+	// column with exactly the same name, rather than ignoring case.
+	// QualifierQuoted is the same for the qualifier.
+	Quoted          bool
+	QualifierQuoted bool
+	// Path is a list of keys into JSON held by the attribute, from either
+	// payload.user.id or payload->'user'->>'id'. PathJSON is set when the last
+	// step was ->, which keeps the result as JSON.
+	Path     []string
+	PathJSON bool
+	// Exclude and Replace modify a * attribute, leaving out some columns, or
+	// replacing them with a computed value.
+	Exclude []*Attribute
+	Replace []*Replacement
+}
+
+// Replacement computes a value in place of a column, named Name.
+type Replacement struct {
+	Expression Expression
+	Name       string
+	Quoted     bool
+}
+
+// Expression is a value computed for each row, an *Attribute, a *Constant or
+// a *FunctionCall.
+type Expression interface{}
+
+// FunctionCall is a scalar function applied to its arguments, like
+// UPPER(name).
+type FunctionCall struct {
+	Name string
+	Args []Expression
 }
 
 type From interface {
 	Tables() []*Relation
 }
+
+// Relation is a table in a FROM clause. It is named, like cities, a quoted
+// file path or glob, like 'logs/*.csv', or a table valued function.
 type Relation struct {
-	Name string
+	Name     string
+	Quoted   bool
+	Path     string
+	Function *TableFunction
+}
+
+// TableFunction is a table valued function in a FROM clause, like
+// read_csv('x.txt', delim='|', header=false).
+type TableFunction struct {
+	Name    string
+	Args    []*Constant
+	Options []*Option
+}
+
+type Option struct {
+	Name  string
+	Value *Constant
 }
 
 func (r *Relation) Tables() []*Relation { return []*Relation{r} }
@@ -45,7 +160,12 @@ type AndCondition struct {
 	LHS Condition
 	RHS Condition
 }
-type InCondition struct{}
+
+// InCondition is true when the column is equal to one of the values.
+type InCondition struct {
+	LHS    *Attribute
+	Values []*Constant
+}
 type EqualColumnCondition struct {
 	Left  *Attribute
 	Right *Attribute
@@ -54,6 +174,15 @@ type EqualCondition struct {
 	LHS *Attribute
 	RHS *Constant
 }
+
+// CompareCondition compares a column with a value, using one of <, <=, > and
+// >=.
+type CompareCondition struct {
+	LHS      *Attribute
+	Operator string
+	RHS      *Constant
+}
+
 type LikeCondition struct {
 	LHS *Attribute
 	RHS string
@@ -80,10 +209,23 @@ type Type string
 const (
 	StringType  Type = "string"
 	IntegerType Type = "integer"
+	FloatType   Type = "float"
+	BooleanType Type = "boolean"
 )
 
 type Constant struct {
 	Type  Type
 	Value interface{}
 	Raw   string
+	// Parameter is set for a placeholder, which has no Type or Value until
+	// a value is bound to it.
+	Parameter *Parameter
+}
+
+// Parameter is a placeholder for a value supplied when a query is run. ?
+// placeholders are numbered from 1 in the order they appear, and $n is
+// number n. A :name placeholder has a Name rather than a Position.
+type Parameter struct {
+	Position int
+	Name     string
 }
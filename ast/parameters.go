@@ -0,0 +1,72 @@
+package ast
+
+// Parameters lists the placeholders in a query, in the order they appear.
+func Parameters(q Query) []*Constant {
+	var result []*Constant
+	switch q := q.(type) {
+	case *SFW:
+		result = sfwParameters(q)
+	case *Profile:
+		result = sfwParameters(q.SFW)
+	case *Copy:
+		result = sfwParameters(q.Query)
+	case *CreateTableAs:
+		result = sfwParameters(q.Query)
+	case *Insert:
+		result = sfwParameters(q.Query)
+	case *Delete:
+		result = conditionParameters(q.Where)
+	case *Update:
+		for _, a := range q.Set {
+			result = append(result, expressionParameters(a.Value)...)
+		}
+		result = append(result, conditionParameters(q.Where)...)
+	}
+	return result
+}
+
+func sfwParameters(q *SFW) []*Constant {
+	var result []*Constant
+	if q.SelList != nil {
+		for _, a := range q.SelList.Attributes {
+			for _, r := range a.Replace {
+				result = append(result, expressionParameters(r.Expression)...)
+			}
+		}
+	}
+	return append(result, conditionParameters(q.Where)...)
+}
+
+func conditionParameters(c Condition) []*Constant {
+	switch c := c.(type) {
+	case *AndCondition:
+		return append(conditionParameters(c.LHS), conditionParameters(c.RHS)...)
+	case *EqualCondition:
+		return expressionParameters(c.RHS)
+	case *CompareCondition:
+		return expressionParameters(c.RHS)
+	case *InCondition:
+		var result []*Constant
+		for _, v := range c.Values {
+			result = append(result, expressionParameters(v)...)
+		}
+		return result
+	}
+	return nil
+}
+
+func expressionParameters(e Expression) []*Constant {
+	switch e := e.(type) {
+	case *Constant:
+		if e.Parameter != nil {
+			return []*Constant{e}
+		}
+	case *FunctionCall:
+		var result []*Constant
+		for _, a := range e.Args {
+			result = append(result, expressionParameters(a)...)
+		}
+		return result
+	}
+	return nil
+}
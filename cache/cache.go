@@ -0,0 +1,377 @@
+// Package cache keeps the results of queries on disk, so running the same
+// query again over the same files reads the result rather than the files.
+//
+// A result is found by a key made from the text of the query, the values of
+// its parameters, and a hash of the contents of each file it reads. Changing
+// any of the files changes the key, so a stale result is never used, and is
+// eventually evicted once the cache grows past its maximum size. The hash of
+// a file is kept with its size and modification time, and the file is only
+// read to hash it again once they change.
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jacobsimpson/mtsql/ast"
+	"github.com/jacobsimpson/mtsql/csvfile"
+	"github.com/jacobsimpson/mtsql/lexer"
+	md "github.com/jacobsimpson/mtsql/metadata"
+	"github.com/jacobsimpson/mtsql/spool"
+)
+
+// version is part of every key, so results written in an older format are
+// never read.
+const version = "mtsql cache 1"
+
+// DefaultMaxSize is how large the entries in a cache may grow to in total,
+// before the least recently used are removed.
+const DefaultMaxSize = 256 << 20
+
+// statsFile holds the number of hits and misses, next to the entries.
+const statsFile = "stats.json"
+
+// filesDir is the directory in a cache holding the fingerprints of the files
+// queries have read.
+const filesDir = "files"
+
+// ErrUncacheable is returned for a query that reads something without a
+// fingerprint, like standard input.
+var ErrUncacheable = errors.New("the query can't be cached")
+
+// Cache is a directory of query results.
+type Cache struct {
+	Dir     string
+	MaxSize int64
+}
+
+// Entry is a cached query result.
+type Entry struct {
+	Query   string
+	Created time.Time
+	Sources []*Fingerprint
+	Columns []*md.Column
+	Rows    [][]string
+}
+
+// Fingerprint identifies the contents of a file a query read.
+type Fingerprint struct {
+	Path    string
+	Size    int64
+	ModTime int64
+	Hash    string
+}
+
+// Stats describes the entries of a cache, and how often they have been used.
+// Schemas counts the schemas of tables kept alongside the results.
+type Stats struct {
+	Entries int
+	Size    int64
+	Hits    int64
+	Misses  int64
+	Schemas int
+}
+
+// New returns the cache kept in dir. The directory is created when the
+// first entry is written.
+func New(dir string) *Cache {
+	return &Cache{Dir: dir, MaxSize: DefaultMaxSize}
+}
+
+// DefaultDir is the directory for the cache of the current user.
+func DefaultDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "mtsql"), nil
+}
+
+// Key fingerprints the files of the relations a query reads, and makes the
+// key for its result. The query should be normalized so that differences
+// that don't change the result, like spacing, don't change the key.
+func (c *Cache) Key(query string, relations []*md.Relation) (string, []*Fingerprint, error) {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\n%s\n", version, query)
+	fingerprints := []*Fingerprint{}
+	for _, r := range relations {
+		if r.Source == "" || r.Source == spool.StdinName {
+			return "", nil, ErrUncacheable
+		}
+		files := []string{r.Source}
+		if r.Type == md.CsvType && csvfile.IsGlob(r.Source) {
+			var err error
+			if files, err = csvfile.Glob(r.Source); err != nil {
+				return "", nil, err
+			}
+		}
+		dialect := ""
+		if r.Dialect != nil {
+			dialect = r.Dialect.String()
+		}
+		fmt.Fprintf(h, "%s %s %s\n", r.Type, r.Source, dialect)
+		for _, file := range files {
+			f, err := c.fingerprint(file)
+			if err != nil {
+				return "", nil, err
+			}
+			fmt.Fprintf(h, "%s %s\n", f.Path, f.Hash)
+			fingerprints = append(fingerprints, f)
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil)), fingerprints, nil
+}
+
+// fingerprint returns the fingerprint of a file. The hash kept from the last
+// time the file was fingerprinted is used while the file has the same size
+// and modification time, and otherwise the file is read and hashed again.
+func (c *Cache) fingerprint(file string) (*Fingerprint, error) {
+	path, err := filepath.Abs(file)
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.Mode().IsRegular() {
+		return nil, ErrUncacheable
+	}
+	sum := sha256.Sum256([]byte(path))
+	kept := filepath.Join(c.Dir, filesDir, hex.EncodeToString(sum[:])+".json")
+	if b, err := ioutil.ReadFile(kept); err == nil {
+		f := &Fingerprint{}
+		if err := json.Unmarshal(b, f); err == nil && f.Path == path && !f.changed() {
+			return f, nil
+		}
+	}
+
+	hashing := time.Now()
+	r, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, r); err != nil {
+		return nil, err
+	}
+	f := &Fingerprint{
+		Path:    path,
+		Size:    info.Size(),
+		ModTime: info.ModTime().UnixNano(),
+		Hash:    hex.EncodeToString(h.Sum(nil)),
+	}
+	// A file modified just before it was hashed could be modified again
+	// without its modification time changing, so its hash isn't kept.
+	if hashing.Sub(info.ModTime()) > time.Second {
+		if b, err := json.Marshal(f); err == nil {
+			writeFile(filepath.Dir(kept), kept, b)
+		}
+	}
+	return f, nil
+}
+
+// changed reports whether a file is no longer the size, or has a different
+// modification time, than when it was fingerprinted.
+func (f *Fingerprint) changed() bool {
+	info, err := os.Stat(f.Path)
+	return err != nil || info.Size() != f.Size || info.ModTime().UnixNano() != f.ModTime
+}
+
+func (c *Cache) path(key string) string {
+	return filepath.Join(c.Dir, key+".json")
+}
+
+// Get returns the entry with the key, or nil if there isn't one, and counts
+// the hit or miss.
+func (c *Cache) Get(key string) (*Entry, error) {
+	b, err := ioutil.ReadFile(c.path(key))
+	if os.IsNotExist(err) {
+		c.count(false)
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	e := &Entry{}
+	if err := json.Unmarshal(b, e); err != nil {
+		// A damaged entry is a miss, and is replaced when the result is put.
+		c.count(false)
+		return nil, nil
+	}
+	// The modification time of an entry is when it was last used, which
+	// decides which entries are evicted first.
+	now := time.Now()
+	os.Chtimes(c.path(key), now, now)
+	c.count(true)
+	return e, nil
+}
+
+// Put writes an entry, unless one of the files it was read from changed while
+// the query ran. Once it is written, the least recently used entries are
+// removed until the cache is under its maximum size.
+func (c *Cache) Put(key string, e *Entry) error {
+	for _, f := range e.Sources {
+		if f.changed() {
+			return nil
+		}
+	}
+	b, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	if err := writeFile(c.Dir, c.path(key), b); err != nil {
+		return err
+	}
+	return c.evict()
+}
+
+// writeFile writes a file in the directory, creating it if need be, through
+// a temporary file that is renamed into place once it is complete.
+func writeFile(dir, path string, b []byte) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	f, err := ioutil.TempFile(dir, ".tmp-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write(b); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
+
+// entries lists the entry files, most recently used first.
+func (c *Cache) entries() ([]os.FileInfo, error) {
+	infos, err := ioutil.ReadDir(c.Dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	result := []os.FileInfo{}
+	for _, info := range infos {
+		if info.Mode().IsRegular() && strings.HasSuffix(info.Name(), ".json") && info.Name() != statsFile {
+			result = append(result, info)
+		}
+	}
+	sort.SliceStable(result, func(a, b int) bool {
+		return result[a].ModTime().After(result[b].ModTime())
+	})
+	return result, nil
+}
+
+func (c *Cache) evict() error {
+	infos, err := c.entries()
+	if err != nil {
+		return err
+	}
+	size := int64(0)
+	for _, info := range infos {
+		size += info.Size()
+		if size > c.MaxSize {
+			if err := os.Remove(filepath.Join(c.Dir, info.Name())); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Stats counts the entries in the cache, and the hits and misses since it was
+// last cleared.
+func (c *Cache) Stats() (*Stats, error) {
+	infos, err := c.entries()
+	if err != nil {
+		return nil, err
+	}
+	s := c.readStats()
+	s.Entries = len(infos)
+	s.Schemas = c.schemas()
+	for _, info := range infos {
+		s.Size += info.Size()
+	}
+	return s, nil
+}
+
+// Clear removes every entry, schema and fingerprint, and resets the hits and
+// misses. It returns the number of entries removed.
+func (c *Cache) Clear() (int, error) {
+	infos, err := c.entries()
+	if err != nil {
+		return 0, err
+	}
+	for _, info := range infos {
+		if err := os.Remove(filepath.Join(c.Dir, info.Name())); err != nil && !os.IsNotExist(err) {
+			return 0, err
+		}
+	}
+	if err := os.Remove(filepath.Join(c.Dir, statsFile)); err != nil && !os.IsNotExist(err) {
+		return 0, err
+	}
+	if err := c.clearSchemas(); err != nil {
+		return 0, err
+	}
+	if err := os.RemoveAll(filepath.Join(c.Dir, filesDir)); err != nil {
+		return 0, err
+	}
+	return len(infos), nil
+}
+
+func (c *Cache) readStats() *Stats {
+	s := &Stats{}
+	if b, err := ioutil.ReadFile(filepath.Join(c.Dir, statsFile)); err == nil {
+		json.Unmarshal(b, s)
+	}
+	return s
+}
+
+// count adds a hit or a miss to the stats. Counting is best effort: a count
+// that can't be written is lost, and two queries finishing at once may only
+// count one.
+func (c *Cache) count(hit bool) {
+	s := c.readStats()
+	if hit {
+		s.Hits++
+	} else {
+		s.Misses++
+	}
+	b, err := json.Marshal(&Stats{Hits: s.Hits, Misses: s.Misses})
+	if err != nil {
+		return
+	}
+	writeFile(c.Dir, filepath.Join(c.Dir, statsFile), b)
+}
+
+// Normalize makes the text of a query for its key. The tokens of the query are
+// separated by single spaces, with comments left out, and the values bound to
+// its parameters follow.
+func Normalize(query string, params []*ast.Constant) string {
+	tokens := []string{}
+	l := lexer.NewFilterWhitespace(strings.NewReader(query))
+	for l.Next() && l.Token().Type != lexer.EOFType {
+		tokens = append(tokens, l.Token().Raw)
+	}
+	for _, p := range params {
+		tokens = append(tokens, fmt.Sprintf("\n%s %#v", p.Type, p.Value))
+	}
+	return strings.Join(tokens, " ")
+}
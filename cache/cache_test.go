@@ -0,0 +1,234 @@
+package cache
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jacobsimpson/mtsql/ast"
+	md "github.com/jacobsimpson/mtsql/metadata"
+	"github.com/jacobsimpson/mtsql/physical"
+	"github.com/jacobsimpson/mtsql/schema"
+	"github.com/stretchr/testify/assert"
+)
+
+// inTempDir runs a test with a cache, and a people.csv table, in a new
+// directory.
+func inTempDir(t *testing.T, test func(c *Cache, people *md.Relation)) {
+	dir, err := ioutil.TempDir("", "mtsql-cache-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	source := filepath.Join(dir, "people.csv")
+	if err := ioutil.WriteFile(source, []byte("name,age\nann,31\nbob,42\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// Hashes are only kept for files that weren't just modified.
+	modified := time.Now().Add(-time.Hour)
+	if err := os.Chtimes(source, modified, modified); err != nil {
+		t.Fatal(err)
+	}
+	test(New(filepath.Join(dir, "cache")), &md.Relation{Name: "people", Type: md.CsvType, Source: source})
+}
+
+func TestNormalize(t *testing.T) {
+	assert := assert.New(t)
+	query := "SELECT name FROM people WHERE age = ?"
+
+	assert.Equal(Normalize(query, nil), Normalize("SELECT  name\n FROM people /* all */ WHERE age=?", nil))
+	assert.NotEqual(Normalize(query, nil), Normalize("SELECT name FROM people WHERE age = '?'", nil))
+	assert.NotEqual(
+		Normalize(query, []*ast.Constant{{Type: ast.IntegerType, Value: 31}}),
+		Normalize(query, []*ast.Constant{{Type: ast.StringType, Value: "31"}}))
+}
+
+func TestKey(t *testing.T) {
+	inTempDir(t, func(c *Cache, people *md.Relation) {
+		assert := assert.New(t)
+
+		key, sources, err := c.Key("q", []*md.Relation{people})
+		assert.Nil(err)
+		if assert.Len(sources, 1) {
+			assert.Equal(int64(23), sources[0].Size)
+		}
+		again, _, err := c.Key("q", []*md.Relation{people})
+		assert.Nil(err)
+		assert.Equal(key, again)
+
+		other, _, err := c.Key("r", []*md.Relation{people})
+		assert.Nil(err)
+		assert.NotEqual(key, other)
+
+		// The kept hash is used while the size and modification time are the
+		// same, so the file isn't read again.
+		info, err := os.Stat(people.Source)
+		assert.Nil(err)
+		assert.Nil(ioutil.WriteFile(people.Source, []byte("name,age\nann,31\nbob,43\n"), 0644))
+		assert.Nil(os.Chtimes(people.Source, info.ModTime(), info.ModTime()))
+		kept, _, err := c.Key("q", []*md.Relation{people})
+		assert.Nil(err)
+		assert.Equal(key, kept)
+
+		// Once the modification time changes, the file is hashed again.
+		modified := info.ModTime().Add(time.Minute)
+		assert.Nil(os.Chtimes(people.Source, modified, modified))
+		changed, _, err := c.Key("q", []*md.Relation{people})
+		assert.Nil(err)
+		assert.NotEqual(key, changed)
+
+		// The same contents with a new modification time is the same key.
+		assert.Nil(ioutil.WriteFile(people.Source, []byte("name,age\nann,31\nbob,42\n"), 0644))
+		modified = modified.Add(time.Minute)
+		assert.Nil(os.Chtimes(people.Source, modified, modified))
+		touched, _, err := c.Key("q", []*md.Relation{people})
+		assert.Nil(err)
+		assert.Equal(key, touched)
+
+		_, _, err = c.Key("q", []*md.Relation{{Name: "stdin", Type: md.CsvType, Source: "-"}})
+		assert.Equal(ErrUncacheable, err)
+	})
+}
+
+func TestRecorder(t *testing.T) {
+	inTempDir(t, func(c *Cache, people *md.Relation) {
+		assert := assert.New(t)
+		columns := []*md.Column{{Qualifier: "people", Name: "name"}}
+		rows := [][]string{{"ann"}, {"bob"}}
+		key, sources, err := c.Key("q", []*md.Relation{people})
+		assert.Nil(err)
+
+		entry, err := c.Get(key)
+		assert.Nil(err)
+		assert.Nil(entry)
+
+		// Nothing is cached until the last row is read.
+		recorder := c.NewRecorder(physical.NewMemoryScan(columns, rows), key, "q", sources)
+		assert.Nil(recorder.Open())
+		_, err = recorder.Read()
+		assert.Nil(err)
+		entry, err = c.Get(key)
+		assert.Nil(err)
+		assert.Nil(entry)
+		_, err = recorder.Read()
+		assert.Nil(err)
+		_, err = recorder.Read()
+		assert.Equal(io.EOF, err)
+
+		entry, err = c.Get(key)
+		assert.Nil(err)
+		if assert.NotNil(entry) {
+			assert.Equal("q", entry.Query)
+			assert.Equal(columns, entry.Columns)
+			assert.Equal(rows, entry.Rows)
+			scan := entry.NewScan()
+			assert.Nil(scan.Open())
+			row, err := scan.Read()
+			assert.Nil(err)
+			assert.Equal([]string{"ann"}, row)
+		}
+
+		stats, err := c.Stats()
+		assert.Nil(err)
+		assert.Equal(1, stats.Entries)
+		assert.Equal(int64(1), stats.Hits)
+		assert.Equal(int64(2), stats.Misses)
+
+		n, err := c.Clear()
+		assert.Nil(err)
+		assert.Equal(1, n)
+		stats, err = c.Stats()
+		assert.Nil(err)
+		assert.Equal(&Stats{}, stats)
+	})
+}
+
+func TestPutSkipsChangedSources(t *testing.T) {
+	inTempDir(t, func(c *Cache, people *md.Relation) {
+		assert := assert.New(t)
+		key, sources, err := c.Key("q", []*md.Relation{people})
+		assert.Nil(err)
+
+		assert.Nil(ioutil.WriteFile(people.Source, []byte("name,age\n"), 0644))
+		assert.Nil(c.Put(key, &Entry{Query: "q", Sources: sources}))
+		stats, err := c.Stats()
+		assert.Nil(err)
+		assert.Equal(0, stats.Entries)
+	})
+}
+
+func TestEvict(t *testing.T) {
+	inTempDir(t, func(c *Cache, people *md.Relation) {
+		assert := assert.New(t)
+		c.MaxSize = 300
+		rows := [][]string{{"0123456789012345678901234567890123456789"}}
+
+		// Each entry is used later than the one before.
+		used := time.Now().Add(-time.Hour)
+		for _, key := range []string{"a", "b", "c", "d"} {
+			assert.Nil(c.Put(key, &Entry{Query: key, Rows: rows}))
+			used = used.Add(time.Minute)
+			os.Chtimes(c.path(key), used, used)
+		}
+		stats, err := c.Stats()
+		assert.Nil(err)
+		assert.True(stats.Size <= 300)
+		assert.True(stats.Entries > 0 && stats.Entries < 4)
+		entry, err := c.Get("a")
+		assert.Nil(err)
+		assert.Nil(entry)
+		entry, err = c.Get("d")
+		assert.Nil(err)
+		assert.NotNil(entry)
+	})
+}
+
+func TestSchema(t *testing.T) {
+	inTempDir(t, func(c *Cache, people *md.Relation) {
+		assert := assert.New(t)
+		inferred := 0
+		infer := func() (*schema.Schema, error) {
+			inferred++
+			return &schema.Schema{Rows: int64(inferred)}, nil
+		}
+
+		s, err := c.Schema(people, infer)
+		assert.Nil(err)
+		assert.Equal(int64(1), s.Rows)
+		s, err = c.Schema(people, infer)
+		assert.Nil(err)
+		assert.Equal(int64(1), s.Rows)
+
+		// While the size and modification time are the same, the file isn't
+		// read again to check it.
+		info, err := os.Stat(people.Source)
+		assert.Nil(err)
+		assert.Nil(ioutil.WriteFile(people.Source, []byte("name,age\nann,31\nbob,43\n"), 0644))
+		assert.Nil(os.Chtimes(people.Source, info.ModTime(), info.ModTime()))
+		s, err = c.Schema(people, infer)
+		assert.Nil(err)
+		assert.Equal(int64(1), s.Rows)
+
+		assert.Nil(ioutil.WriteFile(people.Source, []byte("name,age\n"), 0644))
+		s, err = c.Schema(people, infer)
+		assert.Nil(err)
+		assert.Equal(int64(2), s.Rows)
+
+		stats, err := c.Stats()
+		assert.Nil(err)
+		assert.Equal(2, stats.Schemas)
+		_, err = c.Clear()
+		assert.Nil(err)
+		stats, err = c.Stats()
+		assert.Nil(err)
+		assert.Equal(0, stats.Schemas)
+
+		var none *Cache
+		s, err = none.Schema(people, infer)
+		assert.Nil(err)
+		assert.Equal(int64(3), s.Rows)
+	})
+}
@@ -0,0 +1,93 @@
+package cache
+
+import (
+	"io"
+	"time"
+
+	md "github.com/jacobsimpson/mtsql/metadata"
+	"github.com/jacobsimpson/mtsql/physical"
+)
+
+// maxEntrySize is the most a result may hold, counting the bytes of its
+// values, to be cached. Larger results are not recorded.
+const maxEntrySize = 64 << 20
+
+// recorder keeps a copy of the rows read from a query, and puts them in the
+// cache once the last row is read. A query that fails, or is stopped, never
+// reads to the end, so its rows are not cached.
+type recorder struct {
+	physical.RowReader
+	cache *Cache
+	key   string
+	entry *Entry
+	size  int
+	// recording is cleared once the result is too large to cache, or has
+	// been cached.
+	recording bool
+}
+
+// NewRecorder reads the rows of a query, and puts them in the cache under the
+// key once they have all been read.
+func (c *Cache) NewRecorder(rowReader physical.RowReader, key, query string, sources []*Fingerprint) physical.RowReader {
+	return &recorder{
+		RowReader: rowReader,
+		cache:     c,
+		key:       key,
+		recording: true,
+		entry: &Entry{
+			Query:   query,
+			Sources: sources,
+			Columns: rowReader.Columns(),
+			Rows:    [][]string{},
+		},
+	}
+}
+
+func (r *recorder) Open() error {
+	r.restart()
+	return r.RowReader.Open()
+}
+
+func (r *recorder) Reset() error {
+	r.restart()
+	return r.RowReader.Reset()
+}
+
+func (r *recorder) restart() {
+	r.entry.Rows, r.size, r.recording = [][]string{}, 0, true
+}
+
+func (r *recorder) Read() ([]string, error) {
+	row, err := r.RowReader.Read()
+	if !r.recording {
+		return row, err
+	}
+	if err == io.EOF {
+		r.entry.Created = time.Now()
+		// The result has already been read, so failing to cache it is not
+		// an error of the query.
+		r.cache.Put(r.key, r.entry)
+		r.recording = false
+	}
+	if err != nil {
+		return row, err
+	}
+	for _, v := range row {
+		r.size += len(v) + 16
+	}
+	if r.size > maxEntrySize {
+		r.entry.Rows, r.recording = nil, false
+		return row, nil
+	}
+	r.entry.Rows = append(r.entry.Rows, append([]string{}, row...))
+	return row, nil
+}
+
+// NewScan reads the rows of a cached result.
+func (e *Entry) NewScan() physical.RowReader {
+	columns := e.Columns
+	if columns == nil {
+		columns = []*md.Column{}
+	}
+	return physical.NewMemoryScan(columns, e.Rows)
+}
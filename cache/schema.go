@@ -0,0 +1,81 @@
+package cache
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	md "github.com/jacobsimpson/mtsql/metadata"
+	"github.com/jacobsimpson/mtsql/schema"
+)
+
+// schemaDir is the directory in a cache holding the schemas of tables.
+const schemaDir = "schemas"
+
+// schemaEntry is a cached schema, and the files it was inferred from.
+type schemaEntry struct {
+	Sources []*Fingerprint
+	Schema  *schema.Schema
+}
+
+// Schema returns the schema of a table. It is only inferred, with infer, if
+// the files of the table have changed since it was last inferred, or the
+// table can't be fingerprinted. Like Key, a file is only read to check it
+// when its size or modification time has changed. A nil cache always infers
+// the schema.
+func (c *Cache) Schema(relation *md.Relation, infer func() (*schema.Schema, error)) (*schema.Schema, error) {
+	if c == nil {
+		return infer()
+	}
+	key, sources, err := c.Key("schema", []*md.Relation{relation})
+	if err != nil {
+		return infer()
+	}
+	path := filepath.Join(c.Dir, schemaDir, key+".json")
+	if b, err := ioutil.ReadFile(path); err == nil {
+		e := &schemaEntry{}
+		if err := json.Unmarshal(b, e); err == nil && e.Schema != nil {
+			return e.Schema, nil
+		}
+	}
+
+	s, err := infer()
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range sources {
+		if f.changed() {
+			return s, nil
+		}
+	}
+	if b, err := json.Marshal(&schemaEntry{Sources: sources, Schema: s}); err == nil {
+		// The schema has been inferred, so failing to keep it is not an
+		// error.
+		writeFile(filepath.Dir(path), path, b)
+	}
+	return s, nil
+}
+
+// schemas counts the cached schemas.
+func (c *Cache) schemas() int {
+	infos, err := ioutil.ReadDir(filepath.Join(c.Dir, schemaDir))
+	if err != nil {
+		return 0
+	}
+	n := 0
+	for _, info := range infos {
+		if filepath.Ext(info.Name()) == ".json" {
+			n++
+		}
+	}
+	return n
+}
+
+func (c *Cache) clearSchemas() error {
+	err := os.RemoveAll(filepath.Join(c.Dir, schemaDir))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,360 @@
+package csvfile
+
+import (
+	"bufio"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/jacobsimpson/mtsql/decompress"
+	md "github.com/jacobsimpson/mtsql/metadata"
+)
+
+// FilenameColumn is the virtual column added to relations read from a glob,
+// holding the file each row came from.
+const FilenameColumn = "_filename"
+
+// IsGlob reports whether the path is a pattern matching multiple files.
+func IsGlob(path string) bool {
+	return strings.ContainsAny(path, "*?[")
+}
+
+// Glob returns the files matching the pattern, in lexical order. It is an
+// error for the pattern to match nothing.
+func Glob(pattern string) ([]string, error) {
+	files, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid file pattern %q: %v", pattern, err)
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no files match %q", pattern)
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// Reader reads the records of a CSV file, honouring the delimiter, quote,
+// comment, header and encoding settings of a metadata.CsvDialect. It keeps
+// track of the byte offset each record starts at, so it can go back to one.
+type Reader struct {
+	closer   io.Closer
+	fileName string
+	// file is the open file when it isn't compressed, and can be seeked.
+	file    *os.File
+	lines   *lineReader
+	reader  *csv.Reader
+	dialect *md.CsvDialect
+	header  []string
+	pending []string
+	// start is the offset of the first record after the header, and offset
+	// the offset of the record last read.
+	start  int64
+	offset int64
+}
+
+// Open opens the named file, decompressing it if it is gzip, zstd or bzip2
+// compressed, and reads its header. If the dialect has no
+// header row, the columns are named c1..cn after the width of the first
+// record.
+func Open(fileName string, dialect *md.CsvDialect) (*Reader, error) {
+	file, err := decompress.OpenPlain(fileName)
+	if err != nil {
+		return nil, err
+	}
+	var f io.ReadCloser = file
+	if file == nil {
+		if f, err = decompress.Open(fileName); err != nil {
+			return nil, err
+		}
+	}
+	r, err := newReader(f, dialect, true, 0)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	r.closer = f
+	r.fileName = fileName
+	r.file = file
+	return r, nil
+}
+
+// NewReader reads CSV records from an already open stream.
+func NewReader(stream io.Reader, dialect *md.CsvDialect) (*Reader, error) {
+	return newReader(stream, dialect, true, 0)
+}
+
+// newReader reads CSV records from a stream, starting with the header unless
+// header is false, for streams that start partway through a file, at offset.
+func newReader(stream io.Reader, dialect *md.CsvDialect, header bool, offset int64) (*Reader, error) {
+	if dialect == nil {
+		dialect = md.NewCsvDialect()
+	}
+	if dialect.Quote >= utf8.RuneSelf || dialect.Quote == '\n' || dialect.Quote == '\r' {
+		return nil, fmt.Errorf("invalid quote character %q", dialect.Quote)
+	}
+	switch dialect.Encoding {
+	case "", md.Utf8Encoding, md.Latin1Encoding:
+	default:
+		return nil, fmt.Errorf("unsupported encoding %q", dialect.Encoding)
+	}
+
+	r := &Reader{dialect: dialect}
+	r.follow(newLineReader(stream, offset))
+	r.start = r.lines.offset
+	if !header {
+		return r, nil
+	}
+	first, err := r.read()
+	if err != nil {
+		return nil, err
+	}
+	if dialect.Header {
+		r.header = first
+		r.start = r.lines.offset
+	} else {
+		for i := range first {
+			r.header = append(r.header, fmt.Sprintf("c%d", i+1))
+		}
+		r.pending = first
+	}
+	return r, nil
+}
+
+// follow starts parsing records from lines.
+func (r *Reader) follow(lines *lineReader) {
+	var input io.Reader = lines
+	if r.dialect.Encoding == md.Latin1Encoding {
+		input = &latin1Reader{stream: bufio.NewReader(input)}
+	}
+	if r.dialect.Quote != 0 && r.dialect.Quote != '"' {
+		input = &swapReader{stream: input, a: byte(r.dialect.Quote), b: '"'}
+	}
+
+	reader := csv.NewReader(input)
+	if r.dialect.Delimiter != 0 {
+		reader.Comma = r.dialect.Delimiter
+	}
+	reader.Comment = r.dialect.Comment
+	r.lines = lines
+	r.reader = reader
+	r.pending = nil
+}
+
+// Header returns the column names of the file.
+func (r *Reader) Header() []string {
+	return r.header
+}
+
+// Read returns the next record, or io.EOF when there are no more.
+func (r *Reader) Read() ([]string, error) {
+	if r.pending != nil {
+		record := r.pending
+		r.pending = nil
+		return record, nil
+	}
+	return r.read()
+}
+
+func (r *Reader) read() ([]string, error) {
+	for {
+		r.offset = r.lines.offset
+		record, err := r.reader.Read()
+		if err != nil {
+			return nil, err
+		}
+		// After the CSV reader has read all the lines in a file, it will
+		// return an extra line, a 0 length array.
+		if len(record) == 0 {
+			continue
+		}
+		if r.dialect.Quote != 0 && r.dialect.Quote != '"' {
+			for i, field := range record {
+				record[i] = swap(field, r.dialect.Quote, '"')
+			}
+		}
+		return record, nil
+	}
+}
+
+// Offset returns the byte offset in the file of the record last read.
+// SeekRecord with it reads that record again. The offset is into the decompressed
+// contents of a compressed file.
+func (r *Reader) Offset() int64 {
+	return r.offset
+}
+
+// SeekRecord goes to a record at an offset returned by Offset, so it is the next
+// one read. Seeking in a file that isn't compressed is immediate; a
+// compressed file, or standard input, is read again from the start up to the
+// offset. A Reader made from a stream can only seek forward.
+func (r *Reader) SeekRecord(offset int64) error {
+	switch {
+	case r.file != nil:
+		if _, err := r.file.Seek(offset, io.SeekStart); err != nil {
+			return err
+		}
+		r.follow(newLineReader(r.file, offset))
+		return nil
+	case offset < r.lines.offset:
+		if r.fileName == "" {
+			return fmt.Errorf("unable to seek back to offset %d in a stream", offset)
+		}
+		f, err := decompress.Open(r.fileName)
+		if err != nil {
+			return err
+		}
+		r.closer.Close()
+		r.closer = f
+		r.follow(newLineReader(f, 0))
+	}
+	if err := r.lines.discard(offset - r.lines.offset); err != nil {
+		return err
+	}
+	r.follow(r.lines)
+	return nil
+}
+
+// Rewind goes back to the first record after the header.
+func (r *Reader) Rewind() error {
+	return r.SeekRecord(r.start)
+}
+
+func (r *Reader) Close() error {
+	if r.closer == nil {
+		return nil
+	}
+	return r.closer.Close()
+}
+
+// lineReader hands the bytes of a stream on no more than a line at a time,
+// counting them as it goes. encoding/csv reads ahead into a buffer, but only
+// by one Read of its input at a time, and only until it has the end of a
+// line, so it never holds bytes past the record it returns, and the count is
+// the offset of the next one.
+type lineReader struct {
+	stream *bufio.Reader
+	line   []byte
+	err    error
+	offset int64
+}
+
+// newLineReader reads a stream starting at offset in a file. A UTF-8 byte
+// order mark at the start of the file is skipped.
+func newLineReader(stream io.Reader, offset int64) *lineReader {
+	l := &lineReader{stream: bufio.NewReaderSize(stream, 64*1024), offset: offset}
+	if offset == 0 {
+		if b, err := l.stream.Peek(3); err == nil && string(b) == "\xef\xbb\xbf" {
+			l.stream.Discard(3)
+			l.offset = 3
+		}
+	}
+	return l
+}
+
+func (l *lineReader) Read(p []byte) (int, error) {
+	if len(l.line) == 0 {
+		if l.err != nil {
+			return 0, l.err
+		}
+		var err error
+		l.line, err = l.stream.ReadSlice('\n')
+		if err != nil && err != bufio.ErrBufferFull {
+			l.err = err
+		}
+		if len(l.line) == 0 {
+			return 0, l.err
+		}
+	}
+	n := copy(p, l.line)
+	l.line = l.line[n:]
+	l.offset += int64(n)
+	return n, nil
+}
+
+// discard skips n bytes.
+func (l *lineReader) discard(n int64) error {
+	if _, err := io.CopyN(ioutil.Discard, l, n); err != nil && err != io.EOF {
+		return err
+	}
+	return nil
+}
+
+// latin1Reader decodes ISO-8859-1 into UTF-8. Every Latin-1 byte is the code
+// point of the same value, so the decoding is a direct widening.
+type latin1Reader struct {
+	stream  *bufio.Reader
+	pending []byte
+}
+
+func (l *latin1Reader) Read(p []byte) (int, error) {
+	n := 0
+	for n < len(p) {
+		if len(l.pending) > 0 {
+			c := copy(p[n:], l.pending)
+			l.pending = l.pending[c:]
+			n += c
+			continue
+		}
+		b, err := l.stream.ReadByte()
+		if err != nil {
+			if n > 0 {
+				return n, nil
+			}
+			return 0, err
+		}
+		if b < utf8.RuneSelf {
+			p[n] = b
+			n++
+			// Stopping at the end of a line keeps the offsets of the
+			// lineReader underneath right.
+			if b == '\n' {
+				break
+			}
+			continue
+		}
+		buf := make([]byte, 2)
+		l.pending = buf[:utf8.EncodeRune(buf, rune(b))]
+	}
+	return n, nil
+}
+
+// swapReader exchanges two ASCII bytes in the stream. encoding/csv only
+// understands '"' as a quote, so a custom quote character is swapped with '"'
+// on the way in and swapped back in each field on the way out.
+type swapReader struct {
+	stream io.Reader
+	a, b   byte
+}
+
+func (s *swapReader) Read(p []byte) (int, error) {
+	n, err := s.stream.Read(p)
+	for i := 0; i < n; i++ {
+		if p[i] == s.a {
+			p[i] = s.b
+		} else if p[i] == s.b {
+			p[i] = s.a
+		}
+	}
+	return n, err
+}
+
+func swap(s string, a, b rune) string {
+	if !strings.ContainsRune(s, a) && !strings.ContainsRune(s, b) {
+		return s
+	}
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case a:
+			return b
+		case b:
+			return a
+		}
+		return r
+	}, s)
+}
@@ -0,0 +1,421 @@
+package csvfile_test
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jacobsimpson/mtsql/csvfile"
+	md "github.com/jacobsimpson/mtsql/metadata"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadDialects(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		dialect  *md.CsvDialect
+		header   []string
+		expected [][]string
+	}{
+		{
+			name:     "default dialect",
+			input:    "a,b\n1,\"x,y\"\n",
+			header:   []string{"a", "b"},
+			expected: [][]string{{"1", "x,y"}},
+		},
+		{
+			name:     "semicolon delimiter",
+			input:    "a;b\n1;2,5\n",
+			dialect:  &md.CsvDialect{Delimiter: ';', Quote: '"', Header: true},
+			header:   []string{"a", "b"},
+			expected: [][]string{{"1", "2,5"}},
+		},
+		{
+			name:     "no header",
+			input:    "1|2\n3|4\n",
+			dialect:  &md.CsvDialect{Delimiter: '|', Quote: '"'},
+			header:   []string{"c1", "c2"},
+			expected: [][]string{{"1", "2"}, {"3", "4"}},
+		},
+		{
+			name:     "comments",
+			input:    "# exported\na,b\n# halfway\n1,2\n",
+			dialect:  &md.CsvDialect{Delimiter: ',', Quote: '"', Comment: '#', Header: true},
+			header:   []string{"a", "b"},
+			expected: [][]string{{"1", "2"}},
+		},
+		{
+			name:     "byte order mark",
+			input:    "\xef\xbb\xbfa,b\n1,2\n",
+			header:   []string{"a", "b"},
+			expected: [][]string{{"1", "2"}},
+		},
+		{
+			name:     "custom quote",
+			input:    "a,b\n'x,y',\"z\"\n",
+			dialect:  &md.CsvDialect{Delimiter: ',', Quote: '\'', Header: true},
+			header:   []string{"a", "b"},
+			expected: [][]string{{"x,y", `"z"`}},
+		},
+		{
+			name:     "latin1",
+			input:    "name\nJos\xe9\n",
+			dialect:  &md.CsvDialect{Delimiter: ',', Quote: '"', Header: true, Encoding: md.Latin1Encoding},
+			header:   []string{"name"},
+			expected: [][]string{{"José"}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			r, err := csvfile.NewReader(strings.NewReader(test.input), test.dialect)
+			assert.Nil(err)
+			assert.Equal(test.header, r.Header())
+
+			rows := [][]string{}
+			for {
+				row, err := r.Read()
+				if err == io.EOF {
+					break
+				}
+				assert.Nil(err)
+				rows = append(rows, row)
+			}
+			assert.Equal(test.expected, rows)
+		})
+	}
+}
+
+func TestWriteStyles(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		style    *csvfile.Style
+		expected string
+	}{
+		{"plain", "a,b\n1,2\n", &csvfile.Style{}, "a,b\n\"#1\",\"x\ny\"\n"},
+		{"CRLF", "a,b\r\n1,2\r\n", &csvfile.Style{CRLF: true}, "a,b\r\n\"#1\",\"x\r\ny\"\r\n"},
+		{"byte order mark", "\xef\xbb\xbfa,b\n", &csvfile.Style{BOM: true}, "\xef\xbb\xbfa,b\n\"#1\",\"x\ny\"\n"},
+		{"one line", "\xef\xbb\xbfa,b\r\n", &csvfile.Style{BOM: true, CRLF: true}, "\xef\xbb\xbfa,b\r\n\"#1\",\"x\r\ny\"\r\n"},
+		{"no line ending", "a,b", &csvfile.Style{}, "a,b\n\"#1\",\"x\ny\"\n"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert := assert.New(t)
+			f, err := ioutil.TempFile("", "mtsql-style-")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.Remove(f.Name())
+			f.WriteString(test.input)
+			f.Close()
+
+			style, err := csvfile.DetectStyle(f.Name())
+			assert.Nil(err)
+			assert.Equal(test.style, style)
+
+			dialect := md.NewCsvDialect()
+			dialect.Comment = '#'
+			var b strings.Builder
+			w, err := csvfile.NewStyledWriter(&b, dialect, style)
+			assert.Nil(err)
+			assert.Nil(w.Write([]string{"a", "b"}))
+			assert.Nil(w.Write([]string{"#1", "x\ny"}))
+			assert.Nil(w.Flush())
+			assert.Equal(test.expected, b.String())
+
+			r, err := csvfile.NewReader(strings.NewReader(b.String()), dialect)
+			assert.Nil(err)
+			assert.Equal([]string{"a", "b"}, r.Header())
+			record, err := r.Read()
+			assert.Nil(err)
+			assert.Equal([]string{"#1", "x\ny"}, record)
+		})
+	}
+}
+
+func TestWriteDialects(t *testing.T) {
+	records := [][]string{
+		{"a", "b"},
+		{"x,y", `say "hi"`},
+		{"it's", "#1"},
+		{"#2", ""},
+	}
+	tests := []struct {
+		name     string
+		dialect  *md.CsvDialect
+		expected string
+	}{
+		{
+			name:     "default dialect",
+			expected: "a,b\n\"x,y\",\"say \"\"hi\"\"\"\nit's,#1\n#2,\n",
+		},
+		{
+			name:     "custom quote",
+			dialect:  &md.CsvDialect{Delimiter: ';', Quote: '\'', Header: true},
+			expected: "a;b\nx,y;say \"hi\"\n'it''s';#1\n#2;\n",
+		},
+		{
+			name:     "comments",
+			dialect:  &md.CsvDialect{Delimiter: ',', Quote: '"', Comment: '#', Header: true},
+			expected: "a,b\n\"x,y\",\"say \"\"hi\"\"\"\nit's,#1\n\"#2\",\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert := assert.New(t)
+			var b strings.Builder
+
+			w, err := csvfile.NewWriter(&b, test.dialect)
+			assert.Nil(err)
+			for _, record := range records {
+				assert.Nil(w.Write(record))
+			}
+			assert.Nil(w.Flush())
+			assert.Equal(test.expected, b.String())
+
+			// What is written reads back as the same records.
+			r, err := csvfile.NewReader(strings.NewReader(b.String()), test.dialect)
+			assert.Nil(err)
+			assert.Equal(records[0], r.Header())
+			rows := [][]string{}
+			for {
+				row, err := r.Read()
+				if err == io.EOF {
+					break
+				}
+				assert.Nil(err)
+				rows = append(rows, row)
+			}
+			assert.Equal(records[1:], rows)
+		})
+	}
+}
+
+func TestSplit(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		dialect *md.CsvDialect
+		size    int64
+		parts   [][][]string
+	}{
+		{
+			name:  "one record to a part",
+			input: "a,b\n1,2\n3,4\n5,6\n",
+			size:  1,
+			parts: [][][]string{{{"1", "2"}}, {{"3", "4"}}, {{"5", "6"}}},
+		},
+		{
+			name:  "records per part",
+			input: "a,b\n1,2\n3,4\n5,6",
+			size:  8,
+			parts: [][][]string{{{"1", "2"}, {"3", "4"}}, {{"5", "6"}}},
+		},
+		{
+			name:  "line breaks in quotes",
+			input: "a,b\n1,\"x\ny\"\n2,\"\"\"\n\"\n3,z\n",
+			size:  1,
+			parts: [][][]string{{{"1", "x\ny"}}, {{"2", "\"\n"}}, {{"3", "z"}}},
+		},
+		{
+			name:    "comments and no header",
+			input:   "# a \"quote\n1|2\n\n# \"\n3|4\n",
+			dialect: &md.CsvDialect{Delimiter: '|', Quote: '"', Comment: '#'},
+			size:    1,
+			parts:   [][][]string{{{"1", "2"}}, {{"3", "4"}}},
+		},
+		{
+			name:    "custom quote",
+			input:   "a,b\n'x\ny',1\n2,3\n",
+			dialect: &md.CsvDialect{Delimiter: ',', Quote: '\'', Header: true},
+			size:    1,
+			parts:   [][][]string{{{"x\ny", "1"}}, {{"2", "3"}}},
+		},
+		{
+			name:  "only a header",
+			input: "a,b",
+			size:  1,
+			parts: [][][]string{},
+		},
+	}
+
+	dir, err := ioutil.TempDir("", "csvfile")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for i, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert := assert.New(t)
+			name := filepath.Join(dir, fmt.Sprintf("%d.csv", i))
+			if err := ioutil.WriteFile(name, []byte(test.input), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			offsets, err := csvfile.Split(name, test.dialect, test.size)
+			assert.Nil(err)
+
+			parts := [][][]string{}
+			for p := 1; p < len(offsets); p++ {
+				r, err := csvfile.OpenRange(name, test.dialect, offsets[p-1], offsets[p])
+				if !assert.Nil(err) {
+					return
+				}
+				rows := [][]string{}
+				for {
+					row, err := r.Read()
+					if err == io.EOF {
+						break
+					}
+					assert.Nil(err)
+					rows = append(rows, row)
+				}
+				r.Close()
+				parts = append(parts, rows)
+			}
+			assert.Equal(test.parts, parts)
+		})
+	}
+}
+
+func TestSeekRecord(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		dialect *md.CsvDialect
+	}{
+		{
+			name:  "default dialect",
+			input: "a,b\n1,2\n3,4\n5,6",
+		},
+		{
+			name:  "short lines and a byte order mark",
+			input: "\xef\xbb\xbfa\n1\n\n2\n3\n",
+		},
+		{
+			name:  "line breaks in quotes",
+			input: "a,b\n1,\"x\ny\"\n2,\"\"\"\n\"\n3,z\n",
+		},
+		{
+			name:    "comments and no header",
+			input:   "# a \"quote\n1|2\n\n# \"\n3|4\n",
+			dialect: &md.CsvDialect{Delimiter: '|', Quote: '"', Comment: '#'},
+		},
+		{
+			name:    "custom quote and latin1",
+			input:   "a,b\n'x\ny',Jos\xe9\n2,\xe9\xe9\n3,4\n",
+			dialect: &md.CsvDialect{Delimiter: ',', Quote: '\'', Header: true, Encoding: md.Latin1Encoding},
+		},
+		{
+			name:  "long lines",
+			input: "a,b\n1," + strings.Repeat("x", 100000) + "\n2,y\n",
+		},
+	}
+
+	dir, err := ioutil.TempDir("", "csvfile")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for i, test := range tests {
+		for _, compressed := range []bool{false, true} {
+			t.Run(fmt.Sprintf("%s, compressed %v", test.name, compressed), func(t *testing.T) {
+				assert := assert.New(t)
+				name := filepath.Join(dir, fmt.Sprintf("%d.csv", i))
+				if err := ioutil.WriteFile(name, []byte(test.input), 0644); err != nil {
+					t.Fatal(err)
+				}
+				if compressed {
+					name = gzipFile(t, name)
+				}
+
+				r, err := csvfile.Open(name, test.dialect)
+				if !assert.Nil(err) {
+					return
+				}
+				defer r.Close()
+				rows := [][]string{}
+				offsets := []int64{}
+				for {
+					row, err := r.Read()
+					if err == io.EOF {
+						break
+					}
+					assert.Nil(err)
+					rows = append(rows, row)
+					offsets = append(offsets, r.Offset())
+				}
+
+				// Seek to each of the records, from last to first.
+				for n := len(offsets) - 1; n >= 0; n-- {
+					assert.Nil(r.SeekRecord(offsets[n]))
+					row, err := r.Read()
+					assert.Nil(err)
+					assert.Equal(rows[n], row)
+					assert.Equal(offsets[n], r.Offset())
+				}
+
+				assert.Nil(r.Rewind())
+				again := [][]string{}
+				for {
+					row, err := r.Read()
+					if err == io.EOF {
+						break
+					}
+					assert.Nil(err)
+					again = append(again, row)
+				}
+				assert.Equal(rows, again)
+			})
+		}
+	}
+}
+
+func TestSeekRecordInStream(t *testing.T) {
+	assert := assert.New(t)
+	r, err := csvfile.NewReader(strings.NewReader("a\n1\n2\n3\n"), nil)
+	assert.Nil(err)
+
+	_, err = r.Read()
+	assert.Nil(err)
+	first := r.Offset()
+	_, err = r.Read()
+	assert.Nil(err)
+	third := r.Offset() + 2
+
+	assert.Nil(r.SeekRecord(third))
+	row, err := r.Read()
+	assert.Nil(err)
+	assert.Equal([]string{"3"}, row)
+	assert.EqualError(r.SeekRecord(first), "unable to seek back to offset 2 in a stream")
+}
+
+func gzipFile(t *testing.T, name string) string {
+	b, err := ioutil.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Create(name + ".gz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	w := gzip.NewWriter(f)
+	w.Write(b)
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return name + ".gz"
+}
@@ -0,0 +1,103 @@
+package csvfile
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"os"
+
+	md "github.com/jacobsimpson/mtsql/metadata"
+)
+
+// Split finds where to divide a CSV file into parts of about size bytes, so
+// the parts can be read at the same time with OpenRange. The parts start on
+// record boundaries, which takes a pass over the file to tell line breaks in
+// quoted fields from the ones between records. The first offset returned is
+// the start of the first record after the header, and the last is the size
+// of the file. The file must not be compressed.
+func Split(fileName string, dialect *md.CsvDialect, size int64) ([]int64, error) {
+	if dialect == nil {
+		dialect = md.NewCsvDialect()
+	}
+	f, err := os.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	quote := byte('"')
+	if dialect.Quote != 0 {
+		quote = byte(dialect.Quote)
+	}
+	var comment []byte
+	if dialect.Comment != 0 {
+		comment = []byte(string(dialect.Comment))
+	}
+
+	offsets := []int64{}
+	header := dialect.Header
+	if !header {
+		offsets = append(offsets, 0)
+	}
+	reader := bufio.NewReaderSize(f, 1<<20)
+	var offset, start int64
+	quoted, lineStart, blank := false, true, false
+	for {
+		line, err := reader.ReadSlice('\n')
+		if len(line) > 0 {
+			if lineStart {
+				// A line starting with the comment character, or with
+				// nothing on it, is skipped by the reader rather than being
+				// a record.
+				blank = !quoted &&
+					((comment != nil && bytes.HasPrefix(line, comment)) || len(bytes.TrimRight(line, "\r\n")) == 0)
+			}
+			if !blank && bytes.Count(line, []byte{quote})%2 == 1 {
+				quoted = !quoted
+			}
+			offset += int64(len(line))
+			lineStart = line[len(line)-1] == '\n'
+			if lineStart && !quoted && !blank {
+				switch {
+				case header:
+					header = false
+					offsets = append(offsets, offset)
+					start = offset
+				case offset-start >= size:
+					offsets = append(offsets, offset)
+					start = offset
+				}
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil && err != bufio.ErrBufferFull {
+			return nil, err
+		}
+	}
+	if len(offsets) == 0 {
+		// A file with nothing but a header, and no line break after it.
+		offsets = append(offsets, offset)
+	}
+	if offsets[len(offsets)-1] != offset {
+		offsets = append(offsets, offset)
+	}
+	return offsets, nil
+}
+
+// OpenRange reads the records between two offsets in a file, as returned by
+// Split. The records are all data, so the Reader has no Header.
+func OpenRange(fileName string, dialect *md.CsvDialect, start, end int64) (*Reader, error) {
+	f, err := os.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+	r, err := newReader(io.NewSectionReader(f, start, end-start), dialect, false, start)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	r.closer = f
+	return r, nil
+}
@@ -0,0 +1,163 @@
+package csvfile
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode/utf8"
+
+	md "github.com/jacobsimpson/mtsql/metadata"
+)
+
+// Writer writes CSV records in a dialect, so they can be read back with a
+// Reader in the same dialect. Only UTF-8 is written.
+type Writer struct {
+	dialect *md.CsvDialect
+	buf     *bufio.Writer
+	writer  *csv.Writer
+}
+
+// Style is how a file is laid out, beyond what its dialect describes: whether
+// it starts with a UTF-8 byte order mark, and whether its lines end with CRLF
+// rather than LF. Readers accept either, so it only matters when a file is
+// rewritten.
+type Style struct {
+	BOM  bool
+	CRLF bool
+}
+
+// bom is the UTF-8 byte order mark.
+const bom = "\xef\xbb\xbf"
+
+// DetectStyle finds the Style of a file that isn't compressed, from its
+// first line.
+func DetectStyle(fileName string) (*Style, error) {
+	f, err := os.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	line, err := bufio.NewReader(f).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	return &Style{
+		BOM:  strings.HasPrefix(line, bom),
+		CRLF: strings.HasSuffix(line, "\r\n"),
+	}, nil
+}
+
+// NewWriter writes CSV records to a stream. A nil dialect is the default
+// comma separated, double quoted dialect. Writing the header is left to the
+// caller.
+func NewWriter(stream io.Writer, dialect *md.CsvDialect) (*Writer, error) {
+	return NewStyledWriter(stream, dialect, nil)
+}
+
+// NewStyledWriter writes CSV records to a stream like NewWriter, laid out in
+// the style. A nil style is LF line endings without a byte order mark. The
+// byte order mark is written straight away.
+func NewStyledWriter(stream io.Writer, dialect *md.CsvDialect, style *Style) (*Writer, error) {
+	if dialect == nil {
+		dialect = md.NewCsvDialect()
+	}
+	if dialect.Quote >= utf8.RuneSelf || dialect.Quote == '\n' || dialect.Quote == '\r' {
+		return nil, fmt.Errorf("invalid quote character %q", dialect.Quote)
+	}
+	if dialect.Encoding != "" && dialect.Encoding != md.Utf8Encoding {
+		return nil, fmt.Errorf("can only write UTF-8, not %q", dialect.Encoding)
+	}
+	// encoding/csv only quotes with '"', so a custom quote is swapped with
+	// '"' in each field on the way in, and back in the output, as the Reader
+	// does.
+	if dialect.Quote != 0 && dialect.Quote != '"' {
+		stream = &swapWriter{stream: stream, a: byte(dialect.Quote), b: '"'}
+	}
+	buf := bufio.NewWriter(stream)
+	writer := csv.NewWriter(buf)
+	if dialect.Delimiter != 0 {
+		writer.Comma = dialect.Delimiter
+	}
+	if style != nil {
+		writer.UseCRLF = style.CRLF
+		if style.BOM {
+			buf.WriteString(bom)
+		}
+	}
+	return &Writer{dialect: dialect, buf: buf, writer: writer}, nil
+}
+
+// Write writes a record.
+func (w *Writer) Write(record []string) error {
+	if w.dialect.Quote != 0 && w.dialect.Quote != '"' {
+		swapped := make([]string, len(record))
+		for i, field := range record {
+			swapped[i] = swap(field, w.dialect.Quote, '"')
+		}
+		record = swapped
+	}
+	if !w.commented(record) {
+		return w.writer.Write(record)
+	}
+
+	// The record would be read back as a comment, so its first field, which
+	// encoding/csv writes as it is, is put in quotes.
+	var line bytes.Buffer
+	c := csv.NewWriter(&line)
+	c.Comma = w.writer.Comma
+	c.UseCRLF = w.writer.UseCRLF
+	if err := c.Write(record); err != nil {
+		return err
+	}
+	c.Flush()
+	w.writer.Flush()
+	if err := w.writer.Error(); err != nil {
+		return err
+	}
+	w.buf.WriteString(`"` + record[0] + `"`)
+	_, err := w.buf.Write(line.Bytes()[len(record[0]):])
+	return err
+}
+
+// commented checks whether a record starts with the comment character, and
+// wouldn't otherwise have its first field quoted.
+func (w *Writer) commented(record []string) bool {
+	return len(record) > 0 &&
+		w.dialect.Comment != 0 &&
+		strings.HasPrefix(record[0], string(w.dialect.Comment)) &&
+		!strings.ContainsAny(record[0], string(w.writer.Comma)+"\"\r\n")
+}
+
+// Flush writes any buffered records to the stream.
+func (w *Writer) Flush() error {
+	w.writer.Flush()
+	if err := w.writer.Error(); err != nil {
+		return err
+	}
+	return w.buf.Flush()
+}
+
+// swapWriter exchanges two ASCII bytes in a stream, like swapReader.
+type swapWriter struct {
+	stream io.Writer
+	a, b   byte
+}
+
+func (s *swapWriter) Write(p []byte) (int, error) {
+	swapped := make([]byte, len(p))
+	for i, c := range p {
+		switch c {
+		case s.a:
+			swapped[i] = s.b
+		case s.b:
+			swapped[i] = s.a
+		default:
+			swapped[i] = c
+		}
+	}
+	return s.stream.Write(swapped)
+}
@@ -0,0 +1,166 @@
+package decompress
+
+import (
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/jacobsimpson/mtsql/spool"
+	"github.com/klauspost/compress/zstd"
+)
+
+type Codec string
+
+const (
+	None  Codec = ""
+	Gzip  Codec = "gzip"
+	Zstd  Codec = "zstd"
+	Bzip2 Codec = "bzip2"
+)
+
+var magic = []struct {
+	codec  Codec
+	prefix []byte
+}{
+	{Gzip, []byte{0x1f, 0x8b}},
+	{Zstd, []byte{0x28, 0xb5, 0x2f, 0xfd}},
+}
+
+// bzip2Magic are the magic numbers of a bzip2 block, and of the end of a
+// stream. A bzip2 file starts with BZh, a block size from 1 to 9, and then one
+// of them, so a CSV file starting with BZh isn't taken for one.
+var bzip2Magic = [][]byte{
+	{0x31, 0x41, 0x59, 0x26, 0x53, 0x59},
+	{0x17, 0x72, 0x45, 0x38, 0x50, 0x90},
+}
+
+// sniffLength is the number of bytes at the start of a file sniff looks at.
+const sniffLength = 10
+
+// sniff returns the compression the bytes at the start of a file indicate.
+func sniff(b []byte) Codec {
+	for _, m := range magic {
+		if bytes.HasPrefix(b, m.prefix) {
+			return m.codec
+		}
+	}
+	if len(b) >= sniffLength && bytes.HasPrefix(b, []byte("BZh")) && '1' <= b[3] && b[3] <= '9' {
+		for _, m := range bzip2Magic {
+			if bytes.Equal(b[4:sniffLength], m) {
+				return Bzip2
+			}
+		}
+	}
+	return None
+}
+
+// CodecForName returns the compression a file name's extension indicates.
+func CodecForName(fileName string) Codec {
+	switch strings.ToLower(filepath.Ext(fileName)) {
+	case ".gz", ".gzip":
+		return Gzip
+	case ".zst", ".zstd":
+		return Zstd
+	case ".bz2":
+		return Bzip2
+	}
+	return None
+}
+
+// TrimExt removes a compression extension from a file name, so report.csv.gz
+// becomes report.csv.
+func TrimExt(fileName string) string {
+	if CodecForName(fileName) == None {
+		return fileName
+	}
+	return strings.TrimSuffix(fileName, filepath.Ext(fileName))
+}
+
+// Open opens a file for reading, decompressing it if it is compressed. The
+// compression is determined from the extension, or failing that, from the
+// magic bytes at the start of the file. The file name "-" reads standard
+// input from the start.
+func Open(fileName string) (io.ReadCloser, error) {
+	var f io.ReadCloser
+	if fileName == spool.StdinName {
+		f = spool.Stdin().NewReader()
+	} else {
+		file, err := os.Open(fileName)
+		if err != nil {
+			return nil, err
+		}
+		f = file
+	}
+	r, err := NewReader(f, CodecForName(fileName))
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return &readCloser{Reader: r, closers: []io.Closer{r, f}}, nil
+}
+
+// OpenPlain opens a file that isn't compressed, so it can be read from any
+// offset. It returns nil for standard input and for files compressed by their
+// extension or magic bytes, which can only be read from the start with Open.
+func OpenPlain(fileName string) (*os.File, error) {
+	if fileName == spool.StdinName || CodecForName(fileName) != None {
+		return nil, nil
+	}
+	f, err := os.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+	b := make([]byte, sniffLength)
+	n, _ := f.ReadAt(b, 0)
+	if sniff(b[:n]) != None {
+		f.Close()
+		return nil, nil
+	}
+	return f, nil
+}
+
+// NewReader decompresses a stream with the codec, or with the codec its
+// magic bytes indicate when codec is None.
+func NewReader(stream io.Reader, codec Codec) (io.ReadCloser, error) {
+	input := bufio.NewReader(stream)
+	if codec == None {
+		// Peek returns what there is of a shorter stream, with an error.
+		b, _ := input.Peek(sniffLength)
+		codec = sniff(b)
+	}
+
+	switch codec {
+	case Gzip:
+		return gzip.NewReader(input)
+	case Zstd:
+		d, err := zstd.NewReader(input)
+		if err != nil {
+			return nil, err
+		}
+		return d.IOReadCloser(), nil
+	case Bzip2:
+		return ioutil.NopCloser(bzip2.NewReader(input)), nil
+	}
+	return ioutil.NopCloser(input), nil
+}
+
+type readCloser struct {
+	io.Reader
+	closers []io.Closer
+}
+
+func (r *readCloser) Close() error {
+	var result error
+	for _, c := range r.closers {
+		if err := c.Close(); err != nil && result == nil {
+			result = err
+		}
+	}
+	return result
+}
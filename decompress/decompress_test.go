@@ -0,0 +1,79 @@
+package decompress_test
+
+import (
+	"io/ioutil"
+	"testing"
+
+	"github.com/jacobsimpson/mtsql/decompress"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOpen(t *testing.T) {
+	for _, fileName := range []string{
+		"testdata/sample.csv.gz",
+		"testdata/sample.csv.zst",
+		"testdata/sample.csv.bz2",
+		// No extension, so the compression is detected by the magic bytes.
+		"testdata/sample-gzip.dat",
+		"testdata/sample-bzip2.dat",
+	} {
+		t.Run(fileName, func(t *testing.T) {
+			assert := assert.New(t)
+
+			r, err := decompress.Open(fileName)
+			assert.Nil(err)
+			defer r.Close()
+
+			b, err := ioutil.ReadAll(r)
+			assert.Nil(err)
+			assert.Equal("a,b\n1,2\n", string(b))
+		})
+	}
+}
+
+func TestOpenStartingWithBZh(t *testing.T) {
+	assert := assert.New(t)
+
+	r, err := decompress.Open("testdata/bzh.csv")
+	assert.Nil(err)
+	defer r.Close()
+
+	b, err := ioutil.ReadAll(r)
+	assert.Nil(err)
+	assert.Equal("BZh9,name\n1,ann\n", string(b))
+}
+
+func TestTrimExt(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal("report.csv", decompress.TrimExt("report.csv.gz"))
+	assert.Equal("events.ndjson", decompress.TrimExt("events.ndjson.zst"))
+	assert.Equal("report.csv", decompress.TrimExt("report.csv"))
+}
+
+func TestOpenPlain(t *testing.T) {
+	tests := []struct {
+		fileName string
+		plain    bool
+	}{
+		{"testdata/sample.csv.gz", false},
+		{"testdata/sample-gzip.dat", false},
+		{"testdata/sample-bzip2.dat", false},
+		{"testdata/bzh.csv", true},
+		{"-", false},
+		{"decompress_test.go", true},
+	}
+	for _, test := range tests {
+		t.Run(test.fileName, func(t *testing.T) {
+			assert := assert.New(t)
+
+			f, err := decompress.OpenPlain(test.fileName)
+
+			assert.Nil(err)
+			assert.Equal(test.plain, f != nil)
+			if f != nil {
+				f.Close()
+			}
+		})
+	}
+}
@@ -44,7 +44,14 @@ type columnFormat struct {
 	format        string
 }
 
+// Print opens the plan, and prints each row as it is read.
 func (f *tableFormatter) Print(w io.Writer) {
+	if err := f.rowReader.Open(); err != nil {
+		fmt.Fprintf(w, "\n\n%+v\n\n", err)
+		return
+	}
+	defer f.rowReader.Close()
+
 	columnFormats := []columnFormat{}
 
 	for _, name := range f.rowReader.Columns() {
@@ -0,0 +1,165 @@
+package formatter
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+
+	"github.com/jacobsimpson/mtsql/csvfile"
+	"github.com/jacobsimpson/mtsql/metadata"
+	"github.com/jacobsimpson/mtsql/physical"
+)
+
+// Writer is the counterpart to Formatter for saving results rather than
+// displaying them. Write opens the plan, and returns the number of rows
+// written.
+type Writer interface {
+	Write(io.Writer) (int, error)
+}
+
+type csvWriter struct {
+	rowReader physical.RowReader
+	dialect   *metadata.CsvDialect
+	style     *csvfile.Style
+}
+
+// NewCsvWriter writes rows as CSV, in the dialect, with a header row if the
+// dialect has one. A nil dialect is the default comma
+// separated file with a header row.
+func NewCsvWriter(rowReader physical.RowReader, dialect *metadata.CsvDialect) Writer {
+	return NewStyledCsvWriter(rowReader, dialect, nil)
+}
+
+// NewStyledCsvWriter writes rows as CSV like NewCsvWriter, laid out in the
+// style of the file they are replacing.
+func NewStyledCsvWriter(rowReader physical.RowReader, dialect *metadata.CsvDialect, style *csvfile.Style) Writer {
+	if dialect == nil {
+		dialect = metadata.NewCsvDialect()
+	}
+	return &csvWriter{
+		rowReader: rowReader,
+		dialect:   dialect,
+		style:     style,
+	}
+}
+
+func (f *csvWriter) Write(w io.Writer) (int, error) {
+	if err := f.rowReader.Open(); err != nil {
+		return 0, err
+	}
+	defer f.rowReader.Close()
+
+	out, err := csvfile.NewStyledWriter(w, f.dialect, f.style)
+	if err != nil {
+		return 0, err
+	}
+	if f.dialect.Header {
+		header := []string{}
+		for _, c := range f.rowReader.Columns() {
+			header = append(header, c.Name)
+		}
+		if err := out.Write(header); err != nil {
+			return 0, err
+		}
+	}
+
+	count := 0
+	for {
+		row, err := f.rowReader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return count, err
+		}
+		if err := out.Write(row); err != nil {
+			return count, err
+		}
+		count++
+	}
+	return count, out.Flush()
+}
+
+type jsonWriter struct {
+	rowReader physical.RowReader
+	lines     bool
+}
+
+// NewJsonWriter writes rows as JSON objects, with a key for each column. The
+// objects are in an array for the JsonType, or one to a line for the
+// NdjsonType.
+func NewJsonWriter(rowReader physical.RowReader, relationType metadata.RelationType) Writer {
+	return &jsonWriter{
+		rowReader: rowReader,
+		lines:     relationType == metadata.NdjsonType,
+	}
+}
+
+func (f *jsonWriter) Write(w io.Writer) (int, error) {
+	if err := f.rowReader.Open(); err != nil {
+		return 0, err
+	}
+	defer f.rowReader.Close()
+
+	// Encode the keys once, and write the objects by hand, so the keys stay
+	// in the order of the columns.
+	keys := [][]byte{}
+	for _, c := range f.rowReader.Columns() {
+		key, err := json.Marshal(c.Name)
+		if err != nil {
+			return 0, err
+		}
+		keys = append(keys, key)
+	}
+
+	count := 0
+	var buf bytes.Buffer
+	for {
+		row, err := f.rowReader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return count, err
+		}
+		buf.Reset()
+		switch {
+		case f.lines:
+		case count == 0:
+			buf.WriteString("[\n")
+		default:
+			buf.WriteString(",\n")
+		}
+		buf.WriteByte('{')
+		for i, cell := range row {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			value, err := json.Marshal(cell)
+			if err != nil {
+				return count, err
+			}
+			buf.Write(keys[i])
+			buf.WriteByte(':')
+			buf.Write(value)
+		}
+		buf.WriteByte('}')
+		if f.lines {
+			buf.WriteByte('\n')
+		}
+		if _, err := w.Write(buf.Bytes()); err != nil {
+			return count, err
+		}
+		count++
+	}
+	if !f.lines {
+		end := "\n]\n"
+		if count == 0 {
+			end = "[]\n"
+		}
+		if _, err := io.WriteString(w, end); err != nil {
+			return count, err
+		}
+	}
+	return count, nil
+}
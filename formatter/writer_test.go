@@ -0,0 +1,81 @@
+package formatter
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jacobsimpson/mtsql/metadata"
+	"github.com/jacobsimpson/mtsql/physical"
+	"github.com/stretchr/testify/assert"
+)
+
+func newWriterTestScan() physical.RowReader {
+	return physical.NewMemoryScan(
+		[]*metadata.Column{
+			&metadata.Column{Qualifier: "tb1", Name: "name"},
+			&metadata.Column{Qualifier: "tb1", Name: "note"},
+		},
+		[][]string{
+			[]string{"Ann", "says \"hi\", twice"},
+			[]string{"Bob", ""},
+		},
+	)
+}
+
+func TestCsvWriter(t *testing.T) {
+	assert := assert.New(t)
+	var builder strings.Builder
+
+	count, err := NewCsvWriter(newWriterTestScan(), nil).Write(&builder)
+
+	assert.NoError(err)
+	assert.Equal(2, count)
+	assert.Equal("name,note\nAnn,\"says \"\"hi\"\", twice\"\nBob,\n", builder.String())
+}
+
+func TestCsvWriterDialect(t *testing.T) {
+	assert := assert.New(t)
+	dialect := metadata.NewCsvDialect()
+	dialect.Delimiter = '|'
+	dialect.Header = false
+	var builder strings.Builder
+
+	count, err := NewCsvWriter(newWriterTestScan(), dialect).Write(&builder)
+
+	assert.NoError(err)
+	assert.Equal(2, count)
+	assert.Equal("Ann|\"says \"\"hi\"\", twice\"\nBob|\n", builder.String())
+}
+
+func TestJsonWriter(t *testing.T) {
+	assert := assert.New(t)
+	var builder strings.Builder
+
+	count, err := NewJsonWriter(newWriterTestScan(), metadata.JsonType).Write(&builder)
+
+	assert.NoError(err)
+	assert.Equal(2, count)
+	assert.Equal("[\n{\"name\":\"Ann\",\"note\":\"says \\\"hi\\\", twice\"},\n{\"name\":\"Bob\",\"note\":\"\"}\n]\n", builder.String())
+}
+
+func TestNdjsonWriter(t *testing.T) {
+	assert := assert.New(t)
+	var builder strings.Builder
+
+	count, err := NewJsonWriter(newWriterTestScan(), metadata.NdjsonType).Write(&builder)
+
+	assert.NoError(err)
+	assert.Equal(2, count)
+	assert.Equal("{\"name\":\"Ann\",\"note\":\"says \\\"hi\\\", twice\"}\n{\"name\":\"Bob\",\"note\":\"\"}\n", builder.String())
+}
+
+func TestJsonWriterEmpty(t *testing.T) {
+	assert := assert.New(t)
+	var builder strings.Builder
+
+	count, err := NewJsonWriter(physical.NewMemoryScan(nil, nil), metadata.JsonType).Write(&builder)
+
+	assert.NoError(err)
+	assert.Equal(0, count)
+	assert.Equal("[]\n", builder.String())
+}
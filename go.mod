@@ -3,6 +3,8 @@ module github.com/jacobsimpson/mtsql
 go 1.13
 
 require (
+	github.com/fraugster/parquet-go v0.12.0
+	github.com/klauspost/compress v1.11.13
 	github.com/stretchr/testify v1.7.0
 	github.com/wsxiaoys/terminal v0.0.0-20160513160801-0940f3fc43a0
 	golang.org/x/crypto v0.4.0
@@ -0,0 +1,146 @@
+package index
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+)
+
+// Builder collects the values of a column, to write them out as an index.
+type Builder struct {
+	column  string
+	entries []entry
+}
+
+// NewBuilder starts an index of the column.
+func NewBuilder(column string) *Builder {
+	return &Builder{column: column}
+}
+
+// Add records the value of the column in the row starting at offset.
+func (b *Builder) Add(key string, offset int64) {
+	b.entries = append(b.entries, entry{key: key, offset: offset})
+}
+
+// Write sorts the values, and writes them as the index named name on the
+// file source. info describes the source as it was before any of its rows
+// were read, so the index is stale if the file changed while it was being
+// built. The index is written to a temporary file that is renamed into place
+// once it is complete.
+func (b *Builder) Write(source, name string, info os.FileInfo) (*Index, error) {
+	path := Path(source, name)
+	i := &Index{
+		Name:   name,
+		Path:   path,
+		Source: source,
+		header: header{
+			Column:  b.column,
+			Numeric: len(b.entries) > 0,
+			Rows:    int64(len(b.entries)),
+			Size:    info.Size(),
+			ModTime: info.ModTime().UnixNano(),
+		},
+	}
+	// NaN is a number to ParseFloat, but can't be ordered with the others.
+	for _, e := range b.entries {
+		if f, err := strconv.ParseFloat(e.key, 64); err != nil || math.IsNaN(f) {
+			i.Numeric = false
+			break
+		}
+	}
+	sort.SliceStable(b.entries, func(x, y int) bool {
+		return i.compare(b.entries[x].key, b.entries[y].key) < 0
+	})
+
+	// The blocks are written first, which gives the offset of the directory
+	// for the header.
+	blocks := []byte{}
+	var current []byte
+	var first string
+	for n, e := range b.entries {
+		if len(current) == 0 {
+			first = e.key
+		}
+		current = appendString(current, e.key)
+		current = appendUvarint(current, uint64(e.offset))
+		if len(current) >= blockSize || n == len(b.entries)-1 {
+			i.directory = append(i.directory, block{first: first, offset: int64(len(blocks)), length: int64(len(current))})
+			blocks = append(blocks, current...)
+			current = current[:0]
+		}
+	}
+
+	h, err := json.Marshal(&i.header)
+	if err != nil {
+		return nil, err
+	}
+	start := int64(len(magic) + len(h) + 1)
+	// The length of the header depends on the offset of the directory, so it
+	// is marshalled again until it stops changing.
+	for {
+		i.Directory = start + int64(len(blocks))
+		if h, err = json.Marshal(&i.header); err != nil {
+			return nil, err
+		}
+		if int64(len(magic)+len(h)+1) == start {
+			break
+		}
+		start = int64(len(magic) + len(h) + 1)
+	}
+	for n := range i.directory {
+		i.directory[n].offset += start
+	}
+	i.loaded = true
+
+	f, err := ioutil.TempFile(filepath.Dir(path), "."+filepath.Base(path)+".")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(f.Name())
+	w := bufio.NewWriter(f)
+	w.WriteString(magic)
+	w.Write(h)
+	w.WriteString("\n")
+	w.Write(blocks)
+	directory := []byte{}
+	for _, d := range i.directory {
+		directory = appendString(directory, d.first)
+		directory = appendUvarint(directory, uint64(d.offset))
+		directory = appendUvarint(directory, uint64(d.length))
+	}
+	w.Write(directory)
+	err = w.Flush()
+	if err == nil {
+		err = f.Sync()
+	}
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	if err := f.Close(); err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(f.Name(), 0644); err != nil {
+		return nil, err
+	}
+	if err := os.Rename(f.Name(), path); err != nil {
+		return nil, err
+	}
+	return i, nil
+}
+
+func appendString(b []byte, s string) []byte {
+	b = appendUvarint(b, uint64(len(s)))
+	return append(b, s...)
+}
+
+func appendUvarint(b []byte, v uint64) []byte {
+	var buf [binary.MaxVarintLen64]byte
+	return append(b, buf[:binary.PutUvarint(buf[:], v)]...)
+}
@@ -0,0 +1,295 @@
+// Package index builds and reads indexes of the values in a column of a CSV
+// file. An index is a file next to the CSV file, holding the values of the
+// column in sorted order with the offset of the row each came from, so the
+// rows with a value, or a range of values, can be read without reading the
+// rest of the file.
+//
+// The file starts with a header line describing the index, followed by the
+// entries in blocks, and a directory of the first value in each block. A
+// lookup reads the directory, and then only the blocks that can hold the
+// values it wants.
+package index
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// magic is the first line of an index file.
+const magic = "mtsql index 1\n"
+
+// blockSize is the size the blocks of entries are kept to.
+const blockSize = 4096
+
+// Path is the file an index named name on the CSV file source is kept in.
+func Path(source, name string) string {
+	return source + "." + name + ".idx"
+}
+
+// Index is an index of one column of a CSV file.
+type Index struct {
+	Name string
+	// Path is the file holding the index, and Source is the file indexed.
+	Path   string
+	Source string
+	header
+	directory []block
+	loaded    bool
+}
+
+// header is the first line of an index file, after the magic line. Size and
+// ModTime are those of the source when the index was built, and Directory is
+// where the directory starts.
+type header struct {
+	Column string
+	// Numeric is set when every value in the column is a number, and the
+	// entries are in numeric order rather than byte order.
+	Numeric   bool
+	Rows      int64
+	Size      int64
+	ModTime   int64
+	Directory int64
+}
+
+// block is an entry in the directory.
+type block struct {
+	first  string
+	offset int64
+	length int64
+}
+
+// entry is a value in the column, and the offset of its row.
+type entry struct {
+	key    string
+	offset int64
+}
+
+// Bound is one end of a range of values to look up. A Bound that isn't
+// Inclusive leaves out the rows with the value itself.
+type Bound struct {
+	Key       string
+	Inclusive bool
+}
+
+// Open reads the header of an index file.
+func Open(path string) (*Index, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	r := bufio.NewReader(f)
+	line, err := r.ReadString('\n')
+	if err != nil || line != magic {
+		return nil, fmt.Errorf("%s is not an index file", path)
+	}
+	line, err = r.ReadString('\n')
+	if err != nil {
+		return nil, fmt.Errorf("%s is not an index file", path)
+	}
+	i := &Index{Path: path}
+	if err := json.Unmarshal([]byte(line), &i.header); err != nil {
+		return nil, fmt.Errorf("%s is not an index file: %v", path, err)
+	}
+	return i, nil
+}
+
+// List finds the indexes on a CSV file, in order of their names. Files that
+// look like indexes but can't be read are left out.
+func List(source string) ([]*Index, error) {
+	dir, base := filepath.Split(source)
+	if dir == "" {
+		dir = "."
+	}
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	result := []*Index{}
+	for _, info := range infos {
+		name := info.Name()
+		if !strings.HasPrefix(name, base+".") || !strings.HasSuffix(name, ".idx") || len(name) <= len(base)+5 {
+			continue
+		}
+		i, err := Open(filepath.Join(dir, name))
+		if err != nil {
+			continue
+		}
+		i.Name = name[len(base)+1 : len(name)-4]
+		i.Source = source
+		result = append(result, i)
+	}
+	return result, nil
+}
+
+// Fresh reports whether the source is the same size, and has the same
+// modification time, as when the index was built. An index that isn't fresh
+// may not match the file, and must not be used.
+func (i *Index) Fresh() bool {
+	info, err := os.Stat(i.Source)
+	return err == nil && info.Size() == i.Size && info.ModTime().UnixNano() == i.ModTime
+}
+
+// Range returns the offsets of the rows with values between lower and upper,
+// in the order the rows are in the file. A nil bound leaves that end of the
+// range open. The keys of the bounds of a Numeric index must be numbers.
+func (i *Index) Range(lower, upper *Bound) ([]int64, error) {
+	if err := i.load(); err != nil {
+		return nil, err
+	}
+	compare := i.compare
+	if i.Numeric {
+		for _, b := range []*Bound{lower, upper} {
+			if b == nil {
+				continue
+			}
+			if _, err := strconv.ParseFloat(b.Key, 64); err != nil {
+				return nil, fmt.Errorf("index %s is on numbers, not %q", i.Name, b.Key)
+			}
+		}
+	}
+
+	// The rows with the lower value may start in the block before the first
+	// block starting with it.
+	start := 0
+	if lower != nil {
+		start = sort.Search(len(i.directory), func(n int) bool {
+			return compare(i.directory[n].first, lower.Key) >= 0
+		})
+		if start > 0 {
+			start--
+		}
+	}
+
+	f, err := os.Open(i.Path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	offsets := []int64{}
+	for _, b := range i.directory[start:] {
+		if upper != nil && compare(b.first, upper.Key) > 0 {
+			break
+		}
+		entries, err := b.read(f)
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range entries {
+			if lower != nil {
+				if c := compare(e.key, lower.Key); c < 0 || c == 0 && !lower.Inclusive {
+					continue
+				}
+			}
+			if upper != nil {
+				if c := compare(e.key, upper.Key); c > 0 || c == 0 && !upper.Inclusive {
+					continue
+				}
+			}
+			offsets = append(offsets, e.offset)
+		}
+	}
+	sort.Slice(offsets, func(a, b int) bool { return offsets[a] < offsets[b] })
+	return offsets, nil
+}
+
+// compare orders two values of the column.
+func (i *Index) compare(a, b string) int {
+	if i.Numeric {
+		x, _ := strconv.ParseFloat(a, 64)
+		y, _ := strconv.ParseFloat(b, 64)
+		switch {
+		case x < y:
+			return -1
+		case x > y:
+			return 1
+		}
+		return 0
+	}
+	return strings.Compare(a, b)
+}
+
+// load reads the directory of the index, the first time it is needed.
+func (i *Index) load() error {
+	if i.loaded {
+		return nil
+	}
+	f, err := os.Open(i.Path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	b := make([]byte, info.Size()-i.Directory)
+	if _, err := f.ReadAt(b, i.Directory); err != nil {
+		return err
+	}
+	r := bytes.NewReader(b)
+	directory := []block{}
+	for r.Len() > 0 {
+		first, err := readString(r)
+		if err != nil {
+			return fmt.Errorf("%s is damaged: %v", i.Path, err)
+		}
+		offset, err := binary.ReadUvarint(r)
+		if err != nil {
+			return fmt.Errorf("%s is damaged: %v", i.Path, err)
+		}
+		length, err := binary.ReadUvarint(r)
+		if err != nil {
+			return fmt.Errorf("%s is damaged: %v", i.Path, err)
+		}
+		directory = append(directory, block{first: first, offset: int64(offset), length: int64(length)})
+	}
+	i.directory = directory
+	i.loaded = true
+	return nil
+}
+
+// read reads the entries of a block.
+func (b block) read(f io.ReaderAt) ([]entry, error) {
+	buf := make([]byte, b.length)
+	if _, err := f.ReadAt(buf, b.offset); err != nil {
+		return nil, err
+	}
+	r := bytes.NewReader(buf)
+	entries := []entry{}
+	for r.Len() > 0 {
+		key, err := readString(r)
+		if err != nil {
+			return nil, err
+		}
+		offset, err := binary.ReadUvarint(r)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry{key: key, offset: int64(offset)})
+	}
+	return entries, nil
+}
+
+func readString(r *bytes.Reader) (string, error) {
+	n, err := binary.ReadUvarint(r)
+	if err != nil {
+		return "", err
+	}
+	if n > uint64(r.Len()) {
+		return "", io.ErrUnexpectedEOF
+	}
+	b := make([]byte, n)
+	r.Read(b)
+	return string(b), nil
+}
@@ -0,0 +1,118 @@
+package index
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// build writes an index of the keys, using the position of each key as the
+// offset of its row, on a file in a new directory.
+func build(t *testing.T, keys ...string) (*Index, string) {
+	dir, err := ioutil.TempDir("", "mtsql-index-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	source := filepath.Join(dir, "t.csv")
+	if err := ioutil.WriteFile(source, []byte("a\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(source)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := NewBuilder("a")
+	for n, k := range keys {
+		b.Add(k, int64(n))
+	}
+	i, err := b.Write(source, "i", info)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return i, dir
+}
+
+func TestRange(t *testing.T) {
+	words, dir := build(t, "b", "d", "a", "c", "b")
+	defer os.RemoveAll(dir)
+	numbers, dir := build(t, "10", "9", "-1", "2.5", "9")
+	defer os.RemoveAll(dir)
+
+	tests := []struct {
+		name     string
+		index    *Index
+		lower    *Bound
+		upper    *Bound
+		expected []int64
+	}{
+		{"= b", words, &Bound{"b", true}, &Bound{"b", true}, []int64{0, 4}},
+		{"= x", words, &Bound{"x", true}, &Bound{"x", true}, []int64{}},
+		{"< c", words, nil, &Bound{"c", false}, []int64{0, 2, 4}},
+		{">= c", words, &Bound{"c", true}, nil, []int64{1, 3}},
+		{"everything", words, nil, nil, []int64{0, 1, 2, 3, 4}},
+		{"= 9", numbers, &Bound{"9", true}, &Bound{"9", true}, []int64{1, 4}},
+		{"> 9", numbers, &Bound{"9", false}, nil, []int64{0}},
+		{"<= 2.5", numbers, nil, &Bound{"2.5", true}, []int64{2, 3}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			offsets, err := test.index.Range(test.lower, test.upper)
+			assert.Nil(t, err)
+			assert.Equal(t, test.expected, offsets)
+		})
+	}
+
+	assert.True(t, numbers.Numeric)
+	assert.False(t, words.Numeric)
+	_, err := numbers.Range(&Bound{"x", true}, nil)
+	assert.NotNil(t, err)
+}
+
+func TestRangeAcrossBlocks(t *testing.T) {
+	keys := []string{}
+	for n := 0; n < 5000; n++ {
+		keys = append(keys, fmt.Sprintf("key%05d", n/2))
+	}
+	i, dir := build(t, keys...)
+	defer os.RemoveAll(dir)
+
+	// Read the index back from the file, rather than using the directory the
+	// builder made.
+	i, err := Open(i.Path)
+	assert.Nil(t, err)
+	i.Name = "i"
+	offsets, err := i.Range(&Bound{"key01000", true}, &Bound{"key01001", true})
+	assert.Nil(t, err)
+	assert.Equal(t, []int64{2000, 2001, 2002, 2003}, offsets)
+	assert.True(t, len(i.directory) > 1)
+}
+
+func TestFreshAndList(t *testing.T) {
+	assert := assert.New(t)
+	i, dir := build(t, "a")
+	defer os.RemoveAll(dir)
+
+	indexes, err := List(i.Source)
+	assert.Nil(err)
+	if assert.Len(indexes, 1) {
+		assert.Equal("i", indexes[0].Name)
+		assert.Equal("a", indexes[0].Column)
+		assert.Equal(int64(1), indexes[0].Rows)
+		assert.True(indexes[0].Fresh())
+	}
+
+	assert.Nil(ioutil.WriteFile(i.Source, []byte("a\n"), 0644))
+	later := time.Now().Add(time.Minute)
+	assert.Nil(os.Chtimes(i.Source, later, later))
+	assert.False(i.Fresh())
+
+	assert.Nil(ioutil.WriteFile(filepath.Join(dir, "t.csv.junk.idx"), []byte("junk"), 0644))
+	indexes, err = List(i.Source)
+	assert.Nil(err)
+	assert.Len(indexes, 1)
+}
@@ -0,0 +1,240 @@
+package jsonfile
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/jacobsimpson/mtsql/decompress"
+	md "github.com/jacobsimpson/mtsql/metadata"
+)
+
+// InferenceRecords is the number of records read to determine the columns of
+// a JSON relation. Keys that first appear after that are not visible.
+const InferenceRecords = 100
+
+// Reader reads the top level objects of a JSON or newline delimited JSON
+// file as rows. Each top level key is a column, nested objects and arrays
+// are left as JSON text.
+type Reader struct {
+	closer   io.Closer
+	decoder  *json.Decoder
+	array    bool
+	header   []string
+	buffered []*record
+}
+
+// record is a decoded JSON object, along with the order its keys appeared in.
+type record struct {
+	values map[string]interface{}
+	keys   []string
+}
+
+func Open(fileName string, relationType md.RelationType) (*Reader, error) {
+	f, err := decompress.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+	r, err := NewReader(f, relationType)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	r.closer = f
+	return r, nil
+}
+
+// NewReader reads a JSON relation from a stream. A JsonType stream is a
+// single array of objects, an NdjsonType stream is a sequence of objects.
+func NewReader(stream io.Reader, relationType md.RelationType) (*Reader, error) {
+	r := &Reader{}
+	input := bufio.NewReader(stream)
+	switch relationType {
+	case md.JsonType:
+		first, err := firstByte(input)
+		if err != nil {
+			return nil, err
+		}
+		if first != '[' {
+			return nil, fmt.Errorf("expected a JSON array of objects, found %q", first)
+		}
+		r.decoder = json.NewDecoder(input)
+		if _, err := r.decoder.Token(); err != nil {
+			return nil, err
+		}
+		r.array = true
+	case md.NdjsonType:
+		r.decoder = json.NewDecoder(input)
+	default:
+		return nil, fmt.Errorf("%s is not a JSON relation type", relationType)
+	}
+	r.decoder.UseNumber()
+
+	seen := map[string]bool{}
+	for len(r.buffered) < InferenceRecords {
+		record, err := r.next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		r.buffered = append(r.buffered, record)
+		for _, k := range record.keys {
+			if !seen[k] {
+				seen[k] = true
+				r.header = append(r.header, k)
+			}
+		}
+	}
+	return r, nil
+}
+
+// Header returns the column names, in the order they were first seen.
+func (r *Reader) Header() []string {
+	return r.header
+}
+
+// Read returns the next record, or io.EOF when there are no more.
+func (r *Reader) Read() ([]string, error) {
+	var current *record
+	if len(r.buffered) > 0 {
+		current = r.buffered[0]
+		r.buffered = r.buffered[1:]
+	} else {
+		next, err := r.next()
+		if err != nil {
+			return nil, err
+		}
+		current = next
+	}
+	row := make([]string, len(r.header))
+	for i, k := range r.header {
+		row[i] = text(current.values[k])
+	}
+	return row, nil
+}
+
+func (r *Reader) Close() error {
+	if r.closer == nil {
+		return nil
+	}
+	return r.closer.Close()
+}
+
+// next decodes the next object. Go maps are unordered, so the object is
+// decoded a token at a time to keep the order of its keys.
+func (r *Reader) next() (*record, error) {
+	if r.array && !r.decoder.More() {
+		return nil, io.EOF
+	}
+	var raw json.RawMessage
+	if err := r.decoder.Decode(&raw); err != nil {
+		return nil, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	if t, err := decoder.Token(); err != nil {
+		return nil, err
+	} else if t != json.Delim('{') {
+		return nil, fmt.Errorf("expected a JSON object, found %s", raw)
+	}
+	result := &record{values: map[string]interface{}{}}
+	for decoder.More() {
+		t, err := decoder.Token()
+		if err != nil {
+			return nil, err
+		}
+		k := t.(string)
+		var v interface{}
+		if err := decoder.Decode(&v); err != nil {
+			return nil, err
+		}
+		if _, ok := result.values[k]; !ok {
+			result.keys = append(result.keys, k)
+		}
+		result.values[k] = v
+	}
+	return result, nil
+}
+
+func firstByte(input *bufio.Reader) (byte, error) {
+	for {
+		b, err := input.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		switch b {
+		case ' ', '\t', '\r', '\n':
+			continue
+		case 0xef:
+			// A UTF-8 byte order mark.
+			input.Discard(2)
+			continue
+		}
+		return b, input.UnreadByte()
+	}
+}
+
+// text converts a decoded JSON value to the string representation used for
+// rows. Null and missing values are empty, scalars are their text, and
+// objects and arrays are JSON.
+func text(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	case bool:
+		return strconv.FormatBool(v)
+	default:
+		b, err := json.Marshal(v)
+		if err != nil {
+			return ""
+		}
+		return string(b)
+	}
+}
+
+// Extract follows a path of object keys and array indexes into a JSON value.
+// With asJSON set, the result is JSON text, like the -> operator, otherwise
+// scalars are converted to plain text, like ->>. A value that is not JSON or
+// a path that does not exist results in an empty string.
+func Extract(value string, path []string, asJSON bool) string {
+	decoder := json.NewDecoder(bytes.NewReader([]byte(value)))
+	decoder.UseNumber()
+	var v interface{}
+	if err := decoder.Decode(&v); err != nil {
+		return ""
+	}
+	for _, p := range path {
+		switch c := v.(type) {
+		case map[string]interface{}:
+			v = c[p]
+		case []interface{}:
+			i, err := strconv.Atoi(p)
+			if err != nil || i < 0 || i >= len(c) {
+				return ""
+			}
+			v = c[i]
+		default:
+			return ""
+		}
+	}
+	if asJSON {
+		if v == nil {
+			return ""
+		}
+		b, err := json.Marshal(v)
+		if err != nil {
+			return ""
+		}
+		return string(b)
+	}
+	return text(v)
+}
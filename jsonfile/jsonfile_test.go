@@ -0,0 +1,69 @@
+package jsonfile_test
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/jacobsimpson/mtsql/jsonfile"
+	md "github.com/jacobsimpson/mtsql/metadata"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadJson(t *testing.T) {
+	tests := []struct {
+		name         string
+		input        string
+		relationType md.RelationType
+		header       []string
+		expected     [][]string
+	}{
+		{
+			name:         "json array",
+			input:        `[{"b": "x", "a": 1}, {"a": 2.5, "c": null}]`,
+			relationType: md.JsonType,
+			header:       []string{"b", "a", "c"},
+			expected:     [][]string{{"x", "1", ""}, {"", "2.5", ""}},
+		},
+		{
+			name:         "newline delimited",
+			input:        "{\"id\": 1, \"ok\": true}\n{\"id\": 2, \"user\": {\"id\": \"u2\"}}\n",
+			relationType: md.NdjsonType,
+			header:       []string{"id", "ok", "user"},
+			expected:     [][]string{{"1", "true", ""}, {"2", "", `{"id":"u2"}`}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			r, err := jsonfile.NewReader(strings.NewReader(test.input), test.relationType)
+			assert.Nil(err)
+			assert.Equal(test.header, r.Header())
+
+			rows := [][]string{}
+			for {
+				row, err := r.Read()
+				if err == io.EOF {
+					break
+				}
+				assert.Nil(err)
+				rows = append(rows, row)
+			}
+			assert.Equal(test.expected, rows)
+		})
+	}
+}
+
+func TestExtract(t *testing.T) {
+	assert := assert.New(t)
+
+	value := `{"user": {"id": "u1", "tags": ["a", "b"]}}`
+	assert.Equal("u1", jsonfile.Extract(value, []string{"user", "id"}, false))
+	assert.Equal(`"u1"`, jsonfile.Extract(value, []string{"user", "id"}, true))
+	assert.Equal("b", jsonfile.Extract(value, []string{"user", "tags", "1"}, false))
+	assert.Equal(`["a","b"]`, jsonfile.Extract(value, []string{"user", "tags"}, false))
+	assert.Equal("", jsonfile.Extract(value, []string{"missing"}, false))
+	assert.Equal("", jsonfile.Extract("not json", []string{"user"}, false))
+}
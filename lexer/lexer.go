@@ -3,21 +3,40 @@ package lexer
 import (
 	"fmt"
 	"io"
+	"strings"
 )
 
 type Type string
 
 const (
-	CommaType      Type = "Comma"
-	EOFType        Type = "EOF"
-	EqualType      Type = "Equal"
-	ErrorType      Type = "Error"
-	IdentifierType Type = "Identifier"
-	PeriodType     Type = "Period"
-	IntegerType    Type = "Integer"
-	StringType     Type = "String"
-	StarType       Type = "Star"
-	WhitespaceType Type = "Whitespace"
+	ArrowType            Type = "Arrow"
+	CommaType            Type = "Comma"
+	CommentType          Type = "Comment"
+	ConcatType           Type = "Concat"
+	DoubleArrowType      Type = "DoubleArrow"
+	EOFType              Type = "EOF"
+	EqualType            Type = "Equal"
+	ErrorType            Type = "Error"
+	FloatType            Type = "Float"
+	GreaterEqualType     Type = "GreaterEqual"
+	GreaterType          Type = "Greater"
+	IdentifierType       Type = "Identifier"
+	IntegerType          Type = "Integer"
+	LeftParenType        Type = "LeftParen"
+	LessEqualType        Type = "LessEqual"
+	LessType             Type = "Less"
+	MinusType            Type = "Minus"
+	NotEqualType         Type = "NotEqual"
+	ParameterType        Type = "Parameter"
+	PeriodType           Type = "Period"
+	PlusType             Type = "Plus"
+	QuotedIdentifierType Type = "QuotedIdentifier"
+	RightParenType       Type = "RightParen"
+	SemicolonType        Type = "Semicolon"
+	SlashType            Type = "Slash"
+	StarType             Type = "Star"
+	StringType           Type = "String"
+	WhitespaceType       Type = "Whitespace"
 )
 
 func (t Type) String() string {
@@ -30,9 +49,27 @@ type Lexer interface {
 	UnreadToken() error
 }
 
+// Token is a piece of the input. Raw is the text exactly as it appeared, and
+// for strings and quoted identifiers, Value is the text without the quotes
+// and with escaped quotes replaced. Line and Column, both starting at 1, are
+// where the token begins.
 type Token struct {
-	Raw  string
-	Type Type
+	Raw    string
+	Value  string
+	Type   Type
+	Line   int
+	Column int
+}
+
+// position is a line and column in the input.
+type position struct {
+	line, column int
+}
+
+// char is a rune read from the input, along with where it was found.
+type char struct {
+	r   rune
+	pos position
 }
 
 type tokenizer struct {
@@ -41,15 +78,29 @@ type tokenizer struct {
 	previous       *Token
 	unread         bool
 	skipWhitespace bool
+	// pos is the position of the next rune to be read.
+	pos position
+	// history is the last few runes read, so they can be unread, and pending
+	// is the runes that have been unread, to be read again.
+	history []char
+	pending []char
+	// operand is true when the last significant token could be the left hand
+	// side of a binary operator, which decides whether "-" is subtraction or
+	// the sign of a number.
+	operand bool
 }
 
+// maxHistory is the number of runes that can be unread.
+const maxHistory = 4
+
 func New(stream io.RuneScanner) Lexer {
-	return &tokenizer{stream: stream}
+	return &tokenizer{stream: stream, pos: position{1, 1}}
 }
 
 func NewFilterWhitespace(stream io.RuneScanner) Lexer {
 	return &tokenizer{stream: stream,
 		skipWhitespace: true,
+		pos:            position{1, 1},
 	}
 }
 
@@ -61,14 +112,23 @@ func (l *tokenizer) Next() bool {
 
 	var token *Token
 	for {
+		start := l.pos
 		// Lex until there is a complete token.
 		for next := l.initial; next != nil; token, next = next() {
 		}
+		token.Line, token.Column = start.line, start.column
 		// If keeping whitespace, or the token is not whitespace, break out.
-		if !l.skipWhitespace || token.Type != WhitespaceType {
+		if !l.skipWhitespace || (token.Type != WhitespaceType && token.Type != CommentType) {
 			break
 		}
 	}
+	switch token.Type {
+	case WhitespaceType, CommentType:
+	case IdentifierType, QuotedIdentifierType, IntegerType, FloatType, StringType, ParameterType, RightParenType:
+		l.operand = true
+	default:
+		l.operand = false
+	}
 
 	l.previous = l.current
 	l.current = token
@@ -77,8 +137,56 @@ func (l *tokenizer) Next() bool {
 
 type lexerFn func() (*Token, lexerFn)
 
+// readRune returns the next rune of the input.
+func (l *tokenizer) readRune() (rune, error) {
+	var c char
+	if n := len(l.pending); n > 0 {
+		c = l.pending[n-1]
+		l.pending = l.pending[:n-1]
+	} else {
+		r, _, err := l.stream.ReadRune()
+		if err != nil {
+			return r, err
+		}
+		c = char{r: r, pos: l.pos}
+	}
+	l.history = append(l.history, c)
+	if len(l.history) > maxHistory {
+		l.history = l.history[1:]
+	}
+	if c.r == '\n' {
+		l.pos = position{c.pos.line + 1, 1}
+	} else {
+		l.pos = position{c.pos.line, c.pos.column + 1}
+	}
+	return c.r, nil
+}
+
+// unreadRune puts the last rune read back, so it is the next one read. Unreading
+// at the end of the input does nothing.
+func (l *tokenizer) unreadRune() {
+	n := len(l.history)
+	if n == 0 {
+		return
+	}
+	c := l.history[n-1]
+	l.history = l.history[:n-1]
+	l.pending = append(l.pending, c)
+	l.pos = c.pos
+}
+
+// peekRune returns the next rune of the input without reading it.
+func (l *tokenizer) peekRune() (rune, bool) {
+	r, err := l.readRune()
+	if err != nil {
+		return 0, false
+	}
+	l.unreadRune()
+	return r, true
+}
+
 func (l *tokenizer) initial() (*Token, lexerFn) {
-	r, _, err := l.stream.ReadRune()
+	r, err := l.readRune()
 	if err == io.EOF {
 		return &Token{Type: EOFType}, nil
 	}
@@ -88,26 +196,55 @@ func (l *tokenizer) initial() (*Token, lexerFn) {
 			Raw:  fmt.Sprintf("unable to read rune: %+v", err),
 		}, nil
 	}
-	if r == ' ' || r == '\n' {
-		l.stream.UnreadRune()
+	next, _ := l.peekRune()
+	if isWhitespace(r) {
+		l.unreadRune()
 		return nil, l.whitespace
-	} else if 'a' <= r && r <= 'z' || 'A' <= r && r <= 'Z' {
-		l.stream.UnreadRune()
+	} else if 'a' <= r && r <= 'z' || 'A' <= r && r <= 'Z' || r == '_' {
+		l.unreadRune()
 		return nil, l.identifier
-	} else if '0' <= r && r <= '9' {
-		l.stream.UnreadRune()
+	} else if '0' <= r && r <= '9' || r == '.' && isDigit(next) {
+		l.unreadRune()
 		return nil, l.number
 	} else if '\'' == r {
-		l.stream.UnreadRune()
+		l.unreadRune()
 		return nil, l.string
-	} else if r == '.' {
-		return &Token{Type: PeriodType, Raw: "."}, nil
-	} else if r == ',' {
-		return &Token{Type: CommaType, Raw: ","}, nil
-	} else if r == '=' {
-		return &Token{Type: EqualType, Raw: "="}, nil
-	} else if r == '*' {
-		return &Token{Type: StarType, Raw: "*"}, nil
+	} else if '"' == r {
+		l.unreadRune()
+		return nil, l.quotedIdentifier
+	} else if r == '-' && next == '-' {
+		l.unreadRune()
+		return nil, l.lineComment
+	} else if r == '/' && next == '*' {
+		l.unreadRune()
+		return nil, l.blockComment
+	} else if r == '-' && next == '>' {
+		l.unreadRune()
+		return nil, l.arrow
+	} else if r == '-' && isDigit(next) && !l.operand {
+		l.unreadRune()
+		return nil, l.number
+	} else if r == '?' {
+		return &Token{Type: ParameterType, Raw: "?"}, nil
+	} else if r == '$' && isDigit(next) {
+		return &Token{Type: ParameterType, Raw: "$" + l.digits()}, nil
+	} else if r == ':' && ('a' <= next && next <= 'z' || 'A' <= next && next <= 'Z' || next == '_') {
+		name, _ := l.identifier()
+		return &Token{Type: ParameterType, Raw: ":" + name.Raw}, nil
+	} else if r == '<' || r == '>' || r == '!' {
+		l.unreadRune()
+		return nil, l.comparison
+	} else if r == '|' {
+		if next != '|' {
+			return &Token{
+				Type: ErrorType,
+				Raw:  "unrecognized char while tokenizing: '|'",
+			}, nil
+		}
+		l.readRune()
+		return &Token{Type: ConcatType, Raw: "||"}, nil
+	} else if t, ok := punctuation[r]; ok {
+		return &Token{Type: t, Raw: string(r)}, nil
 	} else {
 		return &Token{
 			Type: ErrorType,
@@ -116,12 +253,33 @@ func (l *tokenizer) initial() (*Token, lexerFn) {
 	}
 }
 
+// punctuation is the tokens made of a single character.
+var punctuation = map[rune]Type{
+	'.': PeriodType,
+	',': CommaType,
+	'=': EqualType,
+	'*': StarType,
+	'(': LeftParenType,
+	')': RightParenType,
+	'+': PlusType,
+	'-': MinusType,
+	'/': SlashType,
+	';': SemicolonType,
+}
+
+func isWhitespace(r rune) bool {
+	return r == ' ' || r == '\t' || r == '\r' || r == '\n'
+}
+
+func isDigit(r rune) bool {
+	return '0' <= r && r <= '9'
+}
+
 func (l *tokenizer) identifier() (*Token, lexerFn) {
 	raw := ""
 	for {
-		r, _, err := l.stream.ReadRune()
+		r, err := l.readRune()
 		if err == io.EOF {
-			l.stream.UnreadRune()
 			return &Token{Type: IdentifierType, Raw: raw}, nil
 		}
 		if err != nil {
@@ -133,67 +291,206 @@ func (l *tokenizer) identifier() (*Token, lexerFn) {
 			r == '_' {
 			raw += string(r)
 		} else {
-			l.stream.UnreadRune()
+			l.unreadRune()
 			return &Token{Type: IdentifierType, Raw: raw}, nil
 		}
 	}
 }
 
+// number lexes integers and floats, with an optional leading minus sign, a
+// fraction and an exponent: -12, 1.5, .5 and 2e-3.
 func (l *tokenizer) number() (*Token, lexerFn) {
 	raw := ""
-	for {
-		r, _, err := l.stream.ReadRune()
-		if err == io.EOF {
-			l.stream.UnreadRune()
-			return &Token{Type: IntegerType, Raw: raw}, nil
+	if r, _ := l.peekRune(); r == '-' {
+		l.readRune()
+		raw += "-"
+	}
+	raw += l.digits()
+	t := IntegerType
+	if r, _ := l.peekRune(); r == '.' {
+		l.readRune()
+		if r, _ := l.peekRune(); isDigit(r) || raw != "" && raw != "-" {
+			raw += "." + l.digits()
+			t = FloatType
+		} else {
+			l.unreadRune()
 		}
-		if err != nil {
-			return &Token{Type: ErrorType}, nil
+	}
+	if r, _ := l.peekRune(); r == 'e' || r == 'E' {
+		l.readRune()
+		exponent := string(r)
+		if r, _ := l.peekRune(); r == '+' || r == '-' {
+			l.readRune()
+			exponent += string(r)
 		}
-		if '0' <= r && r <= '9' {
-			raw += string(r)
+		if r, _ := l.peekRune(); isDigit(r) {
+			raw += exponent + l.digits()
+			t = FloatType
 		} else {
-			l.stream.UnreadRune()
-			return &Token{Type: IntegerType, Raw: raw}, nil
+			// Not an exponent after all, so leave it for the next token.
+			for range exponent {
+				l.unreadRune()
+			}
+		}
+	}
+	return &Token{Type: t, Raw: raw}, nil
+}
+
+// digits reads a run of digits.
+func (l *tokenizer) digits() string {
+	raw := ""
+	for {
+		r, err := l.readRune()
+		if err != nil {
+			return raw
 		}
+		if !isDigit(r) {
+			l.unreadRune()
+			return raw
+		}
+		raw += string(r)
 	}
 }
 
 func (l *tokenizer) string() (*Token, lexerFn) {
-	r, _, _ := l.stream.ReadRune()
-	raw := string(r)
+	raw, value, ok := l.quoted()
+	if !ok {
+		return &Token{
+			Type: ErrorType,
+			Raw:  fmt.Sprintf("unterminated string %s", raw),
+		}, nil
+	}
+	return &Token{Type: StringType, Raw: raw, Value: value}, nil
+}
+
+// quotedIdentifier lexes a name in double quotes, which may contain any
+// character. A double quote is written as two double quotes.
+func (l *tokenizer) quotedIdentifier() (*Token, lexerFn) {
+	raw, value, ok := l.quoted()
+	if !ok {
+		return &Token{
+			Type: ErrorType,
+			Raw:  fmt.Sprintf("unterminated quoted identifier %s", raw),
+		}, nil
+	}
+	return &Token{Type: QuotedIdentifierType, Raw: raw, Value: value}, nil
+}
+
+// quoted reads text surrounded by the quote character it starts with, where
+// two quote characters in a row stand for one. It returns the raw text, the
+// text it stands for, and false if the input ended before the closing quote.
+func (l *tokenizer) quoted() (string, string, bool) {
+	quote, _ := l.readRune()
+	var raw, value strings.Builder
+	raw.WriteRune(quote)
 	for {
-		r, _, err := l.stream.ReadRune()
-		if err == io.EOF {
-			l.stream.UnreadRune()
-			return &Token{Type: IdentifierType, Raw: raw}, nil
+		r, err := l.readRune()
+		if err != nil {
+			return raw.String(), value.String(), false
+		}
+		raw.WriteRune(r)
+		if r != quote {
+			value.WriteRune(r)
+			continue
+		}
+		if next, ok := l.peekRune(); !ok || next != quote {
+			return raw.String(), value.String(), true
 		}
+		l.readRune()
+		raw.WriteRune(quote)
+		value.WriteRune(quote)
+	}
+}
+
+// lineComment lexes a comment from -- to the end of the line.
+func (l *tokenizer) lineComment() (*Token, lexerFn) {
+	raw := ""
+	for {
+		r, err := l.readRune()
 		if err != nil {
-			return &Token{Type: ErrorType}, nil
+			return &Token{Type: CommentType, Raw: raw}, nil
+		}
+		if r == '\n' {
+			l.unreadRune()
+			return &Token{Type: CommentType, Raw: raw}, nil
+		}
+		raw += string(r)
+	}
+}
+
+// blockComment lexes a comment from /* to */.
+func (l *tokenizer) blockComment() (*Token, lexerFn) {
+	l.readRune()
+	l.readRune()
+	raw := "/*"
+	for {
+		r, err := l.readRune()
+		if err != nil {
+			return &Token{Type: ErrorType, Raw: "unterminated comment"}, nil
 		}
 		raw += string(r)
-		if '\'' == r {
-			return &Token{Type: StringType, Raw: raw}, nil
+		if r == '*' {
+			if next, _ := l.peekRune(); next == '/' {
+				l.readRune()
+				return &Token{Type: CommentType, Raw: raw + "/"}, nil
+			}
 		}
 	}
 }
 
+// comparison lexes <, >, <=, >=, <> and !=.
+func (l *tokenizer) comparison() (*Token, lexerFn) {
+	r, _ := l.readRune()
+	next, _ := l.peekRune()
+	switch {
+	case r == '<' && next == '=':
+		l.readRune()
+		return &Token{Type: LessEqualType, Raw: "<="}, nil
+	case r == '<' && next == '>':
+		l.readRune()
+		return &Token{Type: NotEqualType, Raw: "<>"}, nil
+	case r == '>' && next == '=':
+		l.readRune()
+		return &Token{Type: GreaterEqualType, Raw: ">="}, nil
+	case r == '!' && next == '=':
+		l.readRune()
+		return &Token{Type: NotEqualType, Raw: "!="}, nil
+	case r == '<':
+		return &Token{Type: LessType, Raw: "<"}, nil
+	case r == '>':
+		return &Token{Type: GreaterType, Raw: ">"}, nil
+	}
+	return &Token{
+		Type: ErrorType,
+		Raw:  fmt.Sprintf("unrecognized char while tokenizing: %q", r),
+	}, nil
+}
+
+// arrow lexes the JSON path operators -> and ->>.
+func (l *tokenizer) arrow() (*Token, lexerFn) {
+	l.readRune()
+	l.readRune()
+	if r, _ := l.peekRune(); r == '>' {
+		l.readRune()
+		return &Token{Type: DoubleArrowType, Raw: "->>"}, nil
+	}
+	return &Token{Type: ArrowType, Raw: "->"}, nil
+}
+
 func (l *tokenizer) whitespace() (*Token, lexerFn) {
 	raw := ""
 	for {
-		r, _, err := l.stream.ReadRune()
+		r, err := l.readRune()
 		if err == io.EOF {
-			l.stream.UnreadRune()
 			return &Token{Type: WhitespaceType, Raw: raw}, nil
 		}
 		if err != nil {
 			return &Token{Type: ErrorType}, nil
 		}
-		switch r {
-		case ' ', '\n':
+		if isWhitespace(r) {
 			raw += string(r)
-		default:
-			l.stream.UnreadRune()
+		} else {
+			l.unreadRune()
 			return &Token{Type: WhitespaceType, Raw: raw}, nil
 		}
 	}
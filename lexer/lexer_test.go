@@ -68,6 +68,38 @@ func TestLexIdentifierUnread(t *testing.T) {
 
 }
 
+func TestLexLeadingUnderscoreIdentifier(t *testing.T) {
+	assert := assert.New(t)
+	l := lexer.New(strings.NewReader("_filename"))
+
+	l.Next()
+	token := l.Token()
+
+	assert.Equal(lexer.IdentifierType, token.Type)
+	assert.Equal("_filename", token.Raw)
+}
+
+func TestLexJsonArrows(t *testing.T) {
+	assert := assert.New(t)
+	l := lexer.New(strings.NewReader("a->'b'->>'c'"))
+	expected := []lexer.Token{
+		{Type: lexer.IdentifierType, Raw: "a"},
+		{Type: lexer.ArrowType, Raw: "->"},
+		{Type: lexer.StringType, Raw: "'b'"},
+		{Type: lexer.DoubleArrowType, Raw: "->>"},
+		{Type: lexer.StringType, Raw: "'c'"},
+		{Type: lexer.EOFType, Raw: ""},
+	}
+
+	for _, t := range expected {
+		l.Next()
+		token := l.Token()
+
+		assert.Equal(t.Type, token.Type)
+		assert.Equal(t.Raw, token.Raw)
+	}
+}
+
 func TestLexIdentifier(t *testing.T) {
 	assert := assert.New(t)
 	l := lexer.New(strings.NewReader("SELECT"))
@@ -185,3 +217,188 @@ func TestLexQualifiedIdentifier(t *testing.T) {
 		assert.Equal(t.Raw, token.Raw)
 	}
 }
+
+func TestLexPositions(t *testing.T) {
+	assert := assert.New(t)
+	l := lexer.NewFilterWhitespace(strings.NewReader("SELECT a,\r\n\tb -- note\nFROM /* multi\nline */ t"))
+	expected := []lexer.Token{
+		lexer.Token{Type: lexer.IdentifierType, Raw: "SELECT", Line: 1, Column: 1},
+		lexer.Token{Type: lexer.IdentifierType, Raw: "a", Line: 1, Column: 8},
+		lexer.Token{Type: lexer.CommaType, Raw: ",", Line: 1, Column: 9},
+		lexer.Token{Type: lexer.IdentifierType, Raw: "b", Line: 2, Column: 2},
+		lexer.Token{Type: lexer.IdentifierType, Raw: "FROM", Line: 3, Column: 1},
+		lexer.Token{Type: lexer.IdentifierType, Raw: "t", Line: 4, Column: 9},
+		lexer.Token{Type: lexer.EOFType, Raw: "", Line: 4, Column: 10},
+	}
+
+	for _, t := range expected {
+		l.Next()
+		token := l.Token()
+
+		assert.Equal(t.Type, token.Type)
+		assert.Equal(t.Raw, token.Raw)
+		assert.Equal(t.Line, token.Line, t.Raw)
+		assert.Equal(t.Column, token.Column, t.Raw)
+	}
+}
+
+func TestLexComments(t *testing.T) {
+	assert := assert.New(t)
+	l := lexer.New(strings.NewReader("a -- one\n/* two */b"))
+	expected := []lexer.Token{
+		lexer.Token{Type: lexer.IdentifierType, Raw: "a"},
+		lexer.Token{Type: lexer.WhitespaceType, Raw: " "},
+		lexer.Token{Type: lexer.CommentType, Raw: "-- one"},
+		lexer.Token{Type: lexer.WhitespaceType, Raw: "\n"},
+		lexer.Token{Type: lexer.CommentType, Raw: "/* two */"},
+		lexer.Token{Type: lexer.IdentifierType, Raw: "b"},
+		lexer.Token{Type: lexer.EOFType, Raw: ""},
+	}
+
+	for _, t := range expected {
+		l.Next()
+		token := l.Token()
+
+		assert.Equal(t.Type, token.Type)
+		assert.Equal(t.Raw, token.Raw)
+	}
+}
+
+func TestLexUnterminatedComment(t *testing.T) {
+	assert := assert.New(t)
+	l := lexer.NewFilterWhitespace(strings.NewReader("a /* b"))
+
+	l.Next()
+	l.Next()
+	assert.Equal(lexer.ErrorType, l.Token().Type)
+}
+
+func TestLexQuoted(t *testing.T) {
+	assert := assert.New(t)
+	l := lexer.NewFilterWhitespace(strings.NewReader(`"Zip Code" 'it''s' "say ""hi""" ''`))
+	expected := []lexer.Token{
+		lexer.Token{Type: lexer.QuotedIdentifierType, Raw: `"Zip Code"`, Value: "Zip Code"},
+		lexer.Token{Type: lexer.StringType, Raw: `'it''s'`, Value: "it's"},
+		lexer.Token{Type: lexer.QuotedIdentifierType, Raw: `"say ""hi"""`, Value: `say "hi"`},
+		lexer.Token{Type: lexer.StringType, Raw: `''`, Value: ""},
+		lexer.Token{Type: lexer.EOFType, Raw: ""},
+	}
+
+	for _, t := range expected {
+		l.Next()
+		token := l.Token()
+
+		assert.Equal(t.Type, token.Type)
+		assert.Equal(t.Raw, token.Raw)
+		assert.Equal(t.Value, token.Value)
+	}
+}
+
+func TestLexNumbers(t *testing.T) {
+	assert := assert.New(t)
+	l := lexer.NewFilterWhitespace(strings.NewReader("1.5, .25, -3, -0.5, 2e10, 1.5E-3, 7 -2, (2)-1 = -4"))
+	expected := []lexer.Token{
+		lexer.Token{Type: lexer.FloatType, Raw: "1.5"},
+		lexer.Token{Type: lexer.CommaType, Raw: ","},
+		lexer.Token{Type: lexer.FloatType, Raw: ".25"},
+		lexer.Token{Type: lexer.CommaType, Raw: ","},
+		lexer.Token{Type: lexer.IntegerType, Raw: "-3"},
+		lexer.Token{Type: lexer.CommaType, Raw: ","},
+		lexer.Token{Type: lexer.FloatType, Raw: "-0.5"},
+		lexer.Token{Type: lexer.CommaType, Raw: ","},
+		lexer.Token{Type: lexer.FloatType, Raw: "2e10"},
+		lexer.Token{Type: lexer.CommaType, Raw: ","},
+		lexer.Token{Type: lexer.FloatType, Raw: "1.5E-3"},
+		lexer.Token{Type: lexer.CommaType, Raw: ","},
+		lexer.Token{Type: lexer.IntegerType, Raw: "7"},
+		lexer.Token{Type: lexer.MinusType, Raw: "-"},
+		lexer.Token{Type: lexer.IntegerType, Raw: "2"},
+		lexer.Token{Type: lexer.CommaType, Raw: ","},
+		lexer.Token{Type: lexer.LeftParenType, Raw: "("},
+		lexer.Token{Type: lexer.IntegerType, Raw: "2"},
+		lexer.Token{Type: lexer.RightParenType, Raw: ")"},
+		lexer.Token{Type: lexer.MinusType, Raw: "-"},
+		lexer.Token{Type: lexer.IntegerType, Raw: "1"},
+		lexer.Token{Type: lexer.EqualType, Raw: "="},
+		lexer.Token{Type: lexer.IntegerType, Raw: "-4"},
+		lexer.Token{Type: lexer.EOFType, Raw: ""},
+	}
+
+	for _, t := range expected {
+		l.Next()
+		token := l.Token()
+
+		assert.Equal(t.Type, token.Type)
+		assert.Equal(t.Raw, token.Raw)
+	}
+}
+
+func TestLexOperators(t *testing.T) {
+	assert := assert.New(t)
+	l := lexer.NewFilterWhitespace(strings.NewReader("< > <= >= <> != + - / ; || ( )"))
+	expected := []lexer.Token{
+		lexer.Token{Type: lexer.LessType, Raw: "<"},
+		lexer.Token{Type: lexer.GreaterType, Raw: ">"},
+		lexer.Token{Type: lexer.LessEqualType, Raw: "<="},
+		lexer.Token{Type: lexer.GreaterEqualType, Raw: ">="},
+		lexer.Token{Type: lexer.NotEqualType, Raw: "<>"},
+		lexer.Token{Type: lexer.NotEqualType, Raw: "!="},
+		lexer.Token{Type: lexer.PlusType, Raw: "+"},
+		lexer.Token{Type: lexer.MinusType, Raw: "-"},
+		lexer.Token{Type: lexer.SlashType, Raw: "/"},
+		lexer.Token{Type: lexer.SemicolonType, Raw: ";"},
+		lexer.Token{Type: lexer.ConcatType, Raw: "||"},
+		lexer.Token{Type: lexer.LeftParenType, Raw: "("},
+		lexer.Token{Type: lexer.RightParenType, Raw: ")"},
+		lexer.Token{Type: lexer.EOFType, Raw: ""},
+	}
+
+	for _, t := range expected {
+		l.Next()
+		token := l.Token()
+
+		assert.Equal(t.Type, token.Type)
+		assert.Equal(t.Raw, token.Raw)
+	}
+}
+
+func TestLexUnterminatedString(t *testing.T) {
+	assert := assert.New(t)
+	l := lexer.NewFilterWhitespace(strings.NewReader("a = 'abc"))
+
+	l.Next()
+	l.Next()
+	l.Next()
+	token := l.Token()
+
+	assert.Equal(lexer.ErrorType, token.Type)
+	assert.Equal("unterminated string 'abc", token.Raw)
+	assert.Equal(5, token.Column)
+}
+
+func TestLexParameters(t *testing.T) {
+	assert := assert.New(t)
+	l := lexer.NewFilterWhitespace(strings.NewReader("a = ? AND b = $12 -1, :state_1"))
+	expected := []lexer.Token{
+		lexer.Token{Type: lexer.IdentifierType, Raw: "a"},
+		lexer.Token{Type: lexer.EqualType, Raw: "="},
+		lexer.Token{Type: lexer.ParameterType, Raw: "?"},
+		lexer.Token{Type: lexer.IdentifierType, Raw: "AND"},
+		lexer.Token{Type: lexer.IdentifierType, Raw: "b"},
+		lexer.Token{Type: lexer.EqualType, Raw: "="},
+		lexer.Token{Type: lexer.ParameterType, Raw: "$12"},
+		lexer.Token{Type: lexer.MinusType, Raw: "-"},
+		lexer.Token{Type: lexer.IntegerType, Raw: "1"},
+		lexer.Token{Type: lexer.CommaType, Raw: ","},
+		lexer.Token{Type: lexer.ParameterType, Raw: ":state_1"},
+		lexer.Token{Type: lexer.EOFType, Raw: ""},
+	}
+
+	for _, t := range expected {
+		l.Next()
+		token := l.Token()
+
+		assert.Equal(t.Type, token.Type)
+		assert.Equal(t.Raw, token.Raw)
+	}
+}
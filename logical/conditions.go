@@ -0,0 +1,69 @@
+package logical
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jacobsimpson/mtsql/ast"
+	md "github.com/jacobsimpson/mtsql/metadata"
+)
+
+// Condition is a predicate over the columns of a row, applied by a Selection.
+type Condition interface {
+	Requires() []*md.Column
+	String() string
+}
+
+// EqualConstant is true when the column is equal to a constant value.
+type EqualConstant struct {
+	Column *md.Column
+	Value  *ast.Constant
+}
+
+// EqualColumns is true when two columns have the same value.
+type EqualColumns struct {
+	Left  *md.Column
+	Right *md.Column
+}
+
+func (c *EqualConstant) Requires() []*md.Column { return []*md.Column{c.Column} }
+
+func (c *EqualConstant) String() string {
+	return fmt.Sprintf("%s = %s", c.Column.QualifiedName(), c.Value.Raw)
+}
+
+func (c *EqualColumns) Requires() []*md.Column { return []*md.Column{c.Left, c.Right} }
+
+func (c *EqualColumns) String() string {
+	return fmt.Sprintf("%s = %s", c.Left.QualifiedName(), c.Right.QualifiedName())
+}
+
+// CompareConstant is true when the column is less or greater than a constant,
+// by one of the operators <, <=, > and >=.
+type CompareConstant struct {
+	Column   *md.Column
+	Operator string
+	Value    *ast.Constant
+}
+
+func (c *CompareConstant) Requires() []*md.Column { return []*md.Column{c.Column} }
+
+func (c *CompareConstant) String() string {
+	return fmt.Sprintf("%s %s %s", c.Column.QualifiedName(), c.Operator, c.Value.Raw)
+}
+
+// InConstants is true when the column is equal to one of the constants.
+type InConstants struct {
+	Column *md.Column
+	Values []*ast.Constant
+}
+
+func (c *InConstants) Requires() []*md.Column { return []*md.Column{c.Column} }
+
+func (c *InConstants) String() string {
+	values := []string{}
+	for _, v := range c.Values {
+		values = append(values, v.Raw)
+	}
+	return fmt.Sprintf("%s IN (%s)", c.Column.QualifiedName(), strings.Join(values, ", "))
+}
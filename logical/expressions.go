@@ -0,0 +1,53 @@
+package logical
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jacobsimpson/mtsql/ast"
+	md "github.com/jacobsimpson/mtsql/metadata"
+)
+
+// Expression is a value computed from the columns of a row.
+type Expression interface {
+	Requires() []*md.Column
+	String() string
+}
+
+// ColumnValue is the value of a column.
+type ColumnValue struct {
+	Column *md.Column
+}
+
+// ConstantValue is the same value for every row.
+type ConstantValue struct {
+	Value *ast.Constant
+}
+
+// FunctionCall is a scalar function applied to the values of its arguments.
+type FunctionCall struct {
+	Name string
+	Args []Expression
+}
+
+func (e *ColumnValue) Requires() []*md.Column { return []*md.Column{e.Column} }
+func (e *ColumnValue) String() string         { return e.Column.QualifiedName() }
+
+func (e *ConstantValue) Requires() []*md.Column { return nil }
+func (e *ConstantValue) String() string         { return e.Value.Raw }
+
+func (e *FunctionCall) Requires() []*md.Column {
+	var result []*md.Column
+	for _, a := range e.Args {
+		result = append(result, a.Requires()...)
+	}
+	return result
+}
+
+func (e *FunctionCall) String() string {
+	args := []string{}
+	for _, a := range e.Args {
+		args = append(args, a.String())
+	}
+	return fmt.Sprintf("%s(%s)", e.Name, strings.Join(args, ", "))
+}
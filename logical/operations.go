@@ -14,6 +14,19 @@ type Operation interface {
 	String() string
 }
 
+// Relations returns the relations the sources of a plan read, in the order
+// they appear.
+func Relations(o Operation) []*md.Relation {
+	if s, ok := o.(*Source); ok {
+		return []*md.Relation{s.Relation}
+	}
+	result := []*md.Relation{}
+	for _, c := range o.Children() {
+		result = append(result, Relations(c)...)
+	}
+	return result
+}
+
 type Difference struct {
 	LHS Operation
 	RHS Operation
@@ -35,26 +48,39 @@ type Union struct {
 }
 
 type Selection struct {
-	Child    Operation
-	requires []*md.Column
+	Child     Operation
+	Condition Condition
+	requires  []*md.Column
 }
 
-func NewSelection(child Operation, requires []*md.Column) *Selection {
+func NewSelection(child Operation, condition Condition) *Selection {
 	return &Selection{
-		Child:    child,
-		requires: requires,
+		Child:     child,
+		Condition: condition,
+		requires:  condition.Requires(),
 	}
 }
 
 type Projection struct {
-	Child   Operation
-	columns []*md.Column
+	Child Operation
+	// Expressions computes the value of each column, or is nil for a column
+	// that is taken from the child as it is. Expressions itself is nil when
+	// none of the columns are computed.
+	Expressions []Expression
+	columns     []*md.Column
 }
 
 func NewProjection(child Operation, columns []*md.Column) *Projection {
+	return NewComputedProjection(child, columns, nil)
+}
+
+// NewComputedProjection creates a projection where some of the columns are
+// computed by the expression at the same position.
+func NewComputedProjection(child Operation, columns []*md.Column, expressions []Expression) *Projection {
 	return &Projection{
-		Child:   child,
-		columns: columns,
+		Child:       child,
+		Expressions: expressions,
+		columns:     columns,
 	}
 }
 
@@ -71,6 +97,22 @@ type Source struct {
 	Relation *md.Relation
 }
 
+// Modification reads every row of its child, and either drops the rows that
+// match the condition, or sets the assigned columns in them. A nil condition
+// matches every row.
+type Modification struct {
+	Child       Operation
+	Condition   Condition
+	Delete      bool
+	Assignments []*Assignment
+}
+
+// Assignment sets a column to the value of an expression.
+type Assignment struct {
+	Column *md.Column
+	Value  Expression
+}
+
 func (o *Union) Children() []Operation {
 	return []Operation{o.LHS, o.RHS}
 }
@@ -143,13 +185,14 @@ func (o *Selection) Clone(children ...Operation) Operation {
 		panic("wrong number of children")
 	}
 	return &Selection{
-		Child:    children[0],
-		requires: o.requires,
+		Child:     children[0],
+		Condition: o.Condition,
+		requires:  o.requires,
 	}
 }
 
 func (o *Selection) String() string {
-	return fmt.Sprintf("Selection{Child: %s}", o.Child)
+	return fmt.Sprintf("Selection{Condition: %s, Child: %s}", o.Condition, o.Child)
 }
 
 func (o *Selection) Provides() []*md.Column { return o.Child.Provides() }
@@ -164,8 +207,9 @@ func (o *Projection) Clone(children ...Operation) Operation {
 		panic("wrong number of children")
 	}
 	return &Projection{
-		Child:   children[0],
-		columns: o.columns,
+		Child:       children[0],
+		Expressions: o.Expressions,
+		columns:     o.columns,
 	}
 }
 
@@ -174,7 +218,20 @@ func (o *Projection) String() string {
 }
 
 func (o *Projection) Provides() []*md.Column { return o.columns }
-func (o *Projection) Requires() []*md.Column { return o.columns }
+func (o *Projection) Requires() []*md.Column {
+	if o.Expressions == nil {
+		return o.columns
+	}
+	result := []*md.Column{}
+	for i, c := range o.columns {
+		if e := o.Expressions[i]; e != nil {
+			result = append(result, e.Requires()...)
+		} else {
+			result = append(result, c)
+		}
+	}
+	return result
+}
 
 func (o *Product) Children() []Operation {
 	return []Operation{o.LHS, o.RHS}
@@ -258,3 +315,26 @@ func (o *Source) Provides() []*md.Column {
 	return o.Relation.Columns
 }
 func (o *Source) Requires() []*md.Column { return []*md.Column{} }
+
+func (o *Modification) Children() []Operation {
+	return []Operation{o.Child}
+}
+
+func (o *Modification) Clone(children ...Operation) Operation {
+	if len(children) != 1 {
+		panic("wrong number of children")
+	}
+	return &Modification{
+		Child:       children[0],
+		Condition:   o.Condition,
+		Delete:      o.Delete,
+		Assignments: o.Assignments,
+	}
+}
+
+func (o *Modification) String() string {
+	return fmt.Sprintf("Modification{Condition: %s, Delete: %t, Child: %s}", o.Condition, o.Delete, o.Child)
+}
+
+func (o *Modification) Provides() []*md.Column { return o.Child.Provides() }
+func (o *Modification) Requires() []*md.Column { return o.Child.Provides() }
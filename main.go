@@ -1,46 +1,385 @@
 package main
 
 import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
 	"fmt"
+	"io"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"runtime"
+	"strconv"
 	"strings"
+	"time"
+
+	"golang.org/x/crypto/ssh/terminal"
 
 	"github.com/jacobsimpson/mtsql/ast"
+	"github.com/jacobsimpson/mtsql/cache"
 	"github.com/jacobsimpson/mtsql/formatter"
 	"github.com/jacobsimpson/mtsql/lexer"
+	"github.com/jacobsimpson/mtsql/logical"
 	"github.com/jacobsimpson/mtsql/metadata"
+	"github.com/jacobsimpson/mtsql/parameters"
 	"github.com/jacobsimpson/mtsql/parser"
 	"github.com/jacobsimpson/mtsql/physical"
 	"github.com/jacobsimpson/mtsql/preprocessor"
+	"github.com/jacobsimpson/mtsql/script"
+	"github.com/jacobsimpson/mtsql/spool"
+	"github.com/jacobsimpson/mtsql/statement"
 )
 
 func main() {
-	if err := run(); err != nil {
+	err := run()
+	spool.CloseStdin()
+	if err != nil {
 		fmt.Fprintf(os.Stderr, "Unable to execute query: %+v\n", err)
 		os.Exit(1)
 	}
 }
 
+// options are the command line settings that apply to every statement.
+type options struct {
+	dryRun  bool
+	params  paramFlag
+	timeout time.Duration
+	limits  physical.Limits
+	// cache holds the results of queries, unless noCache is set. It is nil
+	// unless --cache or --cache-dir turned it on.
+	cache   *cache.Cache
+	noCache bool
+}
+
+// paramFlag collects the values given with --param name=value. The name is a
+// number for the ? and $n placeholders.
+type paramFlag map[string]interface{}
+
+func (p paramFlag) String() string { return "" }
+
+func (p paramFlag) Set(s string) error {
+	i := strings.Index(s, "=")
+	if i < 1 {
+		return fmt.Errorf("expected name=value, found %q", s)
+	}
+	p[s[:i]] = parameters.ParseValue(s[i+1:])
+	return nil
+}
+
+// byteSize is a flag.Value for a number of bytes, with an optional K, M or G
+// suffix, like 512M.
+type byteSize struct {
+	bytes *int64
+}
+
+func (b byteSize) String() string {
+	if b.bytes == nil {
+		return "0"
+	}
+	return strconv.FormatInt(*b.bytes, 10)
+}
+
+func (b byteSize) Set(s string) error {
+	multipliers := map[string]int64{"K": 1 << 10, "M": 1 << 20, "G": 1 << 30}
+	number, multiplier := s, int64(1)
+	if s != "" {
+		if m, ok := multipliers[strings.ToUpper(s[len(s)-1:])]; ok {
+			number, multiplier = s[:len(s)-1], m
+		}
+	}
+	n, err := strconv.ParseInt(number, 10, 64)
+	if err != nil || n < 0 {
+		return fmt.Errorf("expected a number of bytes, like 512M, found %q", s)
+	}
+	*b.bytes = n * multiplier
+	return nil
+}
+
+// settings are the rows of the mtsql_settings table.
+func (opts *options) settings() []preprocessor.Setting {
+	cacheDir := ""
+	if opts.cache != nil {
+		cacheDir = opts.cache.Dir
+	}
+	return []preprocessor.Setting{
+		{Name: "threads", Value: strconv.Itoa(opts.limits.Threads), Description: "threads scanning a large CSV file"},
+		{Name: "max_rows", Value: strconv.FormatInt(opts.limits.MaxRowsScanned, 10), Description: "rows a statement can read from its tables, or 0 for no limit"},
+		{Name: "max_memory", Value: strconv.FormatInt(opts.limits.MaxMemory, 10), Description: "bytes a statement can hold in memory, or 0 for no limit"},
+		{Name: "timeout", Value: opts.timeout.String(), Description: "how long a statement can run, or 0s for no limit"},
+		{Name: "dry_run", Value: strconv.FormatBool(opts.dryRun), Description: "whether DELETE and UPDATE leave their tables alone"},
+		{Name: "cache_dir", Value: cacheDir, Description: "directory the results of queries are cached in"},
+		{Name: "no_cache", Value: strconv.FormatBool(opts.noCache), Description: "whether every query is run, rather than reusing cached results"},
+	}
+}
+
+func usage() {
+	name := filepath.Base(os.Args[0])
+	fmt.Fprintf(os.Stderr, "%s <SQL query>\n", name)
+	fmt.Fprintf(os.Stderr, "%s -f <SQL script>\n", name)
+	fmt.Fprintf(os.Stderr, "<SQL script> | %s\n", name)
+	flag.PrintDefaults()
+}
+
 func run() error {
-	if len(os.Args) != 2 {
-		fmt.Fprintf(os.Stderr, "%s <SQL query>\n", filepath.Base(os.Args[0]))
+	scriptFile := flag.String("f", "", "run the ;-separated statements in a file")
+	opts := &options{params: paramFlag{}}
+	flag.Var(opts.params, "param", "set a placeholder, like state=WA for :state, or 1=WA for ? and $1")
+	flag.BoolVar(&opts.dryRun, "dry-run", false, "report the rows DELETE and UPDATE would change, without changing them")
+	flag.DurationVar(&opts.timeout, "timeout", 0, "stop each statement after a time, like 30s")
+	flag.Int64Var(&opts.limits.MaxRowsScanned, "max-rows", 0, "stop each statement after it reads this many rows from its tables")
+	flag.Var(byteSize{&opts.limits.MaxMemory}, "max-memory", "stop each statement when it holds more than this much memory, like 512M")
+	flag.IntVar(&opts.limits.Threads, "threads", runtime.NumCPU(), "scan large CSV files with this many threads")
+	flag.BoolVar(&opts.noCache, "no-cache", false, "run every query, rather than reusing the results of earlier runs")
+	useCache := flag.Bool("cache", false, "reuse the results of earlier runs of queries, kept in the user cache directory")
+	cacheDir := flag.String("cache-dir", "", "reuse the results of earlier runs of queries, kept in this directory")
+	flag.Usage = usage
+	flag.Parse()
+
+	if *useCache && *cacheDir == "" {
+		dir, err := cache.DefaultDir()
+		if err != nil {
+			return fmt.Errorf("there is no user cache directory, set one with --cache-dir: %v", err)
+		}
+		*cacheDir = dir
+	}
+	if *cacheDir != "" {
+		opts.cache = cache.New(*cacheDir)
+	}
+
+	tables := map[string]*metadata.Relation{
+		preprocessor.SettingsTable: preprocessor.NewSettings(opts.settings()),
+	}
+
+	switch {
+	case *scriptFile != "" && flag.NArg() == 0:
+		f, err := os.Open(*scriptFile)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		return runScript(f, tables, opts)
+	case *scriptFile == "" && flag.NArg() == 1:
+		query := flag.Arg(0)
+		err := execute(query, tables, opts)
+		var pe *parser.ParseError
+		if errors.As(err, &pe) {
+			return errors.New(describe(&script.Statement{Text: query, Line: 1, Column: 1}, err))
+		}
+		return err
+	case *scriptFile == "" && flag.NArg() == 0 && !terminal.IsTerminal(int(os.Stdin.Fd())):
+		return runScript(os.Stdin, tables, opts)
+	}
+	usage()
+	return nil
+}
+
+// runScript executes each statement of a script in turn. A failed statement
+// is reported, with its position in the script, and the rest still run.
+func runScript(r io.Reader, tables map[string]*metadata.Relation, opts *options) error {
+	statements, err := script.Split(r)
+	if err != nil {
+		return err
+	}
+	failed := 0
+	for i, s := range statements {
+		if i > 0 {
+			fmt.Println()
+		}
+		err := execute(s.Text, tables, opts)
+		if err == errInterrupted {
+			return fmt.Errorf("statement %d was interrupted, the rest were not run", i+1)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Unable to execute statement %d, %s\n", i+1, describe(s, err))
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d statements failed", failed, len(statements))
+	}
+	return nil
+}
+
+// describe explains why a statement failed, starting with where in the input
+// the problem is. Syntax errors also show the line with a caret under the
+// error.
+func describe(s *script.Statement, err error) string {
+	var pe *parser.ParseError
+	if !errors.As(err, &pe) {
+		return fmt.Sprintf("line %d, column %d: %v", s.Line, s.Column, err)
+	}
+	line, column := s.Line+pe.Line-1, pe.Column
+	if pe.Line == 1 {
+		column += s.Column - 1
+	}
+	return fmt.Sprintf("line %d, column %d: %s", line, column, pe.Annotate(s.Text))
+}
+
+// schemaCache is the cache inferred schemas are kept in, or nil when the
+// cache is off or every statement is run afresh with --no-cache.
+func (opts *options) schemaCache() *cache.Cache {
+	if opts.noCache {
 		return nil
 	}
+	return opts.cache
+}
+
+// errInterrupted is returned when a statement is stopped with Ctrl-C.
+var errInterrupted = errors.New("interrupted")
+
+// context is cancelled when the timeout is up, or on Ctrl-C. Once it is
+// cancelled, a second Ctrl-C kills the program as usual.
+func (opts *options) context() (context.Context, context.CancelFunc) {
+	var ctx context.Context
+	var cancel context.CancelFunc
+	if opts.timeout > 0 {
+		ctx, cancel = context.WithTimeout(context.Background(), opts.timeout)
+	} else {
+		ctx, cancel = context.WithCancel(context.Background())
+	}
+	interrupts := make(chan os.Signal, 1)
+	signal.Notify(interrupts, os.Interrupt)
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-interrupts:
+			cancel()
+		case <-done:
+		}
+		signal.Stop(interrupts)
+	}()
+	return ctx, func() {
+		close(done)
+		cancel()
+	}
+}
+
+// stopped explains why the execution of a statement was stopped, or returns
+// nil if it ran to the end.
+func stopped(execution *physical.Execution, opts *options) error {
+	err := execution.Err()
+	switch {
+	case err == context.Canceled:
+		return errInterrupted
+	case err == context.DeadlineExceeded:
+		return fmt.Errorf("timed out after %v", opts.timeout)
+	}
+	return err
+}
+
+func execute(query string, tables map[string]*metadata.Relation, opts *options) error {
+	if strings.HasPrefix(strings.TrimSpace(query), `\`) {
+		return metaCommand(strings.TrimSpace(query), opts)
+	}
+	ctx, cancel := opts.context()
+	defer cancel()
+	execution := physical.NewExecution(ctx, opts.limits)
+	err := runStatement(query, tables, opts, execution)
+	if s := stopped(execution, opts); s != nil {
+		return s
+	}
+	return err
+}
 
-	query := os.Args[1]
+func runStatement(query string, tables map[string]*metadata.Relation, opts *options, execution *physical.Execution) error {
 	queryAst, err := parser.Parse(lexer.NewFilterWhitespace(strings.NewReader(query)))
 	if err != nil {
 		return err
 	}
+	if err := bind(queryAst, opts.params); err != nil {
+		return err
+	}
+
+	switch q := queryAst.(type) {
+	case *ast.Copy:
+		count, err := statement.Copy(q, tables, execution)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%d rows written to %s\n", count, q.Path)
+		return nil
+	case *ast.CreateTableAs:
+		count, err := statement.CreateTableAs(q, tables, execution)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%d rows written to table %s\n", count, q.Table.Name)
+		return nil
+	case *ast.CreateIndex:
+		count, err := statement.CreateIndex(q, tables, execution)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%d rows indexed by %s\n", count, q.Name)
+		return nil
+	case *ast.ShowTables:
+		rowReader, err := statement.ShowTables(tables, "")
+		if err != nil {
+			return err
+		}
+		formatter.NewTableFormatter(rowReader).Print(os.Stdout)
+		return nil
+	case *ast.Describe:
+		rowReader, err := statement.Describe(q, tables, opts.schemaCache(), execution)
+		if err != nil {
+			return err
+		}
+		formatter.NewTableFormatter(rowReader).Print(os.Stdout)
+		return nil
+	case *ast.ShowCreateTable:
+		create, err := statement.ShowCreateTable(q, tables, opts.schemaCache(), execution)
+		if err != nil {
+			return err
+		}
+		fmt.Println(create)
+		return nil
+	case *ast.Insert:
+		count, err := statement.Insert(q, tables, execution)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%d rows inserted into %s\n", count, q.Table.Name)
+		return nil
+	case *ast.Delete:
+		count, err := statement.Delete(q, tables, execution, opts.dryRun)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%d rows %s from %s\n", count, tense(opts, "deleted"), q.Table.Name)
+		return nil
+	case *ast.Update:
+		count, err := statement.Update(q, tables, execution, opts.dryRun)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%d rows %s in %s\n", count, tense(opts, "updated"), q.Table.Name)
+		return nil
+	}
 
-	tables := map[string]*metadata.Relation{}
 	queryLogical, err := preprocessor.Convert(queryAst, tables)
 	if err != nil {
 		return err
 	}
 
-	queryPhysical, err := physical.Convert(queryLogical, tables)
+	// The result of a SELECT over files that haven't changed since it last
+	// ran is read from the cache.
+	var key, normalized string
+	var sources []*cache.Fingerprint
+	if _, ok := queryAst.(*ast.SFW); ok && opts.cache != nil && !opts.noCache {
+		normalized = cache.Normalize(query, ast.Parameters(queryAst))
+		if key, sources, err = opts.cache.Key(normalized, logical.Relations(queryLogical)); err != nil {
+			// A query that can't be cached is run as usual, and any error
+			// reading its files is reported from there.
+			key = ""
+		} else if entry, err := opts.cache.Get(key); err == nil && entry != nil {
+			formatter.NewTableFormatter(entry.NewScan()).Print(os.Stdout)
+			return nil
+		}
+	}
+
+	queryPhysical, err := physical.ConvertWith(queryLogical, tables, execution)
 	if err != nil {
 		return err
 	}
@@ -49,8 +388,73 @@ func run() error {
 		f := formatter.NewQueryPlanFormatter(queryPhysical)
 		f.Print(os.Stdout)
 	} else {
+		if key != "" {
+			queryPhysical = opts.cache.NewRecorder(queryPhysical, key, normalized, sources)
+		}
 		f := formatter.NewTableFormatter(queryPhysical)
 		f.Print(os.Stdout)
 	}
 	return nil
 }
+
+// metaCommand runs a command starting with a backslash, which is handled by
+// mtsql itself rather than being a statement. \cache shows how much the result
+// cache holds and how often it has been used, and \cache clear empties it.
+func metaCommand(command string, opts *options) error {
+	fields := strings.Fields(command)
+	if fields[0] != `\cache` || len(fields) > 2 || len(fields) == 2 && fields[1] != "stats" && fields[1] != "clear" {
+		return fmt.Errorf("unknown command %s, expected \\cache, \\cache stats or \\cache clear", command)
+	}
+	if opts.cache == nil {
+		return errors.New("the result cache is off, turn it on with --cache or --cache-dir")
+	}
+	if len(fields) == 2 && fields[1] == "clear" {
+		n, err := opts.cache.Clear()
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%d cached results removed from %s\n", n, opts.cache.Dir)
+		return nil
+	}
+	stats, err := opts.cache.Stats()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Result cache: %s\n", opts.cache.Dir)
+	fmt.Printf("Entries:      %d, %d bytes\n", stats.Entries, stats.Size)
+	fmt.Printf("Hits:         %d\n", stats.Hits)
+	fmt.Printf("Misses:       %d\n", stats.Misses)
+	fmt.Printf("Schemas:      %d\n", stats.Schemas)
+	if opts.noCache {
+		fmt.Println("Caching is off for this run, with --no-cache")
+	}
+	return nil
+}
+
+// bind sets the placeholders of a statement from the --param values. Values
+// the statement doesn't use are ignored, so one set can serve a whole script.
+func bind(q ast.Query, values paramFlag) error {
+	params := ast.Parameters(q)
+	args := []interface{}{}
+	for i := 1; i <= parameters.Count(params); i++ {
+		v, ok := values[strconv.Itoa(i)]
+		if !ok {
+			return fmt.Errorf("no value given for parameter %d, set it with --param %d=value", i, i)
+		}
+		args = append(args, v)
+	}
+	for name, v := range values {
+		if _, err := strconv.Atoi(name); err != nil {
+			args = append(args, sql.Named(name, v))
+		}
+	}
+	return parameters.Bind(params, args)
+}
+
+// tense describes what a statement did, or would have done in a dry run.
+func tense(opts *options, verb string) string {
+	if opts.dryRun {
+		return "would be " + verb
+	}
+	return verb
+}
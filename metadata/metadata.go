@@ -1,18 +1,30 @@
 package metadata
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+)
 
 type RelationType string
 
 const (
-	CsvType RelationType = "csv"
+	CsvType     RelationType = "csv"
+	JsonType    RelationType = "json"
+	NdjsonType  RelationType = "ndjson"
+	ParquetType RelationType = "parquet"
+	// VirtualType is a table that isn't read from a file, like the system
+	// tables describing the catalog. Its rows are kept in the Relation.
+	VirtualType RelationType = "virtual"
 )
 
 type Relation struct {
 	Name    string
 	Type    RelationType
 	Source  string
+	Dialect *CsvDialect
 	Columns []*Column
+	// Rows are the rows of a VirtualType table.
+	Rows [][]string
 }
 
 func (r *Relation) ColumnsMap() map[string]*Column {
@@ -27,10 +39,46 @@ func (r *Relation) String() string {
 	return fmt.Sprintf("Relation{Name: %s, Type: %s, Source: %s, Columns: ...}", r.Name, r.Type, r.Source)
 }
 
+// CsvDialect describes the layout of a CSV file. A nil *CsvDialect means the
+// default dialect: comma separated, double quoted, UTF-8, with a header row.
+type CsvDialect struct {
+	Delimiter rune
+	Quote     rune
+	Comment   rune
+	Header    bool
+	Encoding  Encoding
+}
+
+type Encoding string
+
+const (
+	Utf8Encoding   Encoding = "utf-8"
+	Latin1Encoding Encoding = "latin1"
+)
+
+func NewCsvDialect() *CsvDialect {
+	return &CsvDialect{
+		Delimiter: ',',
+		Quote:     '"',
+		Header:    true,
+		Encoding:  Utf8Encoding,
+	}
+}
+
+func (d *CsvDialect) String() string {
+	return fmt.Sprintf("CsvDialect{Delimiter: %q, Quote: %q, Comment: %q, Header: %t, Encoding: %s}",
+		d.Delimiter, d.Quote, d.Comment, d.Header, d.Encoding)
+}
+
 type ColumnType string
 
 const (
-	StringType ColumnType = "string"
+	BooleanType   ColumnType = "boolean"
+	DateType      ColumnType = "date"
+	FloatType     ColumnType = "float"
+	IntegerType   ColumnType = "integer"
+	StringType    ColumnType = "string"
+	TimestampType ColumnType = "timestamp"
 )
 
 type Column struct {
@@ -38,19 +86,28 @@ type Column struct {
 	Name      string
 	Alias     string
 	Type      ColumnType
+	// Path selects a value nested within JSON stored in the column. When
+	// PathJSON is set, the value is left as JSON text.
+	Path     []string
+	PathJSON bool
 }
 
 func (c *Column) QualifiedName() string {
+	name := c.Name
+	if len(c.Path) > 0 {
+		name = fmt.Sprintf("%s.%s", name, strings.Join(c.Path, "."))
+	}
 	if c.Qualifier == "" {
-		return c.Name
+		return name
 	}
-	return fmt.Sprintf("%s.%s", c.Qualifier, c.Name)
+	return fmt.Sprintf("%s.%s", c.Qualifier, name)
 }
 
 func (c *Column) String() string {
-	return fmt.Sprintf("{Qualifier: %q, Name: %q, Alias: %q, Type: %s}",
+	return fmt.Sprintf("{Qualifier: %q, Name: %q, Alias: %q, Type: %s, Path: %q}",
 		c.Qualifier,
 		c.Name,
 		c.Alias,
-		c.Type)
+		c.Type,
+		c.Path)
 }
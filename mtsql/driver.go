@@ -0,0 +1,201 @@
+package mtsql
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+
+	md "github.com/jacobsimpson/mtsql/metadata"
+)
+
+func init() {
+	sql.Register("mtsql", &Driver{})
+}
+
+// Driver is the database/sql driver for mtsql, registered as "mtsql". The
+// data source name is a list of space separated key=value settings. The only
+// one is dir, the directory tables are found in.
+//
+//	db, err := sql.Open("mtsql", "dir=/data/csvs")
+type Driver struct{}
+
+// Open opens a connection, which is a DB of its own.
+func (d *Driver) Open(dsn string) (driver.Conn, error) {
+	options, err := parseDSN(dsn)
+	if err != nil {
+		return nil, err
+	}
+	db, err := Open(options)
+	if err != nil {
+		return nil, err
+	}
+	return &conn{db: db}, nil
+}
+
+func parseDSN(dsn string) (Options, error) {
+	options := Options{}
+	for _, setting := range strings.Fields(dsn) {
+		i := strings.Index(setting, "=")
+		if i < 0 {
+			return options, fmt.Errorf("mtsql: expected key=value in the data source name, found %q", setting)
+		}
+		switch key, value := setting[:i], setting[i+1:]; key {
+		case "dir":
+			options.Dir = value
+		default:
+			return options, fmt.Errorf("mtsql: unknown data source setting %q", key)
+		}
+	}
+	return options, nil
+}
+
+type conn struct {
+	db *DB
+}
+
+func (c *conn) Prepare(query string) (driver.Stmt, error) {
+	return c.PrepareContext(context.Background(), query)
+}
+
+func (c *conn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	s, err := c.db.Prepare(query)
+	if err != nil {
+		return nil, err
+	}
+	return &stmt{stmt: s}, nil
+}
+
+func (c *conn) Close() error { return c.db.Close() }
+
+func (c *conn) Begin() (driver.Tx, error) {
+	return nil, errors.New("mtsql: transactions are not supported")
+}
+
+type stmt struct {
+	stmt *Stmt
+}
+
+func (s *stmt) Close() error { return s.stmt.Close() }
+
+// NumInput lets database/sql check the number of arguments, unless the
+// query has :name placeholders, which take named arguments.
+func (s *stmt) NumInput() int {
+	for _, p := range s.stmt.params {
+		if p.Parameter.Name != "" {
+			return -1
+		}
+	}
+	return s.stmt.NumInput()
+}
+
+func (s *stmt) Exec(args []driver.Value) (driver.Result, error) {
+	return nil, ErrNotQuery
+}
+
+func (s *stmt) Query(args []driver.Value) (driver.Rows, error) {
+	values := make([]interface{}, len(args))
+	for i, a := range args {
+		values[i] = a
+	}
+	return s.query(context.Background(), values)
+}
+
+func (s *stmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	values := make([]interface{}, len(args))
+	for i, a := range args {
+		if a.Name != "" {
+			values[i] = sql.Named(a.Name, a.Value)
+		} else {
+			values[i] = a.Value
+		}
+	}
+	return s.query(ctx, values)
+}
+
+func (s *stmt) query(ctx context.Context, args []interface{}) (driver.Rows, error) {
+	r, err := s.stmt.Query(ctx, args...)
+	if err != nil {
+		return nil, err
+	}
+	return &rows{rows: r}, nil
+}
+
+// rows adapts Rows to driver.Rows, converting values to the type of their
+// column.
+type rows struct {
+	rows *Rows
+}
+
+func (r *rows) Columns() []string { return r.rows.Columns() }
+func (r *rows) Close() error      { return r.rows.Close() }
+
+func (r *rows) Next(dest []driver.Value) error {
+	if !r.rows.Next() {
+		if err := r.rows.Err(); err != nil {
+			return err
+		}
+		return io.EOF
+	}
+	for i, v := range r.rows.row {
+		dest[i] = driverValue(r.rows.types[i], v)
+	}
+	return nil
+}
+
+// driverValue converts a value to the type of its column. An empty value in a
+// column that isn't a string is NULL, and a value that doesn't parse is
+// left as a string.
+func driverValue(t md.ColumnType, v string) driver.Value {
+	if v == "" && t != md.StringType && t != "" {
+		return nil
+	}
+	switch t {
+	case md.IntegerType:
+		if i, err := strconv.ParseInt(v, 10, 64); err == nil {
+			return i
+		}
+	case md.FloatType:
+		if f, err := strconv.ParseFloat(v, 64); err == nil {
+			return f
+		}
+	case md.BooleanType:
+		if b, err := strconv.ParseBool(v); err == nil {
+			return b
+		}
+	}
+	return v
+}
+
+// ColumnTypeDatabaseTypeName reports the type of a column, like INTEGER.
+func (r *rows) ColumnTypeDatabaseTypeName(index int) string {
+	if t := r.rows.types[index]; t != "" {
+		return strings.ToUpper(string(t))
+	}
+	return strings.ToUpper(string(md.StringType))
+}
+
+// ColumnTypeScanType reports the Go type values of a column are scanned as.
+func (r *rows) ColumnTypeScanType(index int) reflect.Type {
+	switch r.rows.types[index] {
+	case md.IntegerType:
+		return reflect.TypeOf(int64(0))
+	case md.FloatType:
+		return reflect.TypeOf(float64(0))
+	case md.BooleanType:
+		return reflect.TypeOf(false)
+	}
+	return reflect.TypeOf("")
+}
+
+// ColumnTypeNullable reports that values can be NULL in columns that aren't
+// strings.
+func (r *rows) ColumnTypeNullable(index int) (nullable, ok bool) {
+	t := r.rows.types[index]
+	return t != md.StringType && t != "", true
+}
@@ -0,0 +1,96 @@
+package mtsql
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"testing"
+
+	md "github.com/jacobsimpson/mtsql/metadata"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSqlQuery(t *testing.T) {
+	assert := assert.New(t)
+	db, err := sql.Open("mtsql", "dir=testdata")
+	if !assert.Nil(err) {
+		return
+	}
+	defer db.Close()
+
+	tests := []struct {
+		query string
+		args  []interface{}
+	}{
+		{"SELECT name FROM people WHERE state = ?", []interface{}{"WA"}},
+		{"SELECT name FROM people WHERE state = $1", []interface{}{"WA"}},
+		{"SELECT name FROM people WHERE state = :state", []interface{}{sql.Named("state", "WA")}},
+	}
+	for _, test := range tests {
+		rows, err := db.Query(test.query, test.args...)
+		if !assert.Nil(err) {
+			continue
+		}
+		names := []string{}
+		for rows.Next() {
+			var name string
+			assert.Nil(rows.Scan(&name))
+			names = append(names, name)
+		}
+		assert.Nil(rows.Err())
+		rows.Close()
+		assert.Equal([]string{"ann", "cat"}, names, test.query)
+	}
+}
+
+func TestSqlPreparedStatement(t *testing.T) {
+	assert := assert.New(t)
+	db, err := sql.Open("mtsql", "dir=testdata")
+	if !assert.Nil(err) {
+		return
+	}
+	defer db.Close()
+
+	stmt, err := db.Prepare("SELECT name FROM people WHERE age = ?")
+	if !assert.Nil(err) {
+		return
+	}
+	defer stmt.Close()
+	for age, expected := range map[int]string{31: "ann", 42: "bob"} {
+		var name string
+		assert.Nil(stmt.QueryRow(age).Scan(&name))
+		assert.Equal(expected, name)
+	}
+
+	_, err = stmt.Query()
+	assert.Error(err)
+}
+
+func TestParseDSN(t *testing.T) {
+	assert := assert.New(t)
+
+	options, err := parseDSN("dir=/data/csvs")
+	assert.Nil(err)
+	assert.Equal(Options{Dir: "/data/csvs"}, options)
+
+	_, err = parseDSN("directory=/data")
+	assert.EqualError(err, `mtsql: unknown data source setting "directory"`)
+}
+
+func TestDriverValue(t *testing.T) {
+	tests := []struct {
+		columnType md.ColumnType
+		value      string
+		expected   driver.Value
+	}{
+		{md.StringType, "12", "12"},
+		{md.IntegerType, "12", int64(12)},
+		{md.IntegerType, "", nil},
+		{md.IntegerType, "twelve", "twelve"},
+		{md.FloatType, "1.5", 1.5},
+		{md.BooleanType, "true", true},
+		{"", "", ""},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.expected, driverValue(test.columnType, test.value))
+	}
+}
@@ -0,0 +1,74 @@
+package mtsql
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/jacobsimpson/mtsql/parser"
+	"github.com/jacobsimpson/mtsql/physical"
+)
+
+var (
+	// ErrClosed is returned when a closed DB or Rows is used.
+	ErrClosed = errors.New("mtsql: closed")
+	// ErrNotQuery is returned when Query is given a statement that doesn't
+	// return rows, like INSERT INTO.
+	ErrNotQuery = errors.New("mtsql: only SELECT statements can be queried")
+)
+
+// LimitError is returned by Rows.Err when a query goes over the
+// MaxRowsScanned or MaxMemory of the DB.
+type LimitError = physical.LimitError
+
+// SyntaxError is returned for a query that can't be parsed.
+type SyntaxError struct {
+	Line    int
+	Column  int
+	Message string
+	// Suggestion is the keyword that was probably meant, or empty.
+	Suggestion string
+	// Annotated is the message, followed by the line of the query with a
+	// caret under the error.
+	Annotated string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("mtsql: line %d, column %d: %s", e.Line, e.Column, e.Message)
+}
+
+func newSyntaxError(query string, err error) error {
+	var pe *parser.ParseError
+	if !errors.As(err, &pe) {
+		return &SyntaxError{Line: 1, Column: 1, Message: err.Error(), Annotated: err.Error()}
+	}
+	return &SyntaxError{
+		Line:       pe.Line,
+		Column:     pe.Column,
+		Message:    pe.Error(),
+		Suggestion: pe.Suggestion(),
+		Annotated:  pe.Annotate(query),
+	}
+}
+
+// PlanError is returned for a query that can't be run, usually because a
+// table or column it names doesn't exist.
+type PlanError struct {
+	Err error
+}
+
+func (e *PlanError) Error() string { return "mtsql: " + e.Err.Error() }
+func (e *PlanError) Unwrap() error { return e.Err }
+
+// ScanError is returned by Rows.Scan when a value can't be converted to the
+// type of its destination.
+type ScanError struct {
+	Column string
+	Value  string
+	Err    error
+}
+
+func (e *ScanError) Error() string {
+	return fmt.Sprintf("mtsql: can not scan %q from column %q: %v", e.Value, e.Column, e.Err)
+}
+
+func (e *ScanError) Unwrap() error { return e.Err }
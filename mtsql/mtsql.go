@@ -0,0 +1,223 @@
+// Package mtsql runs SQL queries over CSV, JSON and Parquet files, for
+// programs that want to use mtsql without the command line.
+//
+//	db, err := mtsql.Open(mtsql.Options{Dir: "/data/csvs"})
+//	...
+//	rows, err := db.Query(ctx, "SELECT City, State FROM cities WHERE State = 'WA'")
+//	...
+//	defer rows.Close()
+//	for rows.Next() {
+//		var city, state string
+//		if err := rows.Scan(&city, &state); err != nil {
+//			...
+//		}
+//	}
+//	if err := rows.Err(); err != nil {
+//		...
+//	}
+package mtsql
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/jacobsimpson/mtsql/ast"
+	"github.com/jacobsimpson/mtsql/lexer"
+	md "github.com/jacobsimpson/mtsql/metadata"
+	"github.com/jacobsimpson/mtsql/parser"
+	"github.com/jacobsimpson/mtsql/physical"
+	"github.com/jacobsimpson/mtsql/preprocessor"
+)
+
+// Options configure a DB.
+type Options struct {
+	// Dir is the directory that tables named in queries are found in, and
+	// that relative paths are resolved against. It defaults to the current
+	// directory.
+	Dir string
+	// MaxRowsScanned and MaxMemory limit each run of a query, failing it
+	// with a *LimitError once it reads more rows from its tables, or holds
+	// more bytes in memory, than allowed. Zero is no limit.
+	MaxRowsScanned int64
+	MaxMemory      int64
+	// Threads is how many goroutines scan a large CSV file at the same time.
+	// It defaults to the number of CPUs.
+	Threads int
+}
+
+// TableOptions describe the format of a CSV file registered as a table. The
+// zero value is a comma separated UTF-8 file with a header row.
+type TableOptions struct {
+	Delimiter rune
+	Quote     rune
+	Comment   rune
+	NoHeader  bool
+	// Encoding is "utf-8" or "latin1".
+	Encoding string
+}
+
+// DB is a catalog of tables that queries can be run against. It is safe for
+// concurrent use.
+type DB struct {
+	mu     sync.Mutex
+	dir    string
+	limits physical.Limits
+	tables map[string]*md.Relation
+	closed bool
+}
+
+// Open creates a DB.
+func Open(options Options) (*DB, error) {
+	if options.Dir != "" {
+		info, err := os.Stat(options.Dir)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			return nil, fmt.Errorf("mtsql: %q is not a directory", options.Dir)
+		}
+	}
+	if options.Threads == 0 {
+		options.Threads = runtime.NumCPU()
+	}
+	return &DB{
+		dir: options.Dir,
+		limits: physical.Limits{
+			MaxRowsScanned: options.MaxRowsScanned,
+			MaxMemory:      options.MaxMemory,
+			Threads:        options.Threads,
+		},
+		tables: map[string]*md.Relation{
+			preprocessor.SettingsTable: preprocessor.NewSettings(options.settings()),
+		},
+	}, nil
+}
+
+// settings are the rows of the mtsql_settings table.
+func (o Options) settings() []preprocessor.Setting {
+	return []preprocessor.Setting{
+		{Name: "dir", Value: o.Dir, Description: "directory tables are found in"},
+		{Name: "threads", Value: strconv.Itoa(o.Threads), Description: "threads scanning a large CSV file"},
+		{Name: "max_rows", Value: strconv.FormatInt(o.MaxRowsScanned, 10), Description: "rows a query can read from its tables, or 0 for no limit"},
+		{Name: "max_memory", Value: strconv.FormatInt(o.MaxMemory, 10), Description: "bytes a query can hold in memory, or 0 for no limit"},
+	}
+}
+
+// RegisterTable makes the file at path available to queries as the table
+// name. The format of the file is taken from its extension. opts can be nil,
+// and are only allowed for CSV files.
+func (db *DB) RegisterTable(name, path string, opts *TableOptions) error {
+	if db.dir != "" && !filepath.IsAbs(path) {
+		path = filepath.Join(db.dir, path)
+	}
+	relationType := preprocessor.RelationType(path)
+	var dialect *md.CsvDialect
+	if opts != nil {
+		if relationType != md.CsvType {
+			return fmt.Errorf("mtsql: table options are only supported for CSV files, not %q", path)
+		}
+		d, err := opts.dialect()
+		if err != nil {
+			return err
+		}
+		dialect = d
+	}
+	relation, err := preprocessor.NewRelation(name, path, relationType, dialect)
+	if err != nil {
+		return &PlanError{Err: err}
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if db.closed {
+		return ErrClosed
+	}
+	db.tables[name] = relation
+	return nil
+}
+
+func (o *TableOptions) dialect() (*md.CsvDialect, error) {
+	dialect := md.NewCsvDialect()
+	if o.Delimiter != 0 {
+		dialect.Delimiter = o.Delimiter
+	}
+	if o.Quote != 0 {
+		dialect.Quote = o.Quote
+	}
+	dialect.Comment = o.Comment
+	dialect.Header = !o.NoHeader
+	switch strings.ToLower(o.Encoding) {
+	case "", "utf-8", "utf8":
+	case "latin1", "latin-1", "iso-8859-1":
+		dialect.Encoding = md.Latin1Encoding
+	default:
+		return nil, fmt.Errorf("mtsql: unsupported encoding %q", o.Encoding)
+	}
+	return dialect, nil
+}
+
+// Query runs a SELECT query, and returns an iterator over its rows. The rows
+// are read from the files as Next is called, and stop being read if ctx is
+// cancelled. args are the values of the placeholders in the query, as
+// described for Stmt.Query.
+func (db *DB) Query(ctx context.Context, query string, args ...interface{}) (*Rows, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	stmt, err := db.Prepare(query)
+	if err != nil {
+		return nil, err
+	}
+	return stmt.Query(ctx, args...)
+}
+
+// Prepare parses and plans a SELECT query, so it can be run many times with
+// different values for its placeholders.
+func (db *DB) Prepare(query string) (*Stmt, error) {
+	q, err := parser.Parse(lexer.NewFilterWhitespace(strings.NewReader(query)))
+	if err != nil {
+		return nil, newSyntaxError(query, err)
+	}
+	if _, ok := q.(*ast.SFW); !ok {
+		return nil, ErrNotQuery
+	}
+
+	execution := physical.NewExecution(context.Background(), db.limits)
+	rowReader, err := db.plan(q, execution)
+	if err == ErrClosed {
+		return nil, err
+	} else if err != nil {
+		return nil, &PlanError{Err: err}
+	}
+	return &Stmt{params: ast.Parameters(q), rowReader: rowReader, execution: execution}, nil
+}
+
+// plan builds the physical plan for a query. The catalog is locked while
+// planning, since tables found in the directory are added to it.
+func (db *DB) plan(q ast.Query, execution *physical.Execution) (physical.RowReader, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if db.closed {
+		return nil, ErrClosed
+	}
+	queryLogical, err := preprocessor.ConvertIn(q, db.tables, db.dir)
+	if err != nil {
+		return nil, err
+	}
+	return physical.ConvertWith(queryLogical, db.tables, execution)
+}
+
+// Close releases the DB. Rows that are still open can be read to the end.
+func (db *DB) Close() error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	db.closed = true
+	db.tables = nil
+	return nil
+}
@@ -0,0 +1,294 @@
+package mtsql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func open(t *testing.T) *DB {
+	db, err := Open(Options{Dir: "testdata"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func TestQuery(t *testing.T) {
+	assert := assert.New(t)
+	db := open(t)
+	defer db.Close()
+
+	rows, err := db.Query(context.Background(), "SELECT name, age FROM people WHERE state = 'WA'")
+	if !assert.Nil(err) {
+		return
+	}
+	defer rows.Close()
+
+	assert.Equal([]string{"name", "age"}, rows.Columns())
+	names := []string{}
+	ages := []int{}
+	for rows.Next() {
+		var name string
+		var age int
+		assert.Nil(rows.Scan(&name, &age))
+		names = append(names, name)
+		ages = append(ages, age)
+	}
+	assert.Nil(rows.Err())
+	assert.Equal([]string{"ann", "cat"}, names)
+	assert.Equal([]int{31, 27}, ages)
+}
+
+func TestRegisterTable(t *testing.T) {
+	assert := assert.New(t)
+	db := open(t)
+	defer db.Close()
+
+	err := db.RegisterTable("pets", "pets.txt", &TableOptions{Delimiter: '|', NoHeader: true})
+	if !assert.Nil(err) {
+		return
+	}
+	rows, err := db.Query(context.Background(), "SELECT c1 FROM pets")
+	if !assert.Nil(err) {
+		return
+	}
+	defer rows.Close()
+
+	names := []string{}
+	for rows.Next() {
+		var name string
+		assert.Nil(rows.Scan(&name))
+		names = append(names, name)
+	}
+	assert.Nil(rows.Err())
+	assert.Equal([]string{"dog", "emu"}, names)
+}
+
+func TestSystemTables(t *testing.T) {
+	assert := assert.New(t)
+	db := open(t)
+	defer db.Close()
+
+	rows, err := db.Query(context.Background(), "SELECT column_name, position FROM mtsql_columns WHERE table_name = 'people'")
+	if !assert.Nil(err) {
+		return
+	}
+	names := []string{}
+	positions := []int64{}
+	for rows.Next() {
+		var name string
+		var position int64
+		assert.Nil(rows.Scan(&name, &position))
+		names = append(names, name)
+		positions = append(positions, position)
+	}
+	assert.Nil(rows.Err())
+	rows.Close()
+	assert.Equal([]string{"name", "age", "state"}, names)
+	assert.Equal([]int64{1, 2, 3}, positions)
+	assert.NotContains(db.tables, "mtsql_columns")
+
+	rows, err = db.Query(context.Background(), "SELECT value FROM mtsql_settings WHERE name = 'dir'")
+	if !assert.Nil(err) {
+		return
+	}
+	defer rows.Close()
+	assert.True(rows.Next())
+	var dir string
+	assert.Nil(rows.Scan(&dir))
+	assert.Equal("testdata", dir)
+}
+
+func TestQueryErrors(t *testing.T) {
+	db := open(t)
+	defer db.Close()
+
+	t.Run("syntax", func(t *testing.T) {
+		assert := assert.New(t)
+		_, err := db.Query(context.Background(), "SELECT name FROM people WHER state = 'WA'")
+
+		var se *SyntaxError
+		if assert.True(errors.As(err, &se)) {
+			assert.Equal(1, se.Line)
+			assert.Equal(25, se.Column)
+			assert.Equal("WHERE", se.Suggestion)
+		}
+	})
+	t.Run("plan", func(t *testing.T) {
+		assert := assert.New(t)
+		_, err := db.Query(context.Background(), "SELECT nmae FROM people")
+
+		var pe *PlanError
+		assert.True(errors.As(err, &pe))
+		assert.EqualError(err, `mtsql: no matching name "nmae", did you mean "name"?`)
+	})
+	t.Run("not a query", func(t *testing.T) {
+		_, err := db.Query(context.Background(), "DELETE FROM people")
+
+		assert.Equal(t, ErrNotQuery, err)
+	})
+	t.Run("scan", func(t *testing.T) {
+		assert := assert.New(t)
+		rows, err := db.Query(context.Background(), "SELECT name FROM people")
+		if !assert.Nil(err) {
+			return
+		}
+		defer rows.Close()
+
+		assert.True(rows.Next())
+		var age int
+		err = rows.Scan(&age)
+		var se *ScanError
+		if assert.True(errors.As(err, &se)) {
+			assert.Equal("name", se.Column)
+			assert.Equal("ann", se.Value)
+		}
+	})
+}
+
+func TestQueryCancelled(t *testing.T) {
+	assert := assert.New(t)
+	db := open(t)
+	defer db.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	rows, err := db.Query(ctx, "SELECT name FROM people")
+	if !assert.Nil(err) {
+		return
+	}
+	assert.True(rows.Next())
+	cancel()
+
+	assert.False(rows.Next())
+	assert.Equal(context.Canceled, rows.Err())
+}
+
+func TestQueryLimits(t *testing.T) {
+	assert := assert.New(t)
+	db, err := Open(Options{Dir: "testdata", MaxRowsScanned: 2})
+	if !assert.Nil(err) {
+		return
+	}
+	defer db.Close()
+
+	stmt, err := db.Prepare("SELECT name FROM people")
+	if !assert.Nil(err) {
+		return
+	}
+	defer stmt.Close()
+	for i := 0; i < 2; i++ {
+		rows, err := stmt.Query(context.Background())
+		if !assert.Nil(err) {
+			return
+		}
+		assert.True(rows.Next())
+		assert.True(rows.Next())
+		assert.False(rows.Next())
+
+		var le *LimitError
+		assert.True(errors.As(rows.Err(), &le))
+		assert.Equal(int64(2), le.Max)
+	}
+}
+
+func TestClosed(t *testing.T) {
+	db := open(t)
+	db.Close()
+
+	_, err := db.Query(context.Background(), "SELECT name FROM people")
+
+	assert.Equal(t, ErrClosed, err)
+}
+
+func TestQueryArguments(t *testing.T) {
+	db := open(t)
+	defer db.Close()
+
+	tests := []struct {
+		query string
+		args  []interface{}
+		err   string
+	}{
+		{"SELECT name FROM people WHERE age = ?", []interface{}{42}, ""},
+		{"SELECT name FROM people WHERE age = $1", []interface{}{int64(42)}, ""},
+		{"SELECT name FROM people WHERE age = ?", nil, "mtsql: the query has 1 parameters, but 0 arguments were given"},
+		{"SELECT name FROM people WHERE age = ?", []interface{}{struct{}{}}, "mtsql: parameter ?: unsupported type struct {}"},
+	}
+	for _, test := range tests {
+		t.Run(test.query, func(t *testing.T) {
+			assert := assert.New(t)
+
+			rows, err := db.Query(context.Background(), test.query, test.args...)
+
+			if test.err != "" {
+				assert.EqualError(err, test.err)
+				return
+			}
+			if assert.Nil(err) {
+				defer rows.Close()
+				var name string
+				assert.True(rows.Next())
+				assert.Nil(rows.Scan(&name))
+				assert.Equal("bob", name)
+				assert.False(rows.Next())
+			}
+		})
+	}
+}
+
+func TestPrepare(t *testing.T) {
+	assert := assert.New(t)
+	db := open(t)
+	defer db.Close()
+
+	stmt, err := db.Prepare("SELECT * REPLACE (LOWER(:state) AS state) FROM people WHERE state = :state")
+	if !assert.Nil(err) {
+		return
+	}
+	defer stmt.Close()
+	assert.Equal(0, stmt.NumInput())
+
+	for state, expected := range map[string][]string{"WA": {"ann", "cat"}, "OR": {"bob"}} {
+		rows, err := stmt.Query(context.Background(), sql.Named("state", state))
+		if !assert.Nil(err) {
+			continue
+		}
+		names := []string{}
+		for rows.Next() {
+			var name, age, lower string
+			assert.Nil(rows.Scan(&name, &age, &lower))
+			assert.Equal(strings.ToLower(state), lower)
+			names = append(names, name)
+		}
+		assert.Nil(rows.Err())
+		assert.Equal(expected, names)
+	}
+}
+
+func TestStmtClose(t *testing.T) {
+	assert := assert.New(t)
+	db := open(t)
+	defer db.Close()
+
+	stmt, err := db.Prepare("SELECT name FROM people ORDER BY name")
+	if !assert.Nil(err) {
+		return
+	}
+	rows, err := stmt.Query(context.Background())
+	if !assert.Nil(err) {
+		return
+	}
+	assert.True(rows.Next())
+
+	assert.Nil(stmt.Close())
+	assert.False(rows.Next())
+	assert.Nil(stmt.Close())
+	_, err = stmt.Query(context.Background())
+	assert.Equal(ErrClosed, err)
+}
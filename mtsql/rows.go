@@ -0,0 +1,147 @@
+package mtsql
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+
+	md "github.com/jacobsimpson/mtsql/metadata"
+	"github.com/jacobsimpson/mtsql/physical"
+)
+
+// Rows iterates over the results of a query.
+//
+//	for rows.Next() {
+//		err := rows.Scan(&a, &b)
+//		...
+//	}
+//	err := rows.Err()
+type Rows struct {
+	ctx       context.Context
+	rowReader physical.RowReader
+	columns   []string
+	types     []md.ColumnType
+	row       []string
+	err       error
+	closed    bool
+}
+
+func newRows(ctx context.Context, rowReader physical.RowReader) *Rows {
+	columns := []string{}
+	types := []md.ColumnType{}
+	for _, c := range rowReader.Columns() {
+		columns = append(columns, c.Name)
+		types = append(types, c.Type)
+	}
+	return &Rows{
+		ctx:       ctx,
+		rowReader: rowReader,
+		columns:   columns,
+		types:     types,
+	}
+}
+
+// Columns returns the names of the columns in the results.
+func (r *Rows) Columns() []string {
+	return r.columns
+}
+
+// Next reads the next row, for Scan. It returns false at the end of the
+// results, or when there is an error, which is returned by Err.
+func (r *Rows) Next() bool {
+	if r.closed {
+		return false
+	}
+	if err := r.ctx.Err(); err != nil {
+		r.err = err
+		r.Close()
+		return false
+	}
+	row, err := r.rowReader.Read()
+	if err != nil || row == nil {
+		if err != io.EOF {
+			r.err = err
+		}
+		r.Close()
+		return false
+	}
+	r.row = row
+	return true
+}
+
+// Scan copies the columns of the current row into dest, which must have a
+// pointer for every column. The supported pointers are *string, *[]byte,
+// *int, *int64, *float64, *bool and *interface{}, which receives a string.
+func (r *Rows) Scan(dest ...interface{}) error {
+	if r.closed {
+		return ErrClosed
+	}
+	if r.row == nil {
+		return fmt.Errorf("mtsql: Scan called without calling Next")
+	}
+	if len(dest) != len(r.row) {
+		return fmt.Errorf("mtsql: expected %d destination arguments in Scan, not %d", len(r.row), len(dest))
+	}
+	for i, d := range dest {
+		if err := convert(d, r.row[i]); err != nil {
+			return &ScanError{Column: r.columns[i], Value: r.row[i], Err: err}
+		}
+	}
+	return nil
+}
+
+func convert(dest interface{}, value string) error {
+	switch d := dest.(type) {
+	case *string:
+		*d = value
+	case *[]byte:
+		*d = []byte(value)
+	case *interface{}:
+		*d = value
+	case *int:
+		i, err := strconv.Atoi(value)
+		if err != nil {
+			return err
+		}
+		*d = i
+	case *int64:
+		i, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return err
+		}
+		*d = i
+	case *float64:
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return err
+		}
+		*d = f
+	case *bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		*d = b
+	default:
+		return fmt.Errorf("unsupported destination type %T", dest)
+	}
+	return nil
+}
+
+// Err returns the error, if any, that ended the iteration.
+func (r *Rows) Err() error {
+	return r.err
+}
+
+// Close stops reading the results. It is called automatically when Next
+// returns false.
+func (r *Rows) Close() error {
+	if r.closed {
+		return nil
+	}
+	r.closed = true
+	r.row = nil
+	r.rowReader.Close()
+	return nil
+}
@@ -0,0 +1,69 @@
+package mtsql
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/jacobsimpson/mtsql/ast"
+	"github.com/jacobsimpson/mtsql/parameters"
+	"github.com/jacobsimpson/mtsql/physical"
+)
+
+// Stmt is a query that has been parsed and planned once, to be run with
+// different values for its placeholders. Only the results of the latest run
+// can be read, so running it again closes the Rows of the run before.
+type Stmt struct {
+	mu        sync.Mutex
+	params    []*ast.Constant
+	rowReader physical.RowReader
+	execution *physical.Execution
+	rows      *Rows
+	closed    bool
+}
+
+// NumInput is the number of positional arguments the query needs, for its ?
+// or $n placeholders.
+func (s *Stmt) NumInput() int {
+	return parameters.Count(s.params)
+}
+
+// Query runs the query. $n, or the nth ?, takes the nth argument, and :name
+// takes the argument given as sql.Named("name", value). Arguments can be
+// strings, integers, floats, bools, []byte or time.Time.
+func (s *Stmt) Query(ctx context.Context, args ...interface{}) (*Rows, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return nil, ErrClosed
+	}
+	if s.rows != nil {
+		s.rows.Close()
+	}
+	if err := parameters.Bind(s.params, args); err != nil {
+		return nil, fmt.Errorf("mtsql: %w", err)
+	}
+	s.execution.Restart(ctx)
+	if err := s.rowReader.Open(); err != nil {
+		return nil, err
+	}
+	s.rows = newRows(ctx, s.rowReader)
+	return s.rows, nil
+}
+
+// Close releases the query, closing the Rows of its latest run and the files
+// it has open.
+func (s *Stmt) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+	var err error
+	if s.rows != nil {
+		err = s.rows.Close()
+	}
+	s.rowReader.Close()
+	return err
+}
@@ -0,0 +1,133 @@
+// Package parameters binds values to the placeholders in a query.
+package parameters
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jacobsimpson/mtsql/ast"
+	"github.com/jacobsimpson/mtsql/lexer"
+)
+
+// Bind sets the value of each placeholder in a query from the arguments.
+// $n, or the nth ?, takes the nth argument, and :name takes the argument
+// given as sql.Named("name", value). Binding again replaces the values, so a
+// query that has been planned can be run with different ones.
+func Bind(params []*ast.Constant, args []interface{}) error {
+	positional := []interface{}{}
+	named := map[string]interface{}{}
+	for _, a := range args {
+		if n, ok := a.(sql.NamedArg); ok {
+			named[n.Name] = n.Value
+		} else {
+			positional = append(positional, a)
+		}
+	}
+	if want := Count(params); want != len(positional) {
+		return fmt.Errorf("the query has %d parameters, but %d arguments were given", want, len(positional))
+	}
+
+	for _, p := range params {
+		var value interface{}
+		if p.Parameter.Name != "" {
+			v, ok := named[p.Parameter.Name]
+			if !ok {
+				return fmt.Errorf("no value given for parameter %s", p.Raw)
+			}
+			value = v
+		} else {
+			value = positional[p.Parameter.Position-1]
+		}
+		if err := setValue(p, value); err != nil {
+			return fmt.Errorf("parameter %s: %v", p.Raw, err)
+		}
+	}
+	return nil
+}
+
+// Count is the number of positional arguments a query with the placeholders
+// needs.
+func Count(params []*ast.Constant) int {
+	n := 0
+	for _, p := range params {
+		if p.Parameter.Position > n {
+			n = p.Parameter.Position
+		}
+	}
+	return n
+}
+
+// setValue sets the type and value of a placeholder from a Go value. Raw is
+// left as the placeholder, so plans show where the value came from.
+func setValue(c *ast.Constant, v interface{}) error {
+	switch v := v.(type) {
+	case string:
+		c.Type, c.Value = ast.StringType, v
+	case []byte:
+		c.Type, c.Value = ast.StringType, string(v)
+	case int:
+		c.Type, c.Value = ast.IntegerType, v
+	case int8:
+		c.Type, c.Value = ast.IntegerType, int(v)
+	case int16:
+		c.Type, c.Value = ast.IntegerType, int(v)
+	case int32:
+		c.Type, c.Value = ast.IntegerType, int(v)
+	case int64:
+		c.Type, c.Value = ast.IntegerType, int(v)
+	case uint8:
+		c.Type, c.Value = ast.IntegerType, int(v)
+	case uint16:
+		c.Type, c.Value = ast.IntegerType, int(v)
+	case uint32:
+		c.Type, c.Value = ast.IntegerType, int(v)
+	case float32:
+		c.Type, c.Value = ast.FloatType, float64(v)
+	case float64:
+		c.Type, c.Value = ast.FloatType, v
+	case bool:
+		c.Type, c.Value = ast.BooleanType, v
+	case time.Time:
+		c.Type, c.Value = ast.StringType, v.Format(time.RFC3339Nano)
+	case nil:
+		return fmt.Errorf("NULL values are not supported")
+	default:
+		return fmt.Errorf("unsupported type %T", v)
+	}
+	return nil
+}
+
+// ParseValue reads a value written as a SQL constant, like 42, 1.5, true or
+// 'text'. Anything else, like a bare word, is taken as a string.
+func ParseValue(s string) interface{} {
+	lex := lexer.New(strings.NewReader(s))
+	lex.Next()
+	token := lex.Token()
+	lex.Next()
+	if lex.Token().Type != lexer.EOFType {
+		return s
+	}
+	switch token.Type {
+	case lexer.StringType:
+		return token.Value
+	case lexer.IntegerType:
+		if i, err := strconv.Atoi(token.Raw); err == nil {
+			return i
+		}
+	case lexer.FloatType:
+		if f, err := strconv.ParseFloat(token.Raw, 64); err == nil {
+			return f
+		}
+	case lexer.IdentifierType:
+		switch strings.ToLower(token.Raw) {
+		case "true":
+			return true
+		case "false":
+			return false
+		}
+	}
+	return s
+}
@@ -0,0 +1,66 @@
+package parameters
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/jacobsimpson/mtsql/ast"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBind(t *testing.T) {
+	assert := assert.New(t)
+	first := &ast.Constant{Raw: "$1", Parameter: &ast.Parameter{Position: 1}}
+	second := &ast.Constant{Raw: "$2", Parameter: &ast.Parameter{Position: 2}}
+	again := &ast.Constant{Raw: "$1", Parameter: &ast.Parameter{Position: 1}}
+
+	err := Bind([]*ast.Constant{first, second, again}, []interface{}{"WA", int64(3)})
+
+	assert.Nil(err)
+	assert.Equal(&ast.Constant{Type: ast.StringType, Value: "WA", Raw: "$1", Parameter: &ast.Parameter{Position: 1}}, first)
+	assert.Equal(&ast.Constant{Type: ast.IntegerType, Value: 3, Raw: "$2", Parameter: &ast.Parameter{Position: 2}}, second)
+	assert.Equal(first, again)
+}
+
+func TestBindNamed(t *testing.T) {
+	assert := assert.New(t)
+	state := &ast.Constant{Raw: ":state", Parameter: &ast.Parameter{Name: "state"}}
+
+	err := Bind([]*ast.Constant{state}, []interface{}{sql.Named("state", 1.5)})
+
+	assert.Nil(err)
+	assert.Equal(ast.FloatType, state.Type)
+	assert.Equal(1.5, state.Value)
+
+	err = Bind([]*ast.Constant{state}, []interface{}{sql.Named("city", "x")})
+	assert.EqualError(err, "no value given for parameter :state")
+}
+
+func TestBindErrors(t *testing.T) {
+	assert := assert.New(t)
+	params := []*ast.Constant{{Raw: "?", Parameter: &ast.Parameter{Position: 1}}}
+
+	assert.EqualError(Bind(params, nil), "the query has 1 parameters, but 0 arguments were given")
+	assert.EqualError(Bind(params, []interface{}{nil}), "parameter ?: NULL values are not supported")
+	assert.EqualError(Bind(params, []interface{}{[]int{1}}), "parameter ?: unsupported type []int")
+}
+
+func TestParseValue(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"42", 42},
+		{"-1.5", -1.5},
+		{"true", true},
+		{"FALSE", false},
+		{"'42'", "42"},
+		{"'it''s'", "it's"},
+		{"WA", "WA"},
+		{"New York", "New York"},
+		{"", ""},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.expected, ParseValue(test.input), test.input)
+	}
+}
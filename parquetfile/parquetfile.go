@@ -0,0 +1,358 @@
+package parquetfile
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"os"
+	"strconv"
+	"time"
+
+	goparquet "github.com/fraugster/parquet-go"
+	"github.com/fraugster/parquet-go/parquet"
+
+	md "github.com/jacobsimpson/mtsql/metadata"
+)
+
+// Statistics are the smallest and largest values of a column within a row
+// group. The values are int64, float64, string or bool, depending on the
+// column, or nil when the file does not record them.
+type Statistics struct {
+	Min interface{}
+	Max interface{}
+}
+
+// RowGroupFilter decides whether a row group might contain rows of interest,
+// from the statistics of its columns. Row groups it rejects are not read.
+type RowGroupFilter func(stats map[string]*Statistics) bool
+
+// Reader reads the rows of a Parquet file. Only the top level fields are
+// columns, nested groups are converted to JSON text.
+type Reader struct {
+	file      *os.File
+	reader    *goparquet.FileReader
+	meta      *parquet.FileMetaData
+	header    []string
+	types     []md.ColumnType
+	elements  []*parquet.SchemaElement
+	filter    RowGroupFilter
+	group     int
+	remaining int64
+	skipped   int
+}
+
+// Open opens a Parquet file, reading only the named columns, or every column
+// when columns is empty.
+func Open(fileName string, columns []string, filter RowGroupFilter) (*Reader, error) {
+	f, err := os.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+	r, err := newReader(f, columns, filter)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return r, nil
+}
+
+func newReader(f *os.File, columns []string, filter RowGroupFilter) (*Reader, error) {
+	meta, err := goparquet.ReadFileMetaData(f, false)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read parquet metadata: %v", err)
+	}
+	reader, err := goparquet.NewFileReaderWithOptions(f,
+		goparquet.WithFileMetaData(meta),
+		goparquet.WithColumns(columns...))
+	if err != nil {
+		return nil, err
+	}
+
+	selected := map[string]bool{}
+	for _, c := range columns {
+		selected[c] = true
+	}
+	r := &Reader{
+		file:   f,
+		reader: reader,
+		meta:   meta,
+		filter: filter,
+	}
+	for _, e := range topLevel(meta.Schema) {
+		if len(columns) > 0 && !selected[e.Name] {
+			continue
+		}
+		r.header = append(r.header, e.Name)
+		r.types = append(r.types, columnType(e))
+		r.elements = append(r.elements, e)
+	}
+	if len(r.header) < len(columns) {
+		return nil, fmt.Errorf("parquet file does not have all of the columns %q", columns)
+	}
+	return r, nil
+}
+
+// topLevel returns the fields directly under the root of a flattened schema.
+func topLevel(schema []*parquet.SchemaElement) []*parquet.SchemaElement {
+	result := []*parquet.SchemaElement{}
+	for i := 1; i < len(schema); {
+		result = append(result, schema[i])
+		i += descendants(schema, i) + 1
+	}
+	return result
+}
+
+func descendants(schema []*parquet.SchemaElement, i int) int {
+	n := 0
+	for c := int32(0); c < schema[i].GetNumChildren(); c++ {
+		n += descendants(schema, i+n+1) + 1
+	}
+	return n
+}
+
+// Header returns the names of the columns being read.
+func (r *Reader) Header() []string {
+	return r.header
+}
+
+// Types returns the type of each column being read.
+func (r *Reader) Types() []md.ColumnType {
+	return r.types
+}
+
+// SkippedRowGroups is the number of row groups the filter has rejected.
+func (r *Reader) SkippedRowGroups() int {
+	return r.skipped
+}
+
+// Read returns the next row, or io.EOF when there are no more.
+func (r *Reader) Read() ([]string, error) {
+	for r.remaining == 0 {
+		if err := r.nextRowGroup(); err != nil {
+			return nil, err
+		}
+	}
+	values, err := r.reader.NextRow()
+	if err != nil {
+		return nil, err
+	}
+	r.remaining--
+
+	row := make([]string, len(r.header))
+	for i, name := range r.header {
+		row[i] = text(values[name], r.elements[i])
+	}
+	return row, nil
+}
+
+func (r *Reader) nextRowGroup() error {
+	for r.group < len(r.meta.RowGroups) {
+		rowGroup := r.meta.RowGroups[r.group]
+		r.group++
+		if r.filter != nil && !r.filter(statistics(rowGroup)) {
+			r.skipped++
+			continue
+		}
+		// SeekToRowGroup counts row groups from 1.
+		if err := r.reader.SeekToRowGroup(r.group); err != nil {
+			return err
+		}
+		r.remaining = rowGroup.NumRows
+		return nil
+	}
+	return io.EOF
+}
+
+func (r *Reader) Close() error {
+	return r.file.Close()
+}
+
+func statistics(rowGroup *parquet.RowGroup) map[string]*Statistics {
+	result := map[string]*Statistics{}
+	for _, c := range rowGroup.Columns {
+		m := c.GetMetaData()
+		if m == nil || len(m.PathInSchema) != 1 || m.Statistics == nil {
+			continue
+		}
+		min, max := m.Statistics.MinValue, m.Statistics.MaxValue
+		if len(max) == 0 && m.Type != parquet.Type_BYTE_ARRAY {
+			// The deprecated fields used a signed comparison, which is only
+			// trustworthy for numbers.
+			min, max = m.Statistics.Min, m.Statistics.Max
+		}
+		if len(max) == 0 {
+			// Writers leave the statistics empty when they don't keep them,
+			// even though the field is set.
+			continue
+		}
+		result[m.PathInSchema[0]] = &Statistics{
+			Min: statisticValue(m.Type, min),
+			Max: statisticValue(m.Type, max),
+		}
+	}
+	return result
+}
+
+func statisticValue(t parquet.Type, b []byte) interface{} {
+	switch {
+	case len(b) == 0:
+		return nil
+	case t == parquet.Type_BOOLEAN && len(b) == 1:
+		return b[0] != 0
+	case t == parquet.Type_INT32 && len(b) == 4:
+		return int64(int32(binary.LittleEndian.Uint32(b)))
+	case t == parquet.Type_INT64 && len(b) == 8:
+		return int64(binary.LittleEndian.Uint64(b))
+	case t == parquet.Type_FLOAT && len(b) == 4:
+		return float64(math.Float32frombits(binary.LittleEndian.Uint32(b)))
+	case t == parquet.Type_DOUBLE && len(b) == 8:
+		return math.Float64frombits(binary.LittleEndian.Uint64(b))
+	case t == parquet.Type_BYTE_ARRAY:
+		return string(b)
+	}
+	return nil
+}
+
+// columnType maps the physical and logical type of a Parquet field onto a
+// metadata.ColumnType.
+func columnType(e *parquet.SchemaElement) md.ColumnType {
+	if e.GetNumChildren() > 0 {
+		return md.StringType
+	}
+	lt := e.GetLogicalType()
+	switch {
+	case lt != nil && lt.IsSetDATE(), e.IsSetConvertedType() && e.GetConvertedType() == parquet.ConvertedType_DATE:
+		return md.DateType
+	case lt != nil && lt.IsSetTIMESTAMP(), e.GetType() == parquet.Type_INT96,
+		e.IsSetConvertedType() && (e.GetConvertedType() == parquet.ConvertedType_TIMESTAMP_MILLIS ||
+			e.GetConvertedType() == parquet.ConvertedType_TIMESTAMP_MICROS):
+		return md.TimestampType
+	case lt != nil && lt.IsSetDECIMAL(), e.IsSetConvertedType() && e.GetConvertedType() == parquet.ConvertedType_DECIMAL:
+		return md.FloatType
+	}
+	switch e.GetType() {
+	case parquet.Type_BOOLEAN:
+		return md.BooleanType
+	case parquet.Type_INT32, parquet.Type_INT64:
+		return md.IntegerType
+	case parquet.Type_FLOAT, parquet.Type_DOUBLE:
+		return md.FloatType
+	}
+	return md.StringType
+}
+
+// text converts a value read from the file into its string representation,
+// according to the type of the field.
+func text(v interface{}, e *parquet.SchemaElement) string {
+	if v == nil {
+		return ""
+	}
+	if e.GetNumChildren() > 0 {
+		b, err := json.Marshal(jsonValue(v))
+		if err != nil {
+			return ""
+		}
+		return string(b)
+	}
+
+	switch columnType(e) {
+	case md.DateType:
+		if days, ok := v.(int32); ok {
+			return time.Unix(int64(days)*24*60*60, 0).UTC().Format("2006-01-02")
+		}
+	case md.TimestampType:
+		if t, ok := timestamp(v, e); ok {
+			return t.UTC().Format(time.RFC3339Nano)
+		}
+	case md.FloatType:
+		if e.GetType() != parquet.Type_FLOAT && e.GetType() != parquet.Type_DOUBLE {
+			return decimal(v, e.GetScale())
+		}
+	}
+
+	switch v := v.(type) {
+	case int32:
+		return strconv.FormatInt(int64(v), 10)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float32:
+		return strconv.FormatFloat(float64(v), 'g', -1, 32)
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	case []byte:
+		return string(v)
+	}
+	return fmt.Sprintf("%v", v)
+}
+
+func timestamp(v interface{}, e *parquet.SchemaElement) (time.Time, bool) {
+	if b, ok := v.([12]byte); ok {
+		return goparquet.Int96ToTime(b), true
+	}
+	n, ok := v.(int64)
+	if !ok {
+		return time.Time{}, false
+	}
+	if lt := e.GetLogicalType(); lt != nil && lt.IsSetTIMESTAMP() {
+		unit := lt.GetTIMESTAMP().GetUnit()
+		switch {
+		case unit.IsSetNANOS():
+			return time.Unix(0, n), true
+		case unit.IsSetMICROS():
+			return time.Unix(0, n*int64(time.Microsecond)), true
+		}
+		return time.Unix(0, n*int64(time.Millisecond)), true
+	}
+	if e.GetConvertedType() == parquet.ConvertedType_TIMESTAMP_MICROS {
+		return time.Unix(0, n*int64(time.Microsecond)), true
+	}
+	return time.Unix(0, n*int64(time.Millisecond)), true
+}
+
+// decimal formats an unscaled integer, stored as an int32, int64 or big
+// endian two's complement bytes, with the decimal point scale digits from
+// the right.
+func decimal(v interface{}, scale int32) string {
+	unscaled := new(big.Int)
+	switch v := v.(type) {
+	case int32:
+		unscaled.SetInt64(int64(v))
+	case int64:
+		unscaled.SetInt64(v)
+	case []byte:
+		unscaled.SetBytes(v)
+		if len(v) > 0 && v[0]&0x80 != 0 {
+			unscaled.Sub(unscaled, new(big.Int).Lsh(big.NewInt(1), uint(len(v)*8)))
+		}
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+	return new(big.Rat).SetFrac(unscaled, new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(scale)), nil)).FloatString(int(scale))
+}
+
+// jsonValue converts the byte slices in nested values to strings, so they
+// are marshalled as text rather than base64.
+func jsonValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case []byte:
+		return string(v)
+	case map[string]interface{}:
+		result := map[string]interface{}{}
+		for k, e := range v {
+			result[k] = jsonValue(e)
+		}
+		return result
+	case []interface{}:
+		result := []interface{}{}
+		for _, e := range v {
+			result = append(result, jsonValue(e))
+		}
+		return result
+	}
+	return v
+}
@@ -0,0 +1,73 @@
+package parquetfile_test
+
+import (
+	"io"
+	"testing"
+
+	md "github.com/jacobsimpson/mtsql/metadata"
+	"github.com/jacobsimpson/mtsql/parquetfile"
+	"github.com/stretchr/testify/assert"
+)
+
+func readAll(t *testing.T, r *parquetfile.Reader) [][]string {
+	rows := [][]string{}
+	for {
+		row, err := r.Read()
+		if err == io.EOF {
+			return rows
+		}
+		assert.Nil(t, err)
+		rows = append(rows, row)
+	}
+}
+
+func TestReadAllColumns(t *testing.T) {
+	assert := assert.New(t)
+
+	r, err := parquetfile.Open("testdata/cities.parquet", nil, nil)
+	assert.Nil(err)
+	defer r.Close()
+
+	assert.Equal([]string{"id", "city", "state", "founded", "area", "capital"}, r.Header())
+	assert.Equal([]md.ColumnType{
+		md.IntegerType,
+		md.StringType,
+		md.StringType,
+		md.DateType,
+		md.FloatType,
+		md.BooleanType,
+	}, r.Types())
+	assert.Equal([][]string{
+		{"1", "Olympia", "WA", "1855-01-04", "19.7", "true"},
+		{"2", "Seattle", "WA", "1854-01-22", "142.5", "false"},
+		{"3", "Portland", "OR", "1846-10-18", "145.1", "false"},
+		{"4", "Salem", "OR", "", "49.1", "true"},
+		{"5", "Boise", "ID", "1857-09-30", "84", "true"},
+	}, readAll(t, r))
+}
+
+func TestReadSelectedColumns(t *testing.T) {
+	assert := assert.New(t)
+
+	r, err := parquetfile.Open("testdata/cities.parquet", []string{"state", "city"}, nil)
+	assert.Nil(err)
+	defer r.Close()
+
+	assert.Equal([]string{"city", "state"}, r.Header())
+	assert.Equal([]string{"Olympia", "WA"}, readAll(t, r)[0])
+}
+
+func TestSkipRowGroups(t *testing.T) {
+	assert := assert.New(t)
+
+	idIsFour := func(stats map[string]*parquetfile.Statistics) bool {
+		s := stats["id"]
+		return s == nil || s.Min.(int64) <= 4 && 4 <= s.Max.(int64)
+	}
+	r, err := parquetfile.Open("testdata/cities.parquet", []string{"id", "state"}, idIsFour)
+	assert.Nil(err)
+	defer r.Close()
+
+	assert.Equal([][]string{{"3", "OR"}, {"4", "OR"}}, readAll(t, r))
+	assert.Equal(2, r.SkippedRowGroups())
+}
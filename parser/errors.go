@@ -0,0 +1,103 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jacobsimpson/mtsql/ast"
+	"github.com/jacobsimpson/mtsql/lexer"
+	"github.com/jacobsimpson/mtsql/suggest"
+)
+
+// ParseError is a syntax error in a query. Line and Column, both starting at
+// 1, are where the offending token begins, and Expected is what would have
+// been valid in its place.
+type ParseError struct {
+	Line     int
+	Column   int
+	Found    *lexer.Token
+	Expected []string
+	Message  string
+}
+
+func (e *ParseError) Error() string {
+	return e.Message
+}
+
+// Suggestion returns the expected keyword the offending token is most likely
+// a misspelling of, or "" if there isn't one.
+func (e *ParseError) Suggestion() string {
+	if e.Found == nil || e.Found.Type != lexer.IdentifierType {
+		return ""
+	}
+	keywords := []string{}
+	for _, expected := range e.Expected {
+		// Keywords are upper case, which leaves out descriptions like
+		// "column", and punctuation, which has no case at all.
+		if keyword := strings.Fields(expected)[0]; keyword == strings.ToUpper(keyword) && keyword != strings.ToLower(keyword) {
+			keywords = append(keywords, keyword)
+		}
+	}
+	return suggest.Closest(e.Found.Raw, keywords)
+}
+
+// Annotate returns the line of the query the error is on, with a caret
+// under the start of the offending token, and a suggestion if there is one.
+func (e *ParseError) Annotate(query string) string {
+	lines := strings.Split(query, "\n")
+	if e.Line < 1 || e.Line > len(lines) {
+		return e.Message
+	}
+	line := strings.TrimRight(lines[e.Line-1], "\r")
+	caret := ""
+	for i, r := range []rune(line) {
+		if i >= e.Column-1 {
+			break
+		}
+		// Keep tabs, so the caret lines up however they are displayed.
+		if r == '\t' {
+			caret += "\t"
+		} else {
+			caret += " "
+		}
+	}
+	for len([]rune(caret)) < e.Column-1 {
+		caret += " "
+	}
+	result := fmt.Sprintf("%s\n%s\n%s^", e.Message, line, caret)
+	if s := e.Suggestion(); s != "" {
+		result += fmt.Sprintf("\ndid you mean %s?", s)
+	}
+	return result
+}
+
+// errorAt creates a ParseError for an unexpected token. When the token is
+// a tokenizing error, its message is the one reported.
+func errorAt(token *lexer.Token, expected []string, format string, args ...interface{}) *ParseError {
+	message := fmt.Sprintf(format, args...)
+	if token.Type == lexer.ErrorType {
+		message = fmt.Sprintf("could not tokenize input: %v", token.Raw)
+	}
+	return &ParseError{
+		Line:     token.Line,
+		Column:   token.Column,
+		Found:    token,
+		Expected: expected,
+		Message:  message,
+	}
+}
+
+// remaining lists what could follow the clauses of a query parsed so far.
+func remaining(q *ast.SFW) []string {
+	result := []string{}
+	if _, ok := q.From.(*ast.Relation); ok && q.Where == nil && q.OrderBy == nil {
+		result = append(result, "INNER JOIN")
+	}
+	if q.Where == nil && q.OrderBy == nil {
+		result = append(result, "WHERE")
+	}
+	if q.OrderBy == nil {
+		result = append(result, "ORDER BY")
+	}
+	return append(result, ";")
+}
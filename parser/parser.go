@@ -1,7 +1,6 @@
 package parser
 
 import (
-	"fmt"
 	"strconv"
 	"strings"
 
@@ -14,19 +13,117 @@ func Parse(lex lexer.Lexer) (ast.Query, error) {
 }
 
 func query(lex lexer.Lexer) (ast.Query, error) {
-	if s, err := sfw(lex); err != nil {
+	q, expected, err := statement(lex)
+	if err != nil {
 		return nil, err
+	}
+
+	lex.Next()
+	token := lex.Token()
+	if token.Type == lexer.SemicolonType {
+		lex.Next()
+		token = lex.Token()
+	}
+	if token.Type == lexer.ErrorType {
+		return nil, errorAt(token, nil, "could not tokenize input: %v", token.Raw)
+	}
+	if token.Type != lexer.EOFType {
+		return nil, errorAt(token, expected, "extra stuff left over: %v", token.Raw)
+	}
+	if err := numberParameters(q, token); err != nil {
+		return nil, err
+	}
+	return q, nil
+}
+
+// numberParameters gives each ? placeholder its position. The kinds of
+// placeholder can't be mixed, since it wouldn't be clear which value is which.
+func numberParameters(q ast.Query, end *lexer.Token) error {
+	kinds := map[byte]bool{}
+	anonymous := 0
+	for _, p := range ast.Parameters(q) {
+		kinds[p.Raw[0]] = true
+		if p.Raw == "?" {
+			anonymous++
+			p.Parameter.Position = anonymous
+		}
+	}
+	if len(kinds) > 1 {
+		return errorAt(end, nil, "can not mix ?, $n and :name placeholders in one query")
+	}
+	return nil
+}
+
+// statement parses any kind of statement, and returns what could follow it.
+func statement(lex lexer.Lexer) (ast.Query, []string, error) {
+	if s, err := sfw(lex); err != nil {
+		return nil, nil, err
 	} else if s != nil {
-		return s, nil
+		return s, remaining(s), nil
 	}
 
 	if p, err := profile(lex); err != nil {
-		return nil, err
+		return nil, nil, err
 	} else if p != nil {
-		return p, nil
+		return p, remaining(p.SFW), nil
+	}
+
+	if c, err := copyTo(lex); err != nil {
+		return nil, nil, err
+	} else if c != nil {
+		if c.Options == nil {
+			return c, []string{"WITH", ";"}, nil
+		}
+		return c, []string{";"}, nil
 	}
 
-	return nil, fmt.Errorf("expected SELECT or PROFILE")
+	if c, err := create(lex); err != nil {
+		return nil, nil, err
+	} else if t, ok := c.(*ast.CreateTableAs); ok {
+		return t, remaining(t.Query), nil
+	} else if c != nil {
+		return c, []string{";"}, nil
+	}
+
+	if s, err := show(lex); err != nil {
+		return nil, nil, err
+	} else if s != nil {
+		return s, []string{";"}, nil
+	}
+
+	if d, err := describe(lex); err != nil {
+		return nil, nil, err
+	} else if d != nil {
+		return d, []string{";"}, nil
+	}
+
+	if i, err := insert(lex); err != nil {
+		return nil, nil, err
+	} else if i != nil {
+		return i, remaining(i.Query), nil
+	}
+
+	if d, err := deleteFrom(lex); err != nil {
+		return nil, nil, err
+	} else if d != nil {
+		if d.Where == nil {
+			return d, []string{"WHERE", ";"}, nil
+		}
+		return d, []string{";"}, nil
+	}
+
+	if u, err := update(lex); err != nil {
+		return nil, nil, err
+	} else if u != nil {
+		if u.Where == nil {
+			return u, []string{",", "WHERE", ";"}, nil
+		}
+		return u, []string{";"}, nil
+	}
+
+	return nil, nil, errorAt(lex.Token(),
+		[]string{"SELECT", "PROFILE", "COPY", "CREATE TABLE", "CREATE INDEX", "SHOW", "DESCRIBE", "INSERT INTO", "DELETE FROM", "UPDATE"},
+		"expected SELECT, PROFILE, COPY, CREATE TABLE, CREATE INDEX, SHOW, DESCRIBE, INSERT INTO, DELETE FROM or UPDATE")
 }
 
 func profile(lex lexer.Lexer) (*ast.Profile, error) {
@@ -36,7 +133,7 @@ func profile(lex lexer.Lexer) (*ast.Profile, error) {
 		return nil, nil
 	}
 
-	sfw, err := sfw(lex)
+	sfw, err := requiredSfw(lex)
 	if err != nil {
 		return nil, err
 	}
@@ -45,6 +142,333 @@ func profile(lex lexer.Lexer) (*ast.Profile, error) {
 	}, nil
 }
 
+// copyTo parses COPY (SELECT ...) TO 'path' WITH (name value, ...).
+func copyTo(lex lexer.Lexer) (*ast.Copy, error) {
+	if ok, err := ifKeywords(lex, "COPY"); err != nil {
+		return nil, err
+	} else if !ok {
+		return nil, nil
+	}
+
+	if ok, err := ifToken(lex, lexer.LeftParenType); err != nil {
+		return nil, err
+	} else if !ok {
+		return nil, errorAt(lex.Token(), []string{"("}, "expected ( after COPY")
+	}
+	sfw, err := requiredSfw(lex)
+	if err != nil {
+		return nil, err
+	}
+	if ok, err := ifToken(lex, lexer.RightParenType); err != nil {
+		return nil, err
+	} else if !ok {
+		return nil, errorAt(lex.Token(), append(remaining(sfw), ")"), "expected ) after the query to COPY, found %q", lex.Token().Raw)
+	}
+
+	if ok, err := ifKeywords(lex, "TO"); err != nil {
+		return nil, err
+	} else if !ok {
+		return nil, errorAt(lex.Token(), []string{"TO"}, "expected TO after COPY (...)")
+	}
+	lex.Next()
+	token := lex.Token()
+	if token.Type != lexer.StringType {
+		return nil, errorAt(token, []string{"file name"}, "expected a file name to COPY to, found %q", token.Raw)
+	}
+	result := &ast.Copy{Query: sfw, Path: token.Value}
+
+	if ok, err := ifKeywords(lex, "WITH"); err != nil {
+		return nil, err
+	} else if !ok {
+		return result, nil
+	}
+	result.Options = []*ast.Option{}
+	err = list(lex, "WITH", func() error {
+		lex.Next()
+		token := lex.Token()
+		if token.Type != lexer.IdentifierType {
+			return errorAt(token, []string{"option"}, "expected option name, found %q", token.Raw)
+		}
+		option, err := copyOption(lex, token.Raw)
+		if err != nil {
+			return err
+		}
+		result.Options = append(result.Options, option)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// copyOption parses the value of a COPY option, which is a constant, true or
+// false, or a bare word like csv.
+func copyOption(lex lexer.Lexer, name string) (*ast.Option, error) {
+	if _, err := ifToken(lex, lexer.EqualType); err != nil {
+		return nil, err
+	}
+	lex.Next()
+	token := lex.Token()
+	if token.Type == lexer.IdentifierType {
+		switch strings.ToUpper(token.Raw) {
+		case "TRUE":
+			return &ast.Option{Name: name, Value: &ast.Constant{Type: ast.BooleanType, Value: true, Raw: token.Raw}}, nil
+		case "FALSE":
+			return &ast.Option{Name: name, Value: &ast.Constant{Type: ast.BooleanType, Value: false, Raw: token.Raw}}, nil
+		}
+		return &ast.Option{Name: name, Value: &ast.Constant{Type: ast.StringType, Value: token.Raw, Raw: token.Raw}}, nil
+	}
+	value, err := constant(token)
+	if err != nil {
+		return nil, err
+	}
+	return &ast.Option{Name: name, Value: value}, nil
+}
+
+// create parses CREATE TABLE and CREATE INDEX.
+func create(lex lexer.Lexer) (ast.Query, error) {
+	if ok, err := ifKeywords(lex, "CREATE"); err != nil {
+		return nil, err
+	} else if !ok {
+		return nil, nil
+	}
+	if ok, err := ifKeywords(lex, "INDEX"); err != nil {
+		return nil, err
+	} else if ok {
+		return createIndex(lex)
+	}
+	if ok, err := ifKeywords(lex, "TABLE"); err != nil {
+		return nil, err
+	} else if !ok {
+		return nil, errorAt(lex.Token(), []string{"TABLE", "INDEX"}, "expected TABLE or INDEX after CREATE")
+	}
+	return createTable(lex)
+}
+
+// createIndex parses name ON table (column), after CREATE INDEX.
+func createIndex(lex lexer.Lexer) (*ast.CreateIndex, error) {
+	lex.Next()
+	token := lex.Token()
+	if !isIdentifier(token) {
+		return nil, errorAt(token, []string{"index"}, "expected the name of the index to create, found %q", token.Raw)
+	}
+	result := &ast.CreateIndex{Name: identifier(token)}
+
+	if ok, err := ifKeywords(lex, "ON"); err != nil {
+		return nil, err
+	} else if !ok {
+		return nil, errorAt(lex.Token(), []string{"ON"}, "expected ON after CREATE INDEX %s", result.Name)
+	}
+	// The table can't be a table function, which would look the same as
+	// the column list.
+	lex.Next()
+	token = lex.Token()
+	switch {
+	case token.Type == lexer.StringType:
+		result.Table = &ast.Relation{Path: token.Value}
+	case isIdentifier(token):
+		result.Table = &ast.Relation{Name: identifier(token), Quoted: isQuoted(token)}
+	default:
+		return nil, errorAt(token, []string{"table"}, "expected the name of the table to index, found %q", token.Raw)
+	}
+
+	err := list(lex, "ON "+token.Raw, func() error {
+		if result.Column != nil {
+			return errorAt(lex.Token(), []string{")"}, "an index can only be on one column")
+		}
+		column, err := field(lex)
+		if err != nil {
+			return err
+		}
+		result.Column = column
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// createTable parses name AS SELECT ..., after CREATE TABLE.
+func createTable(lex lexer.Lexer) (*ast.CreateTableAs, error) {
+	name, err := tableName(lex)
+	if err != nil {
+		return nil, err
+	}
+	if name.Name == "" {
+		return nil, errorAt(lex.Token(), []string{"table"}, "expected the name of the table to create")
+	}
+
+	if ok, err := ifKeywords(lex, "AS"); err != nil {
+		return nil, err
+	} else if !ok {
+		return nil, errorAt(lex.Token(), []string{"AS"}, "expected AS after CREATE TABLE %s", name.Name)
+	}
+	sfw, err := requiredSfw(lex)
+	if err != nil {
+		return nil, err
+	}
+	return &ast.CreateTableAs{Table: name, Query: sfw}, nil
+}
+
+// show parses SHOW TABLES, SHOW COLUMNS FROM table and SHOW CREATE TABLE table.
+func show(lex lexer.Lexer) (ast.Query, error) {
+	if ok, err := ifKeywords(lex, "SHOW"); err != nil {
+		return nil, err
+	} else if !ok {
+		return nil, nil
+	}
+	if ok, err := ifKeywords(lex, "TABLES"); err != nil {
+		return nil, err
+	} else if ok {
+		return &ast.ShowTables{}, nil
+	}
+	if ok, err := ifKeywords(lex, "COLUMNS", "FROM"); err != nil {
+		return nil, err
+	} else if ok {
+		table, err := tableName(lex)
+		if err != nil {
+			return nil, err
+		}
+		return &ast.Describe{Table: table}, nil
+	}
+	if ok, err := ifKeywords(lex, "CREATE", "TABLE"); err != nil {
+		return nil, err
+	} else if ok {
+		table, err := tableName(lex)
+		if err != nil {
+			return nil, err
+		}
+		return &ast.ShowCreateTable{Table: table}, nil
+	}
+	return nil, errorAt(lex.Token(), []string{"TABLES", "COLUMNS FROM", "CREATE TABLE"},
+		"expected TABLES, COLUMNS FROM or CREATE TABLE after SHOW")
+}
+
+// describe parses DESCRIBE table.
+func describe(lex lexer.Lexer) (*ast.Describe, error) {
+	if ok, err := ifKeywords(lex, "DESCRIBE"); err != nil {
+		return nil, err
+	} else if !ok {
+		return nil, nil
+	}
+	table, err := tableName(lex)
+	if err != nil {
+		return nil, err
+	}
+	return &ast.Describe{Table: table}, nil
+}
+
+// insert parses INSERT INTO name SELECT ...
+func insert(lex lexer.Lexer) (*ast.Insert, error) {
+	if ok, err := ifKeywords(lex, "INSERT", "INTO"); err != nil {
+		return nil, err
+	} else if !ok {
+		return nil, nil
+	}
+
+	name, err := tableName(lex)
+	if err != nil {
+		return nil, err
+	}
+	if name.Function != nil {
+		return nil, errorAt(lex.Token(), []string{"table"}, "expected the name of the table to insert into")
+	}
+	sfw, err := requiredSfw(lex)
+	if err != nil {
+		return nil, err
+	}
+	return &ast.Insert{Table: name, Query: sfw}, nil
+}
+
+// deleteFrom parses DELETE FROM table WHERE condition.
+func deleteFrom(lex lexer.Lexer) (*ast.Delete, error) {
+	if ok, err := ifKeywords(lex, "DELETE", "FROM"); err != nil {
+		return nil, err
+	} else if !ok {
+		return nil, nil
+	}
+
+	name, err := tableName(lex)
+	if err != nil {
+		return nil, err
+	}
+	if name.Function != nil {
+		return nil, errorAt(lex.Token(), []string{"table"}, "expected the name of the table to delete from")
+	}
+	condition, err := where(lex)
+	if err != nil {
+		return nil, err
+	}
+	return &ast.Delete{Table: name, Where: condition}, nil
+}
+
+// update parses UPDATE table SET column = expression, ... WHERE condition.
+func update(lex lexer.Lexer) (*ast.Update, error) {
+	if ok, err := ifKeywords(lex, "UPDATE"); err != nil {
+		return nil, err
+	} else if !ok {
+		return nil, nil
+	}
+
+	name, err := tableName(lex)
+	if err != nil {
+		return nil, err
+	}
+	if name.Function != nil {
+		return nil, errorAt(lex.Token(), []string{"table"}, "expected the name of the table to update")
+	}
+	if ok, err := ifKeywords(lex, "SET"); err != nil {
+		return nil, err
+	} else if !ok {
+		return nil, errorAt(lex.Token(), []string{"SET"}, "expected SET after UPDATE %s", name.Name)
+	}
+
+	result := &ast.Update{Table: name}
+	for {
+		column, err := field(lex)
+		if err != nil {
+			return nil, err
+		}
+		if ok, err := ifToken(lex, lexer.EqualType); err != nil {
+			return nil, err
+		} else if !ok {
+			return nil, errorAt(lex.Token(), []string{"="}, "expected = after %q", column.Name)
+		}
+		value, err := expression(lex)
+		if err != nil {
+			return nil, err
+		}
+		result.Set = append(result.Set, &ast.Assignment{Column: column, Value: value})
+
+		if ok, err := ifToken(lex, lexer.CommaType); err != nil {
+			return nil, err
+		} else if !ok {
+			break
+		}
+	}
+
+	condition, err := where(lex)
+	if err != nil {
+		return nil, err
+	}
+	result.Where = condition
+	return result, nil
+}
+
+// requiredSfw parses a SELECT query, where nothing else will do.
+func requiredSfw(lex lexer.Lexer) (*ast.SFW, error) {
+	sfw, err := sfw(lex)
+	if err != nil {
+		return nil, err
+	}
+	if sfw == nil {
+		return nil, errorAt(lex.Token(), []string{"SELECT"}, "expected SELECT")
+	}
+	return sfw, nil
+}
+
 func sfw(lex lexer.Lexer) (*ast.SFW, error) {
 	if ok, err := ifKeywords(lex, "SELECT"); err != nil {
 		return nil, err
@@ -77,16 +501,6 @@ func sfw(lex lexer.Lexer) (*ast.SFW, error) {
 	}
 	q.OrderBy = orderby
 
-	if !lex.Next() {
-		return &q, nil
-	}
-	token := lex.Token()
-	if token.Type == lexer.ErrorType {
-		return nil, fmt.Errorf("could not tokenize input: %v", token.Raw)
-	}
-	if token.Type != lexer.EOFType {
-		return nil, fmt.Errorf("extra stuff left over: %v", token.Raw)
-	}
 	return &q, nil
 }
 
@@ -103,7 +517,7 @@ func selList(lex lexer.Lexer) (*ast.SelList, error) {
 		result.Attributes = append(result.Attributes, attribute)
 
 		if !lex.Next() {
-			return nil, fmt.Errorf("unexpected end of query, no FROM clause specified")
+			return nil, errorAt(lex.Token(), []string{"FROM"}, "unexpected end of query, no FROM clause specified")
 		}
 		token := lex.Token()
 		if token.Type != lexer.CommaType {
@@ -115,50 +529,67 @@ func selList(lex lexer.Lexer) (*ast.SelList, error) {
 
 func attribute(lex lexer.Lexer) (*ast.Attribute, error) {
 	if !lex.Next() {
-		return nil, fmt.Errorf("expected column, found nothing")
+		return nil, errorAt(lex.Token(), []string{"column"}, "expected column, found nothing")
 	}
 	token := lex.Token()
 	if token.Type == lexer.StarType {
-		return &ast.Attribute{Name: token.Raw}, nil
-	} else if token.Type != lexer.IdentifierType {
-		return nil, fmt.Errorf("expected column name, found %q", token.Raw)
+		return star(lex, &ast.Attribute{Name: token.Raw})
+	} else if !isIdentifier(token) {
+		return nil, errorAt(token, []string{"column"}, "expected column name, found %q", token.Raw)
 	}
-	if strings.ToUpper(token.Raw) == "FROM" {
+	if isKeyword(token, "FROM") {
 		lex.UnreadToken()
 		return nil, nil
 	}
 
-	attribute := &ast.Attribute{Name: token.Raw}
+	attribute := &ast.Attribute{Name: identifier(token), Quoted: isQuoted(token)}
 	if !lex.Next() {
 		return attribute, nil
 	}
 	token = lex.Token()
 	if token.Type == lexer.PeriodType {
 		attribute.Qualifier = attribute.Name
+		attribute.QualifierQuoted = attribute.Quoted
 
 		if !lex.Next() {
-			return nil, fmt.Errorf("partially specified column '%s.'", attribute.Qualifier)
+			return nil, errorAt(lex.Token(), []string{"column"}, "partially specified column '%s.'", attribute.Qualifier)
 		}
 		token = lex.Token()
-		if token.Type != lexer.IdentifierType {
-			return nil, fmt.Errorf("expected identifier as part of qualified column name '%s.', found %q", attribute.Qualifier, token.Raw)
+		if token.Type == lexer.StarType {
+			attribute.Name = token.Raw
+			attribute.Quoted = false
+			return star(lex, attribute)
+		}
+		if !isIdentifier(token) {
+			return nil, errorAt(token, []string{"column", "*"}, "expected identifier as part of qualified column name '%s.', found %q", attribute.Qualifier, token.Raw)
 		}
-		attribute.Name = token.Raw
+		attribute.Name = identifier(token)
+		attribute.Quoted = isQuoted(token)
 		if !lex.Next() {
 			return attribute, nil
 		}
 		token = lex.Token()
 	}
 
-	if token.Type == lexer.CommaType || token.Type == lexer.EOFType ||
-		(token.Type == lexer.IdentifierType && strings.ToUpper(token.Raw) == "FROM") {
+	if isPathToken(token) {
+		lex.UnreadToken()
+		if err := path(lex, attribute); err != nil {
+			return nil, err
+		}
+		if !lex.Next() {
+			return attribute, nil
+		}
+		token = lex.Token()
+	}
+
+	if token.Type == lexer.CommaType || token.Type == lexer.EOFType || isKeyword(token, "FROM") {
 		lex.UnreadToken()
 		return attribute, nil
 	}
-	if token.Type != lexer.IdentifierType {
-		return nil, fmt.Errorf("expected alias for column name '%s.', found %q", attribute.Name, token.Raw)
+	if !isIdentifier(token) {
+		return nil, errorAt(token, []string{",", "FROM", "alias"}, "expected alias for column name '%s.', found %q", attribute.Name, token.Raw)
 	}
-	attribute.Alias = token.Raw
+	attribute.Alias = identifier(token)
 	return attribute, nil
 }
 
@@ -166,7 +597,7 @@ func from(lex lexer.Lexer) (ast.From, error) {
 	if ok, err := ifKeywords(lex, "FROM"); err != nil {
 		return nil, err
 	} else if !ok {
-		return nil, fmt.Errorf("expected FROM clause")
+		return nil, errorAt(lex.Token(), []string{"FROM"}, "expected FROM clause")
 	}
 
 	tableName, err := tableName(lex)
@@ -184,13 +615,100 @@ func from(lex lexer.Lexer) (ast.From, error) {
 
 func tableName(lex lexer.Lexer) (*ast.Relation, error) {
 	if !lex.Next() {
-		return nil, fmt.Errorf("expected table, found nothing")
+		return nil, errorAt(lex.Token(), []string{"table"}, "expected table, found nothing")
 	}
 	token := lex.Token()
+	if token.Type == lexer.StringType {
+		return &ast.Relation{Path: token.Value}, nil
+	}
+	if token.Type == lexer.QuotedIdentifierType {
+		return &ast.Relation{Name: token.Value, Quoted: true}, nil
+	}
 	if token.Type != lexer.IdentifierType {
-		return nil, fmt.Errorf("expected table name, found %q", token.Raw)
+		return nil, errorAt(token, []string{"table"}, "expected table name, found %q", token.Raw)
+	}
+	name := token.Raw
+
+	if ok, err := ifToken(lex, lexer.LeftParenType); err != nil {
+		return nil, err
+	} else if !ok {
+		return &ast.Relation{Name: name}, nil
+	}
+	function, err := tableFunction(lex, name)
+	if err != nil {
+		return nil, err
+	}
+	return &ast.Relation{Function: function}, nil
+}
+
+// tableFunction parses the arguments of a table valued function, after the
+// opening parenthesis. Positional arguments are constants, and come before
+// any name=value options.
+func tableFunction(lex lexer.Lexer, name string) (*ast.TableFunction, error) {
+	result := &ast.TableFunction{Name: name}
+	if ok, err := ifToken(lex, lexer.RightParenType); err != nil {
+		return nil, err
+	} else if ok {
+		return result, nil
+	}
+
+	for {
+		if !lex.Next() {
+			return nil, errorAt(lex.Token(), []string{"argument"}, "expected argument to %s, found nothing", name)
+		}
+		token := lex.Token()
+		if token.Type == lexer.IdentifierType {
+			option, err := option(lex, token.Raw)
+			if err != nil {
+				return nil, err
+			}
+			result.Options = append(result.Options, option)
+		} else if len(result.Options) > 0 {
+			return nil, errorAt(token, []string{"option"}, "expected option for %s, found %q", name, token.Raw)
+		} else {
+			c, err := constant(token)
+			if err != nil {
+				return nil, err
+			}
+			result.Args = append(result.Args, c)
+		}
+
+		if !lex.Next() {
+			return nil, errorAt(lex.Token(), []string{")"}, "expected ) after arguments to %s, found nothing", name)
+		}
+		token = lex.Token()
+		if token.Type == lexer.RightParenType {
+			return result, nil
+		}
+		if token.Type != lexer.CommaType {
+			return nil, errorAt(token, []string{",", ")"}, "expected ) after arguments to %s, found %q", name, token.Raw)
+		}
 	}
-	return &ast.Relation{Name: token.Raw}, nil
+}
+
+func option(lex lexer.Lexer, name string) (*ast.Option, error) {
+	if ok, err := ifToken(lex, lexer.EqualType); err != nil {
+		return nil, err
+	} else if !ok {
+		return nil, errorAt(lex.Token(), []string{"="}, "expected = after option %q", name)
+	}
+	if !lex.Next() {
+		return nil, errorAt(lex.Token(), []string{"value"}, "expected value for option %q, found nothing", name)
+	}
+	token := lex.Token()
+	if token.Type == lexer.IdentifierType {
+		switch strings.ToUpper(token.Raw) {
+		case "TRUE":
+			return &ast.Option{Name: name, Value: &ast.Constant{Type: ast.BooleanType, Value: true, Raw: token.Raw}}, nil
+		case "FALSE":
+			return &ast.Option{Name: name, Value: &ast.Constant{Type: ast.BooleanType, Value: false, Raw: token.Raw}}, nil
+		}
+	}
+	value, err := constant(token)
+	if err != nil {
+		return nil, err
+	}
+	return &ast.Option{Name: name, Value: value}, nil
 }
 
 func innerJoin(lex lexer.Lexer, left *ast.Relation) (*ast.InnerJoin, error) {
@@ -208,7 +726,7 @@ func innerJoin(lex lexer.Lexer, left *ast.Relation) (*ast.InnerJoin, error) {
 	if ok, err := ifKeywords(lex, "ON"); err != nil {
 		return nil, err
 	} else if !ok {
-		return nil, fmt.Errorf("INNER JOIN requires ON")
+		return nil, errorAt(lex.Token(), []string{"ON"}, "INNER JOIN requires ON")
 	}
 
 	on, err := fieldEqualsField(lex)
@@ -225,14 +743,19 @@ func innerJoin(lex lexer.Lexer, left *ast.Relation) (*ast.InnerJoin, error) {
 
 func field(lex lexer.Lexer) (*ast.Attribute, error) {
 	if !lex.Next() {
-		return nil, fmt.Errorf("expected field name, found nothing")
+		return nil, errorAt(lex.Token(), []string{"field"}, "expected field name, found nothing")
 	}
-	token := lex.Token()
-	if token.Type != lexer.IdentifierType {
-		return nil, fmt.Errorf("expected field name, found %q", token.Raw)
+	return fieldFrom(lex, lex.Token())
+}
+
+// fieldFrom parses a field name starting with a token that has already been
+// read.
+func fieldFrom(lex lexer.Lexer, token *lexer.Token) (*ast.Attribute, error) {
+	if !isIdentifier(token) {
+		return nil, errorAt(token, []string{"field"}, "expected field name, found %q", token.Raw)
 	}
 
-	result := &ast.Attribute{Name: token.Raw}
+	result := &ast.Attribute{Name: identifier(token), Quoted: isQuoted(token)}
 
 	// Check if this is a qualified field name.
 	if !lex.Next() {
@@ -241,22 +764,208 @@ func field(lex lexer.Lexer) (*ast.Attribute, error) {
 	token = lex.Token()
 	if token.Type != lexer.PeriodType {
 		lex.UnreadToken()
+		if err := path(lex, result); err != nil {
+			return nil, err
+		}
 		return result, nil
 	}
 
 	if !lex.Next() {
-		return nil, fmt.Errorf("expected an attribute, found nothing")
+		return nil, errorAt(lex.Token(), []string{"field"}, "expected an attribute, found nothing")
 	}
 	token = lex.Token()
-	if token.Type != lexer.IdentifierType {
-		return nil, fmt.Errorf("expected field name, found %q", token.Raw)
+	if !isIdentifier(token) {
+		return nil, errorAt(token, []string{"field"}, "expected field name, found %q", token.Raw)
 	}
 
 	result.Qualifier = result.Name
-	result.Name = token.Raw
+	result.QualifierQuoted = result.Quoted
+	result.Name = identifier(token)
+	result.Quoted = isQuoted(token)
+	if err := path(lex, result); err != nil {
+		return nil, err
+	}
 	return result, nil
 }
 
+// star parses the EXCLUDE and REPLACE clauses that may follow a *.
+func star(lex lexer.Lexer, attribute *ast.Attribute) (*ast.Attribute, error) {
+	if ok, err := ifKeywords(lex, "EXCLUDE"); err != nil {
+		return nil, err
+	} else if ok {
+		err := list(lex, "EXCLUDE", func() error {
+			f, err := field(lex)
+			if err != nil {
+				return err
+			}
+			attribute.Exclude = append(attribute.Exclude, f)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if ok, err := ifKeywords(lex, "REPLACE"); err != nil {
+		return nil, err
+	} else if ok {
+		err := list(lex, "REPLACE", func() error {
+			r, err := replacement(lex)
+			if err != nil {
+				return err
+			}
+			attribute.Replace = append(attribute.Replace, r)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return attribute, nil
+}
+
+// list parses a parenthesized, comma separated list, calling item to parse
+// each of the items.
+func list(lex lexer.Lexer, name string, item func() error) error {
+	if ok, err := ifToken(lex, lexer.LeftParenType); err != nil {
+		return err
+	} else if !ok {
+		return errorAt(lex.Token(), []string{"("}, "expected ( after %s", name)
+	}
+	for {
+		if err := item(); err != nil {
+			return err
+		}
+		lex.Next()
+		token := lex.Token()
+		if token.Type == lexer.RightParenType {
+			return nil
+		}
+		if token.Type != lexer.CommaType {
+			return errorAt(token, []string{",", ")"}, "expected ) after %s list, found %q", name, token.Raw)
+		}
+	}
+}
+
+// replacement parses an expression and the name of the column it replaces,
+// like UPPER(name) AS name.
+func replacement(lex lexer.Lexer) (*ast.Replacement, error) {
+	e, err := expression(lex)
+	if err != nil {
+		return nil, err
+	}
+	if ok, err := ifKeywords(lex, "AS"); err != nil {
+		return nil, err
+	} else if !ok {
+		return nil, errorAt(lex.Token(), []string{"AS"}, "expected AS after replacement expression")
+	}
+	lex.Next()
+	token := lex.Token()
+	if !isIdentifier(token) {
+		return nil, errorAt(token, []string{"column"}, "expected column name after AS, found %q", token.Raw)
+	}
+	return &ast.Replacement{Expression: e, Name: identifier(token), Quoted: isQuoted(token)}, nil
+}
+
+// expression parses a column, a constant or a function call.
+func expression(lex lexer.Lexer) (ast.Expression, error) {
+	lex.Next()
+	token := lex.Token()
+	if token.Type != lexer.IdentifierType {
+		if isIdentifier(token) {
+			return fieldFrom(lex, token)
+		}
+		return value(token)
+	}
+	if ok, err := ifToken(lex, lexer.LeftParenType); err != nil {
+		return nil, err
+	} else if !ok {
+		return fieldFrom(lex, token)
+	}
+
+	call := &ast.FunctionCall{Name: strings.ToUpper(token.Raw)}
+	if ok, err := ifToken(lex, lexer.RightParenType); err != nil {
+		return nil, err
+	} else if ok {
+		return call, nil
+	}
+	for {
+		arg, err := expression(lex)
+		if err != nil {
+			return nil, err
+		}
+		call.Args = append(call.Args, arg)
+		lex.Next()
+		token := lex.Token()
+		if token.Type == lexer.RightParenType {
+			return call, nil
+		}
+		if token.Type != lexer.CommaType {
+			return nil, errorAt(token, []string{",", ")"}, "expected ) after arguments to %s, found %q", call.Name, token.Raw)
+		}
+	}
+}
+
+func isPathToken(token *lexer.Token) bool {
+	return token.Type == lexer.PeriodType ||
+		token.Type == lexer.ArrowType ||
+		token.Type == lexer.DoubleArrowType
+}
+
+// path parses the steps into JSON nested within an attribute, either .key or
+// ->'key' and ->>'key', and appends them to the attribute.
+func path(lex lexer.Lexer, attribute *ast.Attribute) error {
+	for {
+		if !lex.Next() {
+			return nil
+		}
+		step := lex.Token()
+		if !isPathToken(step) {
+			lex.UnreadToken()
+			return nil
+		}
+		if !lex.Next() {
+			return errorAt(lex.Token(), []string{"key"}, "expected a key after %s, found nothing", step.Raw)
+		}
+		token := lex.Token()
+		switch {
+		case step.Type == lexer.PeriodType && isIdentifier(token):
+			attribute.Path = append(attribute.Path, identifier(token))
+		case step.Type != lexer.PeriodType && token.Type == lexer.StringType:
+			attribute.Path = append(attribute.Path, token.Value)
+		case step.Type != lexer.PeriodType && token.Type == lexer.IntegerType:
+			attribute.Path = append(attribute.Path, token.Raw)
+		default:
+			return errorAt(token, []string{"key"}, "expected a key after %s, found %q", step.Raw, token.Raw)
+		}
+		attribute.PathJSON = step.Type == lexer.ArrowType
+	}
+}
+
+// isIdentifier returns true for names, whether quoted or not.
+func isIdentifier(token *lexer.Token) bool {
+	return token.Type == lexer.IdentifierType || token.Type == lexer.QuotedIdentifierType
+}
+
+// identifier returns the name an identifier token stands for.
+func identifier(token *lexer.Token) string {
+	if token.Type == lexer.QuotedIdentifierType {
+		return token.Value
+	}
+	return token.Raw
+}
+
+// isQuoted returns true for identifiers in double quotes.
+func isQuoted(token *lexer.Token) bool {
+	return token.Type == lexer.QuotedIdentifierType
+}
+
+// isKeyword returns true if the token is the keyword. Quoted identifiers are
+// never keywords.
+func isKeyword(token *lexer.Token, keyword string) bool {
+	return token.Type == lexer.IdentifierType && strings.ToUpper(token.Raw) == keyword
+}
+
 func ifToken(lex lexer.Lexer, t lexer.Type) (bool, error) {
 	if !lex.Next() {
 		return false, nil
@@ -265,7 +974,7 @@ func ifToken(lex lexer.Lexer, t lexer.Type) (bool, error) {
 	case t:
 		return true, nil
 	case lexer.ErrorType:
-		return false, fmt.Errorf("unable to get next token")
+		return false, errorAt(lex.Token(), []string{t.String()}, "could not tokenize input: %v", lex.Token().Raw)
 	default:
 		lex.UnreadToken()
 		return false, nil
@@ -281,7 +990,7 @@ func fieldEqualsField(lex lexer.Lexer) (*ast.EqualColumnCondition, error) {
 	if ok, err := ifToken(lex, lexer.EqualType); err != nil {
 		return nil, err
 	} else if !ok {
-		return nil, fmt.Errorf("expected = after %q", left.Name)
+		return nil, errorAt(lex.Token(), []string{"="}, "expected = after %q", left.Name)
 	}
 
 	right, err := field(lex)
@@ -301,7 +1010,7 @@ func ifKeywords(lex lexer.Lexer, keyword string, keywords ...string) (bool, erro
 	}
 	token := lex.Token()
 	if token.Type == lexer.ErrorType {
-		return false, fmt.Errorf("could not tokenize input: %v", token.Raw)
+		return false, errorAt(token, nil, "could not tokenize input: %v", token.Raw)
 	}
 	if token.Type == lexer.EOFType {
 		return false, nil
@@ -313,17 +1022,17 @@ func ifKeywords(lex lexer.Lexer, keyword string, keywords ...string) (bool, erro
 
 	for _, keyword := range keywords {
 		if !lex.Next() {
-			return false, fmt.Errorf("expected keyword %q, found nothing", keyword)
+			return false, errorAt(token, []string{keyword}, "expected keyword %q, found nothing", keyword)
 		}
 		token := lex.Token()
 		if token.Type == lexer.ErrorType {
-			return false, fmt.Errorf("could not tokenize input: %v", token.Raw)
+			return false, errorAt(token, nil, "could not tokenize input: %v", token.Raw)
 		}
 		if token.Type == lexer.EOFType {
-			return false, fmt.Errorf("expected keyword %q, found nothing", keyword)
+			return false, errorAt(token, []string{keyword}, "expected keyword %q, found nothing", keyword)
 		}
 		if token.Type != lexer.IdentifierType || strings.ToUpper(token.Raw) != keyword {
-			return false, fmt.Errorf("expected keyword %q, found nothing", keyword)
+			return false, errorAt(token, []string{keyword}, "expected keyword %q, found %q", keyword, token.Raw)
 		}
 	}
 	return true, nil
@@ -338,59 +1047,112 @@ func where(lex lexer.Lexer) (ast.Condition, error) {
 	return condition(lex)
 }
 
+// comparisons are the operators that compare a column with a value.
+var comparisons = map[lexer.Type]string{
+	lexer.LessType:         "<",
+	lexer.LessEqualType:    "<=",
+	lexer.GreaterType:      ">",
+	lexer.GreaterEqualType: ">=",
+}
+
+// condition parses a column compared with a value, as in a = 1 or a < 1, or
+// a column in a list of values, as in a IN (1, 2).
 func condition(lex lexer.Lexer) (ast.Condition, error) {
 	field, err := field(lex)
 	if err != nil {
 		return nil, err
 	}
-	result := ast.EqualCondition{LHS: field}
-	//if !lex.Next() {
-	//	return nil, fmt.Errorf("expected an attribute, found nothing")
-	//}
-	//token = lex.Token()
-	//if token.Type != lexer.IdentifierType {
-	//	return nil, fmt.Errorf("expected attribute name, found %q", token.Raw)
-	//}
-	//result.LHS = &ast.Attribute{Name: token.Raw}
 
-	if ok, err := ifToken(lex, lexer.EqualType); err != nil {
+	if ok, err := ifKeywords(lex, "IN"); err != nil {
 		return nil, err
-	} else if !ok {
-		return nil, fmt.Errorf("expected =")
+	} else if ok {
+		result := &ast.InCondition{LHS: field}
+		err := list(lex, "IN", func() error {
+			if !lex.Next() {
+				return errorAt(lex.Token(), []string{"value"}, "expected a value, found nothing")
+			}
+			v, err := value(lex.Token())
+			if err != nil {
+				return err
+			}
+			result.Values = append(result.Values, v)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		return result, nil
+	}
+
+	lex.Next()
+	token := lex.Token()
+	operator, ok := comparisons[token.Type]
+	if token.Type != lexer.EqualType && !ok {
+		return nil, errorAt(token, []string{"=", "<", "<=", ">", ">=", "IN"}, "expected =, <, <=, >, >= or IN")
 	}
-	//if !lex.Next() {
-	//	return nil, fmt.Errorf("expected =, found nothing")
-	//}
-	//token = lex.Token()
-	//if token.Type != lexer.EqualType {
-	//	return nil, fmt.Errorf("expected =, found %q", token.Raw)
-	//}
 
 	if !lex.Next() {
-		return nil, fmt.Errorf("expected an attribute, found nothing")
+		return nil, errorAt(lex.Token(), []string{"value"}, "expected an attribute, found nothing")
 	}
-	token := lex.Token()
+	rhs, err := value(lex.Token())
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		return &ast.CompareCondition{LHS: field, Operator: operator, RHS: rhs}, nil
+	}
+	return &ast.EqualCondition{LHS: field, RHS: rhs}, nil
+}
+
+// value parses a constant, or a placeholder for one.
+func value(token *lexer.Token) (*ast.Constant, error) {
+	if token.Type != lexer.ParameterType {
+		return constant(token)
+	}
+	result := &ast.Constant{Raw: token.Raw, Parameter: &ast.Parameter{}}
+	if token.Raw[0] == ':' {
+		result.Parameter.Name = token.Raw[1:]
+	} else if token.Raw != "?" {
+		n, err := strconv.Atoi(token.Raw[1:])
+		if err != nil || n < 1 {
+			return nil, errorAt(token, nil, "invalid placeholder %s", token.Raw)
+		}
+		result.Parameter.Position = n
+	}
+	return result, nil
+}
+
+func constant(token *lexer.Token) (*ast.Constant, error) {
 	switch token.Type {
 	case lexer.StringType:
-		result.RHS = &ast.Constant{
+		return &ast.Constant{
 			Type:  ast.StringType,
-			Value: token.Raw[1 : len(token.Raw)-1],
+			Value: token.Value,
 			Raw:   token.Raw,
-		}
+		}, nil
 	case lexer.IntegerType:
 		i, err := strconv.Atoi(token.Raw)
 		if err != nil {
-			return nil, fmt.Errorf("unable to convert constant %q to integer", token.Raw)
+			return nil, errorAt(token, nil, "unable to convert constant %q to integer", token.Raw)
 		}
-		result.RHS = &ast.Constant{
+		return &ast.Constant{
 			Type:  ast.IntegerType,
 			Value: i,
 			Raw:   token.Raw,
+		}, nil
+	case lexer.FloatType:
+		f, err := strconv.ParseFloat(token.Raw, 64)
+		if err != nil {
+			return nil, errorAt(token, nil, "unable to convert constant %q to float", token.Raw)
 		}
+		return &ast.Constant{
+			Type:  ast.FloatType,
+			Value: f,
+			Raw:   token.Raw,
+		}, nil
 	default:
-		return nil, fmt.Errorf("unexpected token type %s for %q", token.Type, token.Raw)
+		return nil, errorAt(token, []string{"value"}, "unexpected token type %s for %q", token.Type, token.Raw)
 	}
-	return &result, nil
 }
 
 func orderBy(lex lexer.Lexer) (*ast.OrderBy, error) {
@@ -1,7 +1,6 @@
 package parser
 
 import (
-	"fmt"
 	"strings"
 	"testing"
 
@@ -15,16 +14,28 @@ func TestParseEmptyQuery(t *testing.T) {
 
 	q, err := Parse(lexer.NewFilterWhitespace(strings.NewReader("")))
 
-	assert.Equal(`expected SELECT or PROFILE`, err.Error())
+	assert.Equal(`expected SELECT, PROFILE, COPY, CREATE TABLE, CREATE INDEX, SHOW, DESCRIBE, INSERT INTO, DELETE FROM or UPDATE`, err.Error())
 	assert.Nil(q)
 }
 
+func TestParseTrailingSemicolon(t *testing.T) {
+	assert := assert.New(t)
+
+	q, err := Parse(lexer.NewFilterWhitespace(strings.NewReader("SELECT a FROM t;\n")))
+
+	assert.Nil(err)
+	assert.Equal(&ast.SFW{
+		SelList: &ast.SelList{Attributes: []*ast.Attribute{{Name: "a"}}},
+		From:    &ast.Relation{Name: "t"},
+	}, q)
+}
+
 func TestParseString(t *testing.T) {
 	assert := assert.New(t)
 
 	q, err := Parse(lexer.NewFilterWhitespace(strings.NewReader("'sql string'")))
 
-	assert.Equal(`expected SELECT or PROFILE`, err.Error())
+	assert.Equal(`expected SELECT, PROFILE, COPY, CREATE TABLE, CREATE INDEX, SHOW, DESCRIBE, INSERT INTO, DELETE FROM or UPDATE`, err.Error())
 	assert.Nil(q)
 }
 
@@ -197,6 +208,75 @@ func TestAttribute(t *testing.T) {
 			input:    "qual.a_name al",
 			expected: &ast.Attribute{Qualifier: "qual", Name: "a_name", Alias: "al"},
 		},
+		{
+			name:     "json path attribute",
+			input:    "payload.user.id",
+			expected: &ast.Attribute{Qualifier: "payload", Name: "user", Path: []string{"id"}},
+		},
+		{
+			name:     "json arrow attribute",
+			input:    "payload->'user'->>'id' uid",
+			expected: &ast.Attribute{Name: "payload", Alias: "uid", Path: []string{"user", "id"}},
+		},
+		{
+			name:     "json arrow attribute as json",
+			input:    "payload->'tags'->0",
+			expected: &ast.Attribute{Name: "payload", Path: []string{"tags", "0"}, PathJSON: true},
+		},
+		{
+			name:     "quoted attribute",
+			input:    `"Zip Code" "from"`,
+			expected: &ast.Attribute{Name: "Zip Code", Alias: "from", Quoted: true},
+		},
+		{
+			name:     "qualified quoted attribute",
+			input:    `t."Unit-Price"`,
+			expected: &ast.Attribute{Qualifier: "t", Name: "Unit-Price", Quoted: true},
+		},
+		{
+			name:     "quoted qualifier",
+			input:    `"C".id`,
+			expected: &ast.Attribute{Qualifier: "C", QualifierQuoted: true, Name: "id"},
+		},
+		{
+			name:     "quoted qualifier and star",
+			input:    `"C".*`,
+			expected: &ast.Attribute{Qualifier: "C", QualifierQuoted: true, Name: "*"},
+		},
+		{
+			name:     "qualified star",
+			input:    "c.*, o.id",
+			expected: &ast.Attribute{Qualifier: "c", Name: "*"},
+		},
+		{
+			name:  "star exclude",
+			input: `* EXCLUDE (ssn, p."Date of Birth") FROM t`,
+			expected: &ast.Attribute{Name: "*", Exclude: []*ast.Attribute{
+				{Name: "ssn"},
+				{Qualifier: "p", Name: "Date of Birth", Quoted: true},
+			}},
+		},
+		{
+			name:  "star replace",
+			input: "c.* EXCLUDE (ssn) REPLACE (UPPER(name) AS name, lower(trim(c.city)) AS city)",
+			expected: &ast.Attribute{
+				Qualifier: "c",
+				Name:      "*",
+				Exclude:   []*ast.Attribute{{Name: "ssn"}},
+				Replace: []*ast.Replacement{
+					{
+						Expression: &ast.FunctionCall{Name: "UPPER", Args: []ast.Expression{&ast.Attribute{Name: "name"}}},
+						Name:       "name",
+					},
+					{
+						Expression: &ast.FunctionCall{Name: "LOWER", Args: []ast.Expression{
+							&ast.FunctionCall{Name: "TRIM", Args: []ast.Expression{&ast.Attribute{Qualifier: "c", Name: "city"}}},
+						}},
+						Name: "city",
+					},
+				},
+			},
+		},
 		{
 			name:     "multiple simple attribute",
 			input:    "a_name, b_name",
@@ -235,6 +315,25 @@ func TestFrom(t *testing.T) {
 			input:    "from a_name",
 			expected: &ast.Relation{Name: "a_name"},
 		},
+		{
+			name:     "file path",
+			input:    "from 'data/2024/sales.csv'",
+			expected: &ast.Relation{Path: "data/2024/sales.csv"},
+		},
+		{
+			name:  "table function",
+			input: "from read_csv('x.txt', delim='|', header=false)",
+			expected: &ast.Relation{
+				Function: &ast.TableFunction{
+					Name: "read_csv",
+					Args: []*ast.Constant{{Type: ast.StringType, Value: "x.txt", Raw: "'x.txt'"}},
+					Options: []*ast.Option{
+						{Name: "delim", Value: &ast.Constant{Type: ast.StringType, Value: "|", Raw: "'|'"}},
+						{Name: "header", Value: &ast.Constant{Type: ast.BooleanType, Value: false, Raw: "false"}},
+					},
+				},
+			},
+		},
 		{
 			name:  "basic inner join",
 			input: "from tab1 INNER JOIN tab2 ON tab1.id = tab2.id",
@@ -300,12 +399,24 @@ func TestFieldEqualsField(t *testing.T) {
 		{
 			name:  "qual1.field1",
 			input: "qual1.field1",
-			err:   fmt.Errorf(`expected = after "field1"`),
+			err: &ParseError{
+				Line:     1,
+				Column:   13,
+				Found:    &lexer.Token{Type: lexer.EOFType, Line: 1, Column: 13},
+				Expected: []string{"="},
+				Message:  `expected = after "field1"`,
+			},
 		},
 		{
 			name:  "qual1.field1 =",
 			input: "qual1.field1 =",
-			err:   fmt.Errorf(`expected field name, found ""`),
+			err: &ParseError{
+				Line:     1,
+				Column:   15,
+				Found:    &lexer.Token{Type: lexer.EOFType, Line: 1, Column: 15},
+				Expected: []string{"field"},
+				Message:  `expected field name, found ""`,
+			},
 		},
 	}
 	for _, test := range tests {
@@ -340,7 +451,13 @@ func TestField(t *testing.T) {
 		{
 			name:  "qualified field name missing name",
 			input: "qual.",
-			err:   fmt.Errorf(`expected field name, found ""`),
+			err: &ParseError{
+				Line:     1,
+				Column:   6,
+				Found:    &lexer.Token{Type: lexer.EOFType, Line: 1, Column: 6},
+				Expected: []string{"field"},
+				Message:  `expected field name, found ""`,
+			},
 		},
 	}
 	for _, test := range tests {
@@ -368,6 +485,35 @@ func TestCondition(t *testing.T) {
 				RHS: &ast.Constant{Type: ast.StringType, Value: "abc", Raw: "'abc'"},
 			},
 		},
+		{
+			name:  "a >= -2",
+			input: "a >= -2",
+			expected: &ast.CompareCondition{
+				LHS:      &ast.Attribute{Name: "a"},
+				Operator: ">=",
+				RHS:      &ast.Constant{Type: ast.IntegerType, Value: -2, Raw: "-2"},
+			},
+		},
+		{
+			name:  "t.a < ?",
+			input: "t.a < ?",
+			expected: &ast.CompareCondition{
+				LHS:      &ast.Attribute{Qualifier: "t", Name: "a"},
+				Operator: "<",
+				RHS:      &ast.Constant{Raw: "?", Parameter: &ast.Parameter{}},
+			},
+		},
+		{
+			name:  "a IN ('x', 2)",
+			input: "a IN ('x', 2)",
+			expected: &ast.InCondition{
+				LHS: &ast.Attribute{Name: "a"},
+				Values: []*ast.Constant{
+					{Type: ast.StringType, Value: "x", Raw: "'x'"},
+					{Type: ast.IntegerType, Value: 2, Raw: "2"},
+				},
+			},
+		},
 	}
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
@@ -494,6 +640,27 @@ func TestWhere(t *testing.T) {
 				RHS: &ast.Constant{Type: ast.StringType, Value: "abc", Raw: "'abc'"},
 			},
 		},
+		{
+			input: "WHERE a = 'it''s'",
+			expected: &ast.EqualCondition{
+				LHS: &ast.Attribute{Name: "a"},
+				RHS: &ast.Constant{Type: ast.StringType, Value: "it's", Raw: "'it''s'"},
+			},
+		},
+		{
+			input: "WHERE a = -1.5",
+			expected: &ast.EqualCondition{
+				LHS: &ast.Attribute{Name: "a"},
+				RHS: &ast.Constant{Type: ast.FloatType, Value: -1.5, Raw: "-1.5"},
+			},
+		},
+		{
+			input: "WHERE \"Zip Code\" = -7 -- trailing comment",
+			expected: &ast.EqualCondition{
+				LHS: &ast.Attribute{Name: "Zip Code", Quoted: true},
+				RHS: &ast.Constant{Type: ast.IntegerType, Value: -7, Raw: "-7"},
+			},
+		},
 		{
 			input: "ORDER BY table_name.field_name",
 		},
@@ -541,3 +708,308 @@ func TestParseInnerJoin(t *testing.T) {
 		})
 	}
 }
+
+func TestParseError(t *testing.T) {
+	tests := []struct {
+		name       string
+		input      string
+		message    string
+		line       int
+		column     int
+		suggestion string
+		annotated  string
+	}{
+		{
+			name:       "misspelled select",
+			input:      "SELEC a FROM t",
+			message:    "expected SELECT, PROFILE, COPY, CREATE TABLE, CREATE INDEX, SHOW, DESCRIBE, INSERT INTO, DELETE FROM or UPDATE",
+			line:       1,
+			column:     1,
+			suggestion: "SELECT",
+			annotated:  "expected SELECT, PROFILE, COPY, CREATE TABLE, CREATE INDEX, SHOW, DESCRIBE, INSERT INTO, DELETE FROM or UPDATE\nSELEC a FROM t\n^\ndid you mean SELECT?",
+		},
+		{
+			name:       "misspelled where",
+			input:      "SELECT a\nFROM t\n\tWHER a = 1",
+			message:    "extra stuff left over: WHER",
+			line:       3,
+			column:     2,
+			suggestion: "WHERE",
+			annotated:  "extra stuff left over: WHER\n\tWHER a = 1\n\t^\ndid you mean WHERE?",
+		},
+		{
+			name:      "missing equals",
+			input:     "SELECT a FROM t WHERE a 'x'",
+			message:   "expected =, <, <=, >, >= or IN",
+			line:      1,
+			column:    25,
+			annotated: "expected =, <, <=, >, >= or IN\nSELECT a FROM t WHERE a 'x'\n                        ^",
+		},
+		{
+			name:      "unterminated string",
+			input:     "SELECT a FROM t WHERE a = 'x",
+			message:   "could not tokenize input: unterminated string 'x",
+			line:      1,
+			column:    27,
+			annotated: "could not tokenize input: unterminated string 'x\nSELECT a FROM t WHERE a = 'x\n                          ^",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			_, err := Parse(lexer.NewFilterWhitespace(strings.NewReader(test.input)))
+
+			pe, ok := err.(*ParseError)
+			if !assert.True(ok, "expected a ParseError, got %v", err) {
+				return
+			}
+			assert.Equal(test.message, pe.Error())
+			assert.Equal(test.line, pe.Line)
+			assert.Equal(test.column, pe.Column)
+			assert.Equal(test.suggestion, pe.Suggestion())
+			assert.Equal(test.annotated, pe.Annotate(test.input))
+		})
+	}
+}
+
+func TestParseStarErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		err   string
+	}{
+		{"SELECT * EXCLUDE ssn FROM t", "expected ( after EXCLUDE"},
+		{"SELECT * EXCLUDE (ssn FROM t", `expected ) after EXCLUDE list, found "FROM"`},
+		{"SELECT * REPLACE (UPPER(name) name) FROM t", "expected AS after replacement expression"},
+	}
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			assert := assert.New(t)
+
+			_, err := Parse(lexer.NewFilterWhitespace(strings.NewReader(test.input)))
+
+			if assert.Error(err) {
+				assert.Equal(test.err, err.Error())
+			}
+		})
+	}
+}
+
+func TestParseCopy(t *testing.T) {
+	assert := assert.New(t)
+
+	q, err := Parse(lexer.NewFilterWhitespace(strings.NewReader(
+		"COPY (SELECT a FROM t) TO 'out.csv' WITH (format csv, header = true)")))
+
+	assert.Nil(err)
+	assert.Equal(&ast.Copy{
+		Query: &ast.SFW{
+			SelList: &ast.SelList{Attributes: []*ast.Attribute{{Name: "a"}}},
+			From:    &ast.Relation{Name: "t"},
+		},
+		Path: "out.csv",
+		Options: []*ast.Option{
+			{Name: "format", Value: &ast.Constant{Type: ast.StringType, Value: "csv", Raw: "csv"}},
+			{Name: "header", Value: &ast.Constant{Type: ast.BooleanType, Value: true, Raw: "true"}},
+		},
+	}, q)
+}
+
+func TestParseCreateTableAs(t *testing.T) {
+	assert := assert.New(t)
+
+	q, err := Parse(lexer.NewFilterWhitespace(strings.NewReader("CREATE TABLE u AS SELECT a FROM t")))
+
+	assert.Nil(err)
+	assert.Equal(&ast.CreateTableAs{
+		Table: &ast.Relation{Name: "u"},
+		Query: &ast.SFW{
+			SelList: &ast.SelList{Attributes: []*ast.Attribute{{Name: "a"}}},
+			From:    &ast.Relation{Name: "t"},
+		},
+	}, q)
+}
+
+func TestParseCreateIndex(t *testing.T) {
+	assert := assert.New(t)
+
+	q, err := Parse(lexer.NewFilterWhitespace(strings.NewReader("CREATE INDEX by_state ON cities (State)")))
+
+	assert.Nil(err)
+	assert.Equal(&ast.CreateIndex{
+		Name:   "by_state",
+		Table:  &ast.Relation{Name: "cities"},
+		Column: &ast.Attribute{Name: "State"},
+	}, q)
+}
+
+func TestParseShow(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected ast.Query
+	}{
+		{"SHOW TABLES", &ast.ShowTables{}},
+		{"show tables;", &ast.ShowTables{}},
+		{"DESCRIBE cities", &ast.Describe{Table: &ast.Relation{Name: "cities"}}},
+		{"DESCRIBE 'data/cities.csv'", &ast.Describe{Table: &ast.Relation{Path: "data/cities.csv"}}},
+		{"SHOW COLUMNS FROM \"Cities\"", &ast.Describe{Table: &ast.Relation{Name: "Cities", Quoted: true}}},
+		{"SHOW CREATE TABLE cities", &ast.ShowCreateTable{Table: &ast.Relation{Name: "cities"}}},
+	}
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			q, err := Parse(lexer.NewFilterWhitespace(strings.NewReader(test.input)))
+
+			assert.Nil(t, err)
+			assert.Equal(t, test.expected, q)
+		})
+	}
+}
+
+func TestParseInsert(t *testing.T) {
+	assert := assert.New(t)
+
+	q, err := Parse(lexer.NewFilterWhitespace(strings.NewReader("INSERT INTO u SELECT a FROM t")))
+
+	assert.Nil(err)
+	assert.Equal(&ast.Insert{
+		Table: &ast.Relation{Name: "u"},
+		Query: &ast.SFW{
+			SelList: &ast.SelList{Attributes: []*ast.Attribute{{Name: "a"}}},
+			From:    &ast.Relation{Name: "t"},
+		},
+	}, q)
+}
+
+func TestParseWriteErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		err   string
+	}{
+		{"COPY SELECT a FROM t TO 'x.csv'", "expected ( after COPY"},
+		{"COPY (SELECT a FROM t) 'x.csv'", "expected TO after COPY (...)"},
+		{"COPY (SELECT a FROM t) TO x", `expected a file name to COPY to, found "x"`},
+		{"CREATE TABLE u SELECT a FROM t", "expected AS after CREATE TABLE u"},
+		{"CREATE VIEW v AS SELECT a FROM t", "expected TABLE or INDEX after CREATE"},
+		{"CREATE INDEX i cities (State)", "expected ON after CREATE INDEX i"},
+		{"CREATE INDEX i ON cities (State, City)", "an index can only be on one column"},
+		{"SELECT a FROM t WHERE a IN 1", "expected ( after IN"},
+		{"SHOW VIEWS", "expected TABLES, COLUMNS FROM or CREATE TABLE after SHOW"},
+		{"SHOW COLUMNS cities", `expected keyword "FROM", found "cities"`},
+		{"DESCRIBE", `expected table name, found ""`},
+	}
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			assert := assert.New(t)
+
+			_, err := Parse(lexer.NewFilterWhitespace(strings.NewReader(test.input)))
+
+			if assert.Error(err) {
+				assert.Equal(test.err, err.Error())
+			}
+		})
+	}
+}
+
+func TestParseDelete(t *testing.T) {
+	assert := assert.New(t)
+
+	q, err := Parse(lexer.NewFilterWhitespace(strings.NewReader("DELETE FROM t WHERE a = 1")))
+
+	assert.Nil(err)
+	assert.Equal(&ast.Delete{
+		Table: &ast.Relation{Name: "t"},
+		Where: &ast.EqualCondition{
+			LHS: &ast.Attribute{Name: "a"},
+			RHS: &ast.Constant{Type: ast.IntegerType, Value: 1, Raw: "1"},
+		},
+	}, q)
+}
+
+func TestParseUpdate(t *testing.T) {
+	assert := assert.New(t)
+
+	q, err := Parse(lexer.NewFilterWhitespace(strings.NewReader("UPDATE t SET a = UPPER(a), b = 'x'")))
+
+	assert.Nil(err)
+	assert.Equal(&ast.Update{
+		Table: &ast.Relation{Name: "t"},
+		Set: []*ast.Assignment{
+			{
+				Column: &ast.Attribute{Name: "a"},
+				Value:  &ast.FunctionCall{Name: "UPPER", Args: []ast.Expression{&ast.Attribute{Name: "a"}}},
+			},
+			{
+				Column: &ast.Attribute{Name: "b"},
+				Value:  &ast.Constant{Type: ast.StringType, Value: "x", Raw: "'x'"},
+			},
+		},
+	}, q)
+}
+
+func TestParseModificationErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		err   string
+	}{
+		{"UPDATE t a = 1", "expected SET after UPDATE t"},
+		{"UPDATE t SET a 1", `expected = after "a"`},
+		{"UPDATE t SET a = 1 b = 2", "extra stuff left over: b"},
+		{"DELETE FROM t WHERE a = 1 b", "extra stuff left over: b"},
+	}
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			assert := assert.New(t)
+
+			_, err := Parse(lexer.NewFilterWhitespace(strings.NewReader(test.input)))
+
+			if assert.Error(err) {
+				assert.Equal(test.err, err.Error())
+			}
+		})
+	}
+}
+
+func TestParsePlaceholders(t *testing.T) {
+	assert := assert.New(t)
+
+	q, err := Parse(lexer.NewFilterWhitespace(strings.NewReader("UPDATE t SET a = UPPER(?) WHERE b = ?")))
+
+	assert.Nil(err)
+	assert.Equal(&ast.Update{
+		Table: &ast.Relation{Name: "t"},
+		Set: []*ast.Assignment{{
+			Column: &ast.Attribute{Name: "a"},
+			Value: &ast.FunctionCall{Name: "UPPER", Args: []ast.Expression{
+				&ast.Constant{Raw: "?", Parameter: &ast.Parameter{Position: 1}},
+			}},
+		}},
+		Where: &ast.EqualCondition{
+			LHS: &ast.Attribute{Name: "b"},
+			RHS: &ast.Constant{Raw: "?", Parameter: &ast.Parameter{Position: 2}},
+		},
+	}, q)
+}
+
+func TestParseNumberedPlaceholders(t *testing.T) {
+	assert := assert.New(t)
+
+	q, err := Parse(lexer.NewFilterWhitespace(strings.NewReader("SELECT a FROM t WHERE b = $2")))
+
+	assert.Nil(err)
+	assert.Equal([]*ast.Constant{{Raw: "$2", Parameter: &ast.Parameter{Position: 2}}}, ast.Parameters(q))
+
+	_, err = Parse(lexer.NewFilterWhitespace(strings.NewReader("UPDATE t SET a = ? WHERE b = $1")))
+	assert.EqualError(err, "can not mix ?, $n and :name placeholders in one query")
+}
+
+func TestParseNamedPlaceholders(t *testing.T) {
+	assert := assert.New(t)
+
+	q, err := Parse(lexer.NewFilterWhitespace(strings.NewReader("DELETE FROM t WHERE b = :state")))
+
+	assert.Nil(err)
+	assert.Equal([]*ast.Constant{{Raw: ":state", Parameter: &ast.Parameter{Name: "state"}}}, ast.Parameters(q))
+
+	_, err = Parse(lexer.NewFilterWhitespace(strings.NewReader("UPDATE t SET a = :a WHERE b = ?")))
+	assert.EqualError(err, "can not mix ?, $n and :name placeholders in one query")
+}
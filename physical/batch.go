@@ -0,0 +1,199 @@
+package physical
+
+import (
+	"io"
+
+	"github.com/jacobsimpson/mtsql/metadata"
+)
+
+// BatchSize is the most rows in a Batch.
+const BatchSize = 1024
+
+// Batch is a group of rows stored by column, so an operator can work through
+// a column in a loop rather than making a call for every row.
+type Batch struct {
+	// Vectors holds the values of each column, Vectors[c][r] is the value of
+	// column c in row r.
+	Vectors [][]string
+	// Length is the number of rows in the vectors.
+	Length int
+	// Selection lists the rows that are part of the results, in order, when
+	// some have been filtered out. nil means every row is.
+	Selection []int
+}
+
+// Size is the number of rows in the results.
+func (b *Batch) Size() int {
+	if b.Selection == nil {
+		return b.Length
+	}
+	return len(b.Selection)
+}
+
+// row is the position in the vectors of the ith row in the results.
+func (b *Batch) row(i int) int {
+	if b.Selection == nil {
+		return i
+	}
+	return b.Selection[i]
+}
+
+// BatchReader is the counterpart to RowReader for operators that work on a
+// Batch at a time, with the same lifecycle. ReadBatch returns io.EOF after
+// the last batch, and never returns an empty batch. A batch can only be used
+// until the next call to ReadBatch.
+type BatchReader interface {
+	Columns() []*metadata.Column
+	Open() error
+	ReadBatch() (*Batch, error)
+	Reset() error
+	Close()
+
+	PlanDescription() *PlanDescription
+	Children() []BatchReader
+}
+
+// batchAdapter reads the rows of a RowReader in batches. It doesn't appear
+// in the plan, since it doesn't change the rows.
+type batchAdapter struct {
+	rowReader RowReader
+	batch     Batch
+	err       error
+}
+
+// NewBatchAdapter reads the rows of a RowReader as batches, so it can be used
+// below batch operators.
+func NewBatchAdapter(rowReader RowReader) BatchReader {
+	if r, ok := rowReader.(*rowAdapter); ok {
+		return r.batchReader
+	}
+	vectors := make([][]string, len(rowReader.Columns()))
+	for i := range vectors {
+		vectors[i] = make([]string, BatchSize)
+	}
+	return &batchAdapter{rowReader: rowReader, batch: Batch{Vectors: vectors}}
+}
+
+func (a *batchAdapter) Columns() []*metadata.Column { return a.rowReader.Columns() }
+
+func (a *batchAdapter) ReadBatch() (*Batch, error) {
+	if a.err != nil {
+		return nil, a.err
+	}
+	n := 0
+	for n < BatchSize {
+		row, err := a.rowReader.Read()
+		if err == nil && row == nil {
+			err = io.EOF
+		}
+		if err != nil {
+			// The rows read before the error are still returned, and the
+			// error is returned by the next call.
+			a.err = err
+			break
+		}
+		for c, v := range row {
+			a.batch.Vectors[c][n] = v
+		}
+		n++
+	}
+	if n == 0 {
+		return nil, a.err
+	}
+	a.batch.Length = n
+	a.batch.Selection = nil
+	return &a.batch, nil
+}
+
+func (a *batchAdapter) Open() error {
+	a.err = nil
+	return a.rowReader.Open()
+}
+
+func (a *batchAdapter) Reset() error {
+	a.err = nil
+	return a.rowReader.Reset()
+}
+
+func (a *batchAdapter) Close()                            { a.rowReader.Close() }
+func (a *batchAdapter) PlanDescription() *PlanDescription { return a.rowReader.PlanDescription() }
+
+func (a *batchAdapter) Children() []BatchReader {
+	children := []BatchReader{}
+	for _, c := range a.rowReader.Children() {
+		children = append(children, NewBatchAdapter(c))
+	}
+	return children
+}
+
+// rowAdapter reads the rows of a BatchReader one at a time. It doesn't appear
+// in the plan, since it doesn't change the rows.
+type rowAdapter struct {
+	batchReader BatchReader
+	rows        [][]string
+	next        int
+}
+
+// NewRowAdapter reads the batches of a BatchReader as rows, so it can be used
+// below row operators.
+func NewRowAdapter(batchReader BatchReader) RowReader {
+	if a, ok := batchReader.(*batchAdapter); ok {
+		return a.rowReader
+	}
+	return &rowAdapter{batchReader: batchReader}
+}
+
+func (a *rowAdapter) Columns() []*metadata.Column { return a.batchReader.Columns() }
+
+func (a *rowAdapter) Read() ([]string, error) {
+	if a.next >= len(a.rows) {
+		batch, err := a.batchReader.ReadBatch()
+		if err != nil {
+			return nil, err
+		}
+		a.load(batch)
+	}
+	row := a.rows[a.next]
+	a.next++
+	return row, nil
+}
+
+// load copies the rows of a batch out of its vectors, which are reused for
+// the next batch. The rows share one allocation.
+func (a *rowAdapter) load(batch *Batch) {
+	columns := len(batch.Vectors)
+	values := make([]string, batch.Size()*columns)
+	a.rows = a.rows[:0]
+	for i := 0; i < batch.Size(); i++ {
+		r := batch.row(i)
+		row := values[i*columns : (i+1)*columns : (i+1)*columns]
+		for c, vector := range batch.Vectors {
+			row[c] = vector[r]
+		}
+		a.rows = append(a.rows, row)
+	}
+	a.next = 0
+}
+
+func (a *rowAdapter) Open() error {
+	a.rows = a.rows[:0]
+	a.next = 0
+	return a.batchReader.Open()
+}
+
+func (a *rowAdapter) Reset() error {
+	a.rows = a.rows[:0]
+	a.next = 0
+	return a.batchReader.Reset()
+}
+
+func (a *rowAdapter) Close()                            { a.batchReader.Close() }
+func (a *rowAdapter) PlanDescription() *PlanDescription { return a.batchReader.PlanDescription() }
+
+func (a *rowAdapter) Children() []RowReader {
+	children := []RowReader{}
+	for _, c := range a.batchReader.Children() {
+		children = append(children, NewRowAdapter(c))
+	}
+	return children
+}
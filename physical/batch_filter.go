@@ -0,0 +1,181 @@
+package physical
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jacobsimpson/mtsql/ast"
+	"github.com/jacobsimpson/mtsql/metadata"
+)
+
+// selector narrows the selection of a batch to the rows that match a
+// condition, appending their positions to selection.
+type selector func(b *Batch, selection []int) []int
+
+// batchFilter is a filter that works a batch at a time. Rather than copying
+// the rows that match, it passes the batch on with a selection vector that
+// lists them.
+type batchFilter struct {
+	batchReader BatchReader
+	selector    selector
+	name        string
+	describe    func() string
+	selection   []int
+	batch       Batch
+}
+
+// NewBatchFilter keeps the rows where the column is equal to the value.
+func NewBatchFilter(batchReader BatchReader, column *metadata.Column, value *ast.Constant) (BatchReader, error) {
+	n, err := filterColumn(column, batchReader.Columns())
+	if err != nil {
+		return nil, err
+	}
+	return &batchFilter{
+		batchReader: batchReader,
+		selector: func(b *Batch, selection []int) []int {
+			vector := b.Vectors[n]
+			if value.Type == ast.StringType {
+				// The common case, without the type switch for every value.
+				s := value.Value.(string)
+				for i := 0; i < b.Size(); i++ {
+					if r := b.row(i); vector[r] == s {
+						selection = append(selection, r)
+					}
+				}
+				return selection
+			}
+			for i := 0; i < b.Size(); i++ {
+				if r := b.row(i); equalsConstant(vector[r], value) {
+					selection = append(selection, r)
+				}
+			}
+			return selection
+		},
+		name: "Filter",
+		describe: func() string {
+			return fmt.Sprintf("%s = %v", column.QualifiedName(), value.Value)
+		},
+		selection: make([]int, 0, BatchSize),
+	}, nil
+}
+
+// NewBatchColumnFilter keeps the rows where two columns are equal.
+func NewBatchColumnFilter(batchReader BatchReader, left, right *metadata.Column) (BatchReader, error) {
+	l, err := findColumn(left, batchReader.Columns())
+	if err != nil {
+		return nil, err
+	}
+	r, err := findColumn(right, batchReader.Columns())
+	if err != nil {
+		return nil, err
+	}
+	return &batchFilter{
+		batchReader: batchReader,
+		selector: func(b *Batch, selection []int) []int {
+			lv, rv := b.Vectors[l], b.Vectors[r]
+			for i := 0; i < b.Size(); i++ {
+				if row := b.row(i); lv[row] == rv[row] {
+					selection = append(selection, row)
+				}
+			}
+			return selection
+		},
+		name: "ColumnFilter",
+		describe: func() string {
+			return fmt.Sprintf("%s = %s", left.QualifiedName(), right.QualifiedName())
+		},
+		selection: make([]int, 0, BatchSize),
+	}, nil
+}
+
+// NewBatchCompareFilter keeps the rows where the column compares with the
+// value by the operator, as NewCompareFilter does.
+func NewBatchCompareFilter(batchReader BatchReader, column *metadata.Column, operator string, value *ast.Constant) (BatchReader, error) {
+	n, err := filterColumn(column, batchReader.Columns())
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := comparisons[operator]; !ok {
+		return nil, fmt.Errorf("unsupported comparison %s", operator)
+	}
+	return &batchFilter{
+		batchReader: batchReader,
+		selector: func(b *Batch, selection []int) []int {
+			vector := b.Vectors[n]
+			for i := 0; i < b.Size(); i++ {
+				if r := b.row(i); compareConstant(vector[r], operator, value) {
+					selection = append(selection, r)
+				}
+			}
+			return selection
+		},
+		name: "Filter",
+		describe: func() string {
+			return fmt.Sprintf("%s %s %v", column.QualifiedName(), operator, value.Value)
+		},
+		selection: make([]int, 0, BatchSize),
+	}, nil
+}
+
+// NewBatchInFilter keeps the rows where the column is equal to one of the
+// values, as NewInFilter does.
+func NewBatchInFilter(batchReader BatchReader, column *metadata.Column, values []*ast.Constant) (BatchReader, error) {
+	n, err := filterColumn(column, batchReader.Columns())
+	if err != nil {
+		return nil, err
+	}
+	return &batchFilter{
+		batchReader: batchReader,
+		selector: func(b *Batch, selection []int) []int {
+			vector := b.Vectors[n]
+			for i := 0; i < b.Size(); i++ {
+				r := b.row(i)
+				for _, v := range values {
+					if equalsConstant(vector[r], v) {
+						selection = append(selection, r)
+						break
+					}
+				}
+			}
+			return selection
+		},
+		name: "Filter",
+		describe: func() string {
+			s := []string{}
+			for _, v := range values {
+				s = append(s, fmt.Sprintf("%v", v.Value))
+			}
+			return fmt.Sprintf("%s IN (%s)", column.QualifiedName(), strings.Join(s, ", "))
+		},
+		selection: make([]int, 0, BatchSize),
+	}, nil
+}
+
+func (t *batchFilter) Columns() []*metadata.Column {
+	return t.batchReader.Columns()
+}
+
+func (t *batchFilter) ReadBatch() (*Batch, error) {
+	for {
+		b, err := t.batchReader.ReadBatch()
+		if err != nil {
+			return nil, err
+		}
+		t.selection = t.selector(b, t.selection[:0])
+		if len(t.selection) == 0 {
+			continue
+		}
+		t.batch = Batch{Vectors: b.Vectors, Length: b.Length, Selection: t.selection}
+		return &t.batch, nil
+	}
+}
+
+func (t *batchFilter) Open() error  { return t.batchReader.Open() }
+func (t *batchFilter) Close()       { t.batchReader.Close() }
+func (t *batchFilter) Reset() error { return t.batchReader.Reset() }
+
+func (t *batchFilter) PlanDescription() *PlanDescription {
+	return &PlanDescription{Name: t.name, Description: t.describe()}
+}
+
+func (t *batchFilter) Children() []BatchReader { return []BatchReader{t.batchReader} }
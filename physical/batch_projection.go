@@ -0,0 +1,86 @@
+package physical
+
+import (
+	"github.com/jacobsimpson/mtsql/jsonfile"
+	"github.com/jacobsimpson/mtsql/logical"
+	"github.com/jacobsimpson/mtsql/metadata"
+)
+
+// batchProjection is a projection that works a batch at a time. The vectors
+// of columns passed through unchanged are shared with the input batch, so
+// only computed columns are copied.
+type batchProjection struct {
+	projection  *projection
+	batchReader BatchReader
+	computed    [][]string
+	scratch     []string
+	batch       Batch
+}
+
+// NewBatchProjection creates a projection where the columns that have an
+// expression at the same position are computed from it.
+func NewBatchProjection(batchReader BatchReader, columns []*metadata.Column, expressions []logical.Expression) (BatchReader, error) {
+	p, err := project(batchReader.Columns(), columns, expressions)
+	if err != nil {
+		return nil, err
+	}
+	computed := make([][]string, len(columns))
+	for i, c := range p.columns {
+		if p.evaluators[i] != nil || len(c.Path) > 0 {
+			computed[i] = make([]string, BatchSize)
+		}
+	}
+	return &batchProjection{
+		projection:  p,
+		batchReader: batchReader,
+		computed:    computed,
+		scratch:     make([]string, len(batchReader.Columns())),
+		batch:       Batch{Vectors: make([][]string, len(columns))},
+	}, nil
+}
+
+func (t *batchProjection) ReadBatch() (*Batch, error) {
+	b, err := t.batchReader.ReadBatch()
+	if err != nil {
+		return nil, err
+	}
+	p := t.projection
+	for i, col := range p.columnIndexes {
+		vector := t.computed[i]
+		switch {
+		case p.evaluators[i] != nil:
+			e := p.evaluators[i]
+			for n := 0; n < b.Size(); n++ {
+				r := b.row(n)
+				for c, v := range b.Vectors {
+					t.scratch[c] = v[r]
+				}
+				vector[r] = e(t.scratch)
+			}
+		case vector != nil:
+			c := p.columns[i]
+			for n := 0; n < b.Size(); n++ {
+				r := b.row(n)
+				vector[r] = jsonfile.Extract(b.Vectors[col][r], c.Path, c.PathJSON)
+			}
+		default:
+			vector = b.Vectors[col]
+		}
+		t.batch.Vectors[i] = vector
+	}
+	t.batch.Length = b.Length
+	t.batch.Selection = b.Selection
+	return &t.batch, nil
+}
+
+func (t *batchProjection) Columns() []*metadata.Column { return t.projection.Columns() }
+
+func (t *batchProjection) Open() error  { return t.batchReader.Open() }
+func (t *batchProjection) Close()       { t.batchReader.Close() }
+func (t *batchProjection) Reset() error { return t.batchReader.Reset() }
+
+func (t *batchProjection) PlanDescription() *PlanDescription {
+	return t.projection.PlanDescription()
+}
+
+func (t *batchProjection) Children() []BatchReader { return []BatchReader{t.batchReader} }
@@ -0,0 +1,231 @@
+package physical
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/jacobsimpson/mtsql/ast"
+	"github.com/jacobsimpson/mtsql/logical"
+	"github.com/jacobsimpson/mtsql/metadata"
+	"github.com/stretchr/testify/assert"
+)
+
+// numbered makes rows of people, with names from 0 up, and every third one
+// in OR.
+func numbered(n int) [][]string {
+	rows := [][]string{}
+	for i := 0; i < n; i++ {
+		state := "WA"
+		if i%3 == 0 {
+			state = "OR"
+		}
+		rows = append(rows, []string{fmt.Sprint(i), state})
+	}
+	return rows
+}
+
+func TestBatchAdapters(t *testing.T) {
+	assert := assert.New(t)
+	rows := numbered(2*BatchSize + 10)
+	batchReader := NewBatchAdapter(NewMemoryScan(people, rows))
+	assert.Nil(batchReader.Open())
+
+	b, err := batchReader.ReadBatch()
+	assert.Nil(err)
+	assert.Equal(BatchSize, b.Size())
+	assert.Equal("1", b.Vectors[0][1])
+	assert.Equal("OR", b.Vectors[1][3])
+
+	rowReader := NewRowAdapter(batchReader)
+	assert.Nil(rowReader.Open())
+	assert.Equal(rows, readAll(t, rowReader))
+}
+
+func TestBatchAdapterError(t *testing.T) {
+	assert := assert.New(t)
+	execution := NewExecution(context.Background(), Limits{MaxRowsScanned: 5})
+	rowReader := NewRowAdapter(NewBatchAdapter(newGuard(NewMemoryScan(people, numbered(10)), execution)))
+	assert.Nil(rowReader.Open())
+
+	for i := 0; i < 5; i++ {
+		row, err := rowReader.Read()
+		assert.Nil(err)
+		assert.Equal(fmt.Sprint(i), row[0])
+	}
+	_, err := rowReader.Read()
+	assert.Equal(&LimitError{Limit: "rows scanned", Max: 5}, err)
+}
+
+func TestBatchFilter(t *testing.T) {
+	assert := assert.New(t)
+	rows := numbered(BatchSize + 10)
+	filter, err := NewBatchFilter(NewBatchAdapter(NewMemoryScan(people, rows)), people[1],
+		&ast.Constant{Type: ast.StringType, Value: "OR", Raw: "'OR'"})
+	assert.Nil(err)
+	assert.Nil(filter.Open())
+
+	b, err := filter.ReadBatch()
+	assert.Nil(err)
+	assert.Equal(BatchSize, b.Length)
+	assert.Equal(342, b.Size())
+	assert.Equal([]int{0, 3, 6}, b.Selection[:3])
+
+	b, err = filter.ReadBatch()
+	assert.Nil(err)
+	assert.Equal(10, b.Length)
+	assert.Equal([]int{2, 5, 8}, b.Selection)
+
+	_, err = filter.ReadBatch()
+	assert.Equal(io.EOF, err)
+}
+
+func TestBatchFilterSkipsEmptyBatches(t *testing.T) {
+	assert := assert.New(t)
+	rows := numbered(3 * BatchSize)
+	rows[len(rows)-1][1] = "CA"
+	filter, err := NewBatchFilter(NewBatchAdapter(NewMemoryScan(people, rows)), people[1],
+		&ast.Constant{Type: ast.StringType, Value: "CA", Raw: "'CA'"})
+	assert.Nil(err)
+
+	assert.Equal([][]string{{fmt.Sprint(len(rows) - 1), "CA"}}, openAll(t, NewRowAdapter(filter)))
+}
+
+func TestBatchFilterTypes(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    *ast.Constant
+		expected [][]string
+	}{
+		{"integer", &ast.Constant{Type: ast.IntegerType, Value: 2, Raw: "2"}, [][]string{{"2", "WA"}}},
+		{"float", &ast.Constant{Type: ast.FloatType, Value: 4.0, Raw: "4.0"}, [][]string{{"4", "WA"}}},
+		{"no match", &ast.Constant{Type: ast.IntegerType, Value: 99, Raw: "99"}, [][]string{}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			filter, err := NewBatchFilter(NewBatchAdapter(NewMemoryScan(people, numbered(5))), people[0], test.value)
+
+			assert.Nil(t, err)
+			assert.Equal(t, test.expected, openAll(t, NewRowAdapter(filter)))
+		})
+	}
+}
+
+func TestBatchColumnFilter(t *testing.T) {
+	assert := assert.New(t)
+	rowReader := NewMemoryScan(people, [][]string{{"WA", "WA"}, {"bob", "OR"}, {"OR", "OR"}})
+
+	filter, err := NewBatchColumnFilter(NewBatchAdapter(rowReader), people[0], people[1])
+
+	assert.Nil(err)
+	assert.Equal([][]string{{"WA", "WA"}, {"OR", "OR"}}, openAll(t, NewRowAdapter(filter)))
+	assert.Equal(&PlanDescription{Name: "ColumnFilter", Description: "people.name = people.state"},
+		filter.PlanDescription())
+}
+
+func TestBatchCompareAndInFilters(t *testing.T) {
+	integer := func(i int) *ast.Constant { return &ast.Constant{Type: ast.IntegerType, Value: i} }
+	str := func(s string) *ast.Constant { return &ast.Constant{Type: ast.StringType, Value: s} }
+	rows := [][]string{{"1", "WA"}, {"2", "OR"}, {"x", "CA"}, {"10", "ID"}}
+
+	tests := []struct {
+		name        string
+		column      int
+		operator    string
+		values      []*ast.Constant
+		expected    [][]string
+		description string
+	}{
+		{"numbers", 0, "<", []*ast.Constant{integer(10)}, [][]string{{"1", "WA"}, {"2", "OR"}}, "people.name < 10"},
+		{"strings", 1, ">", []*ast.Constant{str("ID")}, [][]string{{"1", "WA"}, {"2", "OR"}}, "people.state > ID"},
+		{"in", 1, "IN", []*ast.Constant{str("CA"), str("WA"), integer(7)}, [][]string{{"1", "WA"}, {"x", "CA"}}, "people.state IN (CA, WA, 7)"},
+		{"in numbers", 0, "IN", []*ast.Constant{integer(10), str("x")}, [][]string{{"x", "CA"}, {"10", "ID"}}, "people.name IN (10, x)"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var filter BatchReader
+			var err error
+			if test.operator == "IN" {
+				filter, err = NewBatchInFilter(NewBatchAdapter(NewMemoryScan(people, rows)), people[test.column], test.values)
+			} else {
+				filter, err = NewBatchCompareFilter(NewBatchAdapter(NewMemoryScan(people, rows)), people[test.column], test.operator, test.values[0])
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, test.expected, openAll(t, NewRowAdapter(filter)))
+			assert.Equal(t, &PlanDescription{Name: "Filter", Description: test.description}, filter.PlanDescription())
+		})
+	}
+
+	_, err := NewBatchCompareFilter(NewBatchAdapter(NewMemoryScan(people, rows)), people[0], "<>", integer(1))
+	assert.EqualError(t, err, "unsupported comparison <>")
+}
+
+func TestBatchProjection(t *testing.T) {
+	assert := assert.New(t)
+	filter, err := NewBatchFilter(NewBatchAdapter(NewMemoryScan(people, numbered(7))), people[1],
+		&ast.Constant{Type: ast.StringType, Value: "OR", Raw: "'OR'"})
+	assert.Nil(err)
+
+	proj, err := NewBatchProjection(filter,
+		[]*metadata.Column{people[1], {Name: "name"}},
+		[]logical.Expression{nil, &logical.FunctionCall{
+			Name: "LOWER",
+			Args: []logical.Expression{&logical.ColumnValue{Column: people[1]}},
+		}})
+
+	assert.Nil(err)
+	assert.Equal([]*metadata.Column{people[1], {Name: "name"}}, proj.Columns())
+	assert.Equal([][]string{{"OR", "or"}, {"OR", "or"}, {"OR", "or"}}, openAll(t, NewRowAdapter(proj)))
+	assert.Equal("people.state, LOWER(people.state) AS name", proj.PlanDescription().Description)
+}
+
+func TestConvertBatches(t *testing.T) {
+	assert := assert.New(t)
+	relation := &metadata.Relation{
+		Name:   "cities",
+		Type:   metadata.CsvType,
+		Source: "testdata/cities.csv",
+	}
+	state := &metadata.Column{Qualifier: "cities", Name: "State"}
+	plan := logical.NewProjection(
+		logical.NewSelection(&logical.Source{Name: "cities", Relation: relation}, &logical.EqualConstant{
+			Column: state,
+			Value:  &ast.Constant{Type: ast.StringType, Value: "OR", Raw: "'OR'"},
+		}),
+		[]*metadata.Column{{Qualifier: "cities", Name: "City"}})
+
+	rowReader, err := Convert(plan, map[string]*metadata.Relation{"cities": relation})
+
+	assert.Nil(err)
+	assert.IsType(&rowAdapter{}, rowReader)
+	assert.Equal([][]string{{"Salem"}}, openAll(t, rowReader))
+	scan := rowReader.Children()[0].Children()[0]
+	assert.Equal("TableScan", scan.PlanDescription().Name)
+}
+
+func TestConvertComparisonBatches(t *testing.T) {
+	assert := assert.New(t)
+	relation := &metadata.Relation{
+		Name:   "cities",
+		Type:   metadata.CsvType,
+		Source: "testdata/cities.csv",
+	}
+	latD := &metadata.Column{Qualifier: "cities", Name: "LatD"}
+	state := &metadata.Column{Qualifier: "cities", Name: "State"}
+	for _, condition := range []logical.Condition{
+		&logical.CompareConstant{Column: latD, Operator: ">=", Value: &ast.Constant{Type: ast.IntegerType, Value: 49}},
+		&logical.InConstants{Column: state, Values: []*ast.Constant{{Type: ast.StringType, Value: "MB"}, {Type: ast.StringType, Value: "BC"}, {Type: ast.StringType, Value: "SA"}}},
+	} {
+		plan := logical.NewProjection(
+			logical.NewSelection(&logical.Source{Name: "cities", Relation: relation}, condition),
+			[]*metadata.Column{{Qualifier: "cities", Name: "City"}})
+
+		rowReader, err := Convert(plan, map[string]*metadata.Relation{"cities": relation})
+
+		assert.Nil(err)
+		assert.IsType(&rowAdapter{}, rowReader)
+		assert.Equal([][]string{{"Winnipeg"}, {"Vancouver"}, {"Regina"}}, openAll(t, rowReader))
+	}
+}
@@ -45,13 +45,18 @@ func (t *columnFilter) Read() ([]string, error) {
 		if row == nil {
 			return nil, nil
 		}
-		if row[t.leftIndex] == row[t.rightIndex] {
+		if t.matches(row) {
 			return row, nil
 		}
 	}
 }
 
-func (t *columnFilter) Close()       {}
+func (t *columnFilter) matches(row []string) bool {
+	return row[t.leftIndex] == row[t.rightIndex]
+}
+
+func (t *columnFilter) Open() error  { return t.rowReader.Open() }
+func (t *columnFilter) Close()       { t.rowReader.Close() }
 func (t *columnFilter) Reset() error { return t.rowReader.Reset() }
 
 func (t *columnFilter) PlanDescription() *PlanDescription {
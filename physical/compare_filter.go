@@ -0,0 +1,185 @@
+package physical
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/jacobsimpson/mtsql/ast"
+	"github.com/jacobsimpson/mtsql/metadata"
+)
+
+// compareFilter keeps the rows where a column is less or greater than a
+// value.
+type compareFilter struct {
+	rowReader    RowReader
+	column       *metadata.Column
+	columnNumber int
+	operator     string
+	value        *ast.Constant
+}
+
+// NewCompareFilter keeps the rows where the column compares with the value by
+// the operator, one of <, <=, > and >=. Numbers are compared as numbers, and
+// strings in byte order.
+func NewCompareFilter(rowReader RowReader, column *metadata.Column, operator string, value *ast.Constant) (RowReader, error) {
+	n, err := filterColumn(column, rowReader.Columns())
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := comparisons[operator]; !ok {
+		return nil, fmt.Errorf("unsupported comparison %s", operator)
+	}
+	return &compareFilter{
+		rowReader:    rowReader,
+		column:       column,
+		columnNumber: n,
+		operator:     operator,
+		value:        value,
+	}, nil
+}
+
+// comparisons says which results of comparing a value with a constant match
+// each operator.
+var comparisons = map[string]func(int) bool{
+	"<":  func(c int) bool { return c < 0 },
+	"<=": func(c int) bool { return c <= 0 },
+	">":  func(c int) bool { return c > 0 },
+	">=": func(c int) bool { return c >= 0 },
+}
+
+func (t *compareFilter) Columns() []*metadata.Column {
+	return t.rowReader.Columns()
+}
+
+func (t *compareFilter) Read() ([]string, error) {
+	for {
+		row, err := t.rowReader.Read()
+		if err != nil || row == nil {
+			return row, err
+		}
+		if t.matches(row) {
+			return row, nil
+		}
+	}
+}
+
+func (t *compareFilter) matches(row []string) bool {
+	return compareConstant(row[t.columnNumber], t.operator, t.value)
+}
+
+// compareConstant checks whether a value read from a file compares with a
+// constant by the operator, comparing them as the type of the constant. Values
+// that aren't of that type, and booleans, never match.
+func compareConstant(s, operator string, value *ast.Constant) bool {
+	var c int
+	switch value.Type {
+	case ast.StringType:
+		c = strings.Compare(s, value.Value.(string))
+	case ast.IntegerType, ast.FloatType:
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return false
+		}
+		c = compareFloats(f, number(value))
+	default:
+		return false
+	}
+	return comparisons[operator](c)
+}
+
+// number is the value of a numeric constant as a float.
+func number(value *ast.Constant) float64 {
+	if i, ok := value.Value.(int); ok {
+		return float64(i)
+	}
+	return value.Value.(float64)
+}
+
+func compareFloats(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func (t *compareFilter) Open() error  { return t.rowReader.Open() }
+func (t *compareFilter) Close()       { t.rowReader.Close() }
+func (t *compareFilter) Reset() error { return t.rowReader.Reset() }
+
+func (t *compareFilter) PlanDescription() *PlanDescription {
+	return &PlanDescription{
+		Name:        "Filter",
+		Description: fmt.Sprintf("%s %s %v", t.column.QualifiedName(), t.operator, t.value.Value),
+	}
+}
+
+func (t *compareFilter) Children() []RowReader { return []RowReader{t.rowReader} }
+
+// inFilter keeps the rows where a column is equal to one of a list of values.
+type inFilter struct {
+	rowReader    RowReader
+	column       *metadata.Column
+	columnNumber int
+	values       []*ast.Constant
+}
+
+// NewInFilter keeps the rows where the column is equal to one of the values,
+// compared the same way as by NewFilter.
+func NewInFilter(rowReader RowReader, column *metadata.Column, values []*ast.Constant) (RowReader, error) {
+	n, err := filterColumn(column, rowReader.Columns())
+	if err != nil {
+		return nil, err
+	}
+	return &inFilter{
+		rowReader:    rowReader,
+		column:       column,
+		columnNumber: n,
+		values:       values,
+	}, nil
+}
+
+func (t *inFilter) Columns() []*metadata.Column {
+	return t.rowReader.Columns()
+}
+
+func (t *inFilter) Read() ([]string, error) {
+	for {
+		row, err := t.rowReader.Read()
+		if err != nil || row == nil {
+			return row, err
+		}
+		if t.matches(row) {
+			return row, nil
+		}
+	}
+}
+
+func (t *inFilter) matches(row []string) bool {
+	for _, v := range t.values {
+		if equalsConstant(row[t.columnNumber], v) {
+			return true
+		}
+	}
+	return false
+}
+
+func (t *inFilter) Open() error  { return t.rowReader.Open() }
+func (t *inFilter) Close()       { t.rowReader.Close() }
+func (t *inFilter) Reset() error { return t.rowReader.Reset() }
+
+func (t *inFilter) PlanDescription() *PlanDescription {
+	values := []string{}
+	for _, v := range t.values {
+		values = append(values, fmt.Sprintf("%v", v.Value))
+	}
+	return &PlanDescription{
+		Name:        "Filter",
+		Description: fmt.Sprintf("%s IN (%s)", t.column.QualifiedName(), strings.Join(values, ", ")),
+	}
+}
+
+func (t *inFilter) Children() []RowReader { return []RowReader{t.rowReader} }
@@ -3,58 +3,414 @@ package physical
 import (
 	"fmt"
 
+	"github.com/jacobsimpson/mtsql/ast"
+	"github.com/jacobsimpson/mtsql/csvfile"
+	"github.com/jacobsimpson/mtsql/index"
 	"github.com/jacobsimpson/mtsql/logical"
 	md "github.com/jacobsimpson/mtsql/metadata"
+	"github.com/jacobsimpson/mtsql/spool"
 )
 
 func Convert(o logical.Operation, tables map[string]*md.Relation) (RowReader, error) {
+	return ConvertWith(o, tables, nil)
+}
+
+// ConvertWith builds a physical plan that stops when the Execution says to,
+// because its context is done or it has gone over its limits. A nil
+// Execution never stops the plan.
+func ConvertWith(o logical.Operation, tables map[string]*md.Relation, execution *Execution) (RowReader, error) {
+	c := &converter{tables: tables, execution: execution}
+	return c.convert(o, nil, nil)
+}
+
+type converter struct {
+	tables    map[string]*md.Relation
+	execution *Execution
+	// scan is the source for the plan of one thread of a parallel scan.
+	scan *rangeScan
+}
+
+// convert builds the physical plan for a logical operation. required lists
+// the columns the operations above need, so sources that can skip columns
+// only read those, or nil if every column is needed. predicates are the
+// conditions of a Selection directly above a source.
+func (c *converter) convert(o logical.Operation, required []*md.Column, predicates []*ScanPredicate) (RowReader, error) {
 	if o == nil {
 		return nil, fmt.Errorf("unable to covert nil value")
 	}
 
+	// Chains of selections and projections over a table are run a batch at a
+	// time, which is faster than passing rows one at a time.
+	if _, ok := o.(*logical.Source); !ok && batchable(o) && !c.usesIndex(o) {
+		if rr, err := c.parallel(o, required, predicates); rr != nil || err != nil {
+			return rr, err
+		}
+		br, err := c.batch(o, required, predicates)
+		if err != nil {
+			return nil, err
+		}
+		return NewRowAdapter(br), nil
+	}
+
 	if _, ok := o.(*logical.Difference); ok {
 	}
 
 	if _, ok := o.(*logical.Intersection); ok {
 	}
 
-	if _, ok := o.(*logical.Product); ok {
+	if p, ok := o.(*logical.Product); ok {
+		left, err := c.convert(p.LHS, required, nil)
+		if err != nil {
+			return nil, err
+		}
+		right, err := c.convert(p.RHS, required, nil)
+		if err != nil {
+			return nil, err
+		}
+		return NewNestedLoopJoin(left, right)
 	}
 
 	if _, ok := o.(*logical.Union); ok {
 	}
 
 	if s, ok := o.(*logical.Selection); ok {
-		rr, err := Convert(s.Child, tables)
+		if required != nil {
+			required = append(append([]*md.Column{}, required...), s.Requires()...)
+		}
+		var predicates []*ScanPredicate
+		if c, ok := s.Condition.(*logical.EqualConstant); ok {
+			predicates = append(predicates, &ScanPredicate{Column: c.Column, Value: c.Value})
+		}
+		rr, err := c.indexScan(s)
+		if rr == nil && err == nil {
+			rr, err = c.indexJoin(s, required)
+		}
+		if rr == nil && err == nil {
+			rr, err = c.convert(s.Child, required, predicates)
+		}
 		if err != nil {
 			return nil, err
 		}
-		return NewColumnFilter(rr, nil, nil)
+		switch c := s.Condition.(type) {
+		case *logical.EqualConstant:
+			return NewFilter(rr, c.Column, c.Value)
+		case *logical.EqualColumns:
+			return NewColumnFilter(rr, c.Left, c.Right)
+		case *logical.CompareConstant:
+			return NewCompareFilter(rr, c.Column, c.Operator, c.Value)
+		case *logical.InConstants:
+			return NewInFilter(rr, c.Column, c.Values)
+		}
+		return nil, fmt.Errorf("unsupported selection condition %s", s.Condition)
 	}
 
 	if p, ok := o.(*logical.Projection); ok {
-		rr, err := Convert(p.Child, tables)
+		rr, err := c.convert(p.Child, p.Requires(), nil)
 		if err != nil {
 			return nil, err
 		}
-		return NewProjection(rr, p.Provides())
+		return NewComputedProjection(rr, p.Provides(), p.Expressions)
+	}
+
+	if m, ok := o.(*logical.Modification); ok {
+		rr, err := c.convert(m.Child, nil, nil)
+		if err != nil {
+			return nil, err
+		}
+		if m.Delete {
+			return NewDelete(rr, m.Condition)
+		}
+		return NewUpdate(rr, m.Condition, m.Assignments)
 	}
 
 	if _, ok := o.(*logical.Distinct); ok {
 	}
 
 	if s, ok := o.(*logical.Sort); ok {
-		rr, err := Convert(s.Child, tables)
+		rr, err := c.convert(s.Child, required, nil)
 		if err != nil {
 			return nil, err
 		}
-		return NewSortScan(rr, []SortScanCriteria{})
+		return newSortScan(rr, []SortScanCriteria{}, c.execution)
 	}
 
 	if s, ok := o.(*logical.Source); ok {
-		relation := tables[s.Name]
-		return NewTableScan(relation.Name, relation.Source)
+		rr, err := c.source(s, required, predicates)
+		if err != nil {
+			return nil, err
+		}
+		return newGuard(rr, c.execution), nil
 	}
 
 	return nil, nil
 }
+
+// relation is the table a source reads. The system tables aren't in the
+// catalog, so they are only found on the source.
+func (c *converter) relation(s *logical.Source) *md.Relation {
+	if s.Relation != nil {
+		return s.Relation
+	}
+	return c.tables[s.Name]
+}
+
+// source builds the scan that reads a table.
+func (c *converter) source(s *logical.Source, required []*md.Column, predicates []*ScanPredicate) (RowReader, error) {
+	relation := c.relation(s)
+	switch relation.Type {
+	case md.JsonType, md.NdjsonType:
+		return NewJsonScan(relation.Name, relation.Source, relation.Type)
+	case md.ParquetType:
+		return NewParquetScan(relation.Name, relation.Source, requiredNames(relation, required), predicates)
+	case md.VirtualType:
+		return NewMemoryScan(relation.Columns, relation.Rows), nil
+	}
+	if csvfile.IsGlob(relation.Source) {
+		return NewGlobScan(relation.Name, relation.Source, relation.Dialect)
+	}
+	return NewDialectTableScan(relation.Name, relation.Source, relation.Dialect)
+}
+
+// findIndex returns a fresh index on the column of the table o reads, if o is
+// a CSV table that has one.
+func (c *converter) findIndex(o logical.Operation, column *md.Column) *index.Index {
+	s, ok := o.(*logical.Source)
+	if !ok || (column.Qualifier != "" && column.Qualifier != s.Name) {
+		return nil
+	}
+	relation := c.relation(s)
+	if relation.Type != md.CsvType || csvfile.IsGlob(relation.Source) || relation.Source == spool.StdinName {
+		return nil
+	}
+	indexes, err := index.List(relation.Source)
+	if err != nil {
+		return nil
+	}
+	for _, i := range indexes {
+		if i.Column == column.Name && i.Fresh() {
+			return i
+		}
+	}
+	return nil
+}
+
+// indexed returns the index a selection directly over a table can look its
+// rows up in, with the operator and values to look up.
+func (c *converter) indexed(s *logical.Selection) (*index.Index, string, []*ast.Constant) {
+	switch cond := s.Condition.(type) {
+	case *logical.EqualConstant:
+		return c.findIndex(s.Child, cond.Column), "=", []*ast.Constant{cond.Value}
+	case *logical.CompareConstant:
+		return c.findIndex(s.Child, cond.Column), cond.Operator, []*ast.Constant{cond.Value}
+	case *logical.InConstants:
+		return c.findIndex(s.Child, cond.Column), "IN", cond.Values
+	}
+	return nil, "", nil
+}
+
+// usesIndex checks whether a selection in a chain of selections and
+// projections can look its rows up in an index, which is better than reading
+// the table a batch at a time.
+func (c *converter) usesIndex(o logical.Operation) bool {
+	for {
+		if s, ok := o.(*logical.Selection); ok {
+			if idx, _, _ := c.indexed(s); idx != nil {
+				return true
+			}
+		}
+		if len(o.Children()) != 1 {
+			return false
+		}
+		o = o.Children()[0]
+	}
+}
+
+// indexScan builds a scan of the rows of a table a selection needs, looked up
+// in an index, or returns nil if there is no index to use.
+func (c *converter) indexScan(s *logical.Selection) (RowReader, error) {
+	idx, operator, values := c.indexed(s)
+	if idx == nil {
+		return nil, nil
+	}
+	relation := c.relation(s.Child.(*logical.Source))
+	rr, err := NewIndexScan(relation.Name, relation.Source, relation.Dialect, idx, operator, values)
+	if err != nil {
+		return nil, err
+	}
+	return newGuard(rr, c.execution), nil
+}
+
+// indexJoin builds a join of two tables on equal columns, where the column of
+// one of the tables is indexed, or returns nil if neither is. The rows of the
+// indexed table are looked up for each row of the other.
+func (c *converter) indexJoin(s *logical.Selection, required []*md.Column) (RowReader, error) {
+	cond, ok := s.Condition.(*logical.EqualColumns)
+	if !ok {
+		return nil, nil
+	}
+	p, ok := s.Child.(*logical.Product)
+	if !ok {
+		return nil, nil
+	}
+	for _, side := range []struct {
+		inner, outer             logical.Operation
+		innerColumn, outerColumn *md.Column
+		innerFirst               bool
+	}{
+		{p.RHS, p.LHS, cond.Right, cond.Left, false},
+		{p.RHS, p.LHS, cond.Left, cond.Right, false},
+		{p.LHS, p.RHS, cond.Left, cond.Right, true},
+		{p.LHS, p.RHS, cond.Right, cond.Left, true},
+	} {
+		idx := c.findIndex(side.inner, side.innerColumn)
+		if idx == nil {
+			continue
+		}
+		outer, err := c.convert(side.outer, required, nil)
+		if err != nil {
+			return nil, err
+		}
+		n, err := findColumn(side.outerColumn, outer.Columns())
+		if err != nil {
+			return nil, err
+		}
+		relation := c.relation(side.inner.(*logical.Source))
+		inner, err := newIndexScan(relation.Name, relation.Source, relation.Dialect, idx, "=", nil)
+		if err != nil {
+			return nil, err
+		}
+		return &indexJoin{
+			outer:      outer,
+			inner:      inner,
+			innerRows:  newGuard(inner, c.execution),
+			column:     n,
+			innerFirst: side.innerFirst,
+		}, nil
+	}
+	return nil, nil
+}
+
+// batchable checks whether an operation can be run by batch operators, which
+// is the case for selections and projections, all the way down to a source.
+func batchable(o logical.Operation) bool {
+	switch o := o.(type) {
+	case *logical.Source:
+		return true
+	case *logical.Selection:
+		switch o.Condition.(type) {
+		case *logical.EqualConstant, *logical.EqualColumns, *logical.CompareConstant, *logical.InConstants:
+			return batchable(o.Child)
+		}
+	case *logical.Projection:
+		return batchable(o.Child)
+	}
+	return false
+}
+
+// batch builds the batch operators for an operation that is batchable. The
+// arguments are the same as for convert.
+func (c *converter) batch(o logical.Operation, required []*md.Column, predicates []*ScanPredicate) (BatchReader, error) {
+	switch o := o.(type) {
+	case *logical.Selection:
+		if required != nil {
+			required = append(append([]*md.Column{}, required...), o.Requires()...)
+		}
+		var predicates []*ScanPredicate
+		if c, ok := o.Condition.(*logical.EqualConstant); ok {
+			predicates = append(predicates, &ScanPredicate{Column: c.Column, Value: c.Value})
+		}
+		br, err := c.batch(o.Child, required, predicates)
+		if err != nil {
+			return nil, err
+		}
+		switch c := o.Condition.(type) {
+		case *logical.EqualConstant:
+			return NewBatchFilter(br, c.Column, c.Value)
+		case *logical.EqualColumns:
+			return NewBatchColumnFilter(br, c.Left, c.Right)
+		case *logical.CompareConstant:
+			return NewBatchCompareFilter(br, c.Column, c.Operator, c.Value)
+		case *logical.InConstants:
+			return NewBatchInFilter(br, c.Column, c.Values)
+		}
+	case *logical.Projection:
+		br, err := c.batch(o.Child, o.Requires(), nil)
+		if err != nil {
+			return nil, err
+		}
+		return NewBatchProjection(br, o.Provides(), o.Expressions)
+	case *logical.Source:
+		if c.scan != nil {
+			return NewBatchAdapter(newGuard(c.scan, c.execution)), nil
+		}
+		rr, err := c.source(o, required, predicates)
+		if err != nil {
+			return nil, err
+		}
+		return NewBatchAdapter(newGuard(rr, c.execution)), nil
+	}
+	return nil, fmt.Errorf("unable to run %s a batch at a time", o)
+}
+
+// parallel builds a plan that scans a large CSV file in parts, with a thread
+// running the batchable operation for each part. It returns nil when the
+// operation is better run in one thread.
+func (c *converter) parallel(o logical.Operation, required []*md.Column, predicates []*ScanPredicate) (RowReader, error) {
+	threads := c.execution.threads()
+	if threads < 2 {
+		return nil, nil
+	}
+	source := o
+	for len(source.Children()) > 0 {
+		source = source.Children()[0]
+	}
+	relation := c.relation(source.(*logical.Source))
+	if !splittable(relation) {
+		return nil, nil
+	}
+
+	reader, err := csvfile.Open(relation.Source, relation.Dialect)
+	if err != nil {
+		return nil, err
+	}
+	reader.Close()
+	columns := []*md.Column{}
+	for _, name := range reader.Header() {
+		columns = append(columns, &md.Column{Qualifier: relation.Name, Name: name})
+	}
+
+	workers := []*worker{}
+	for i := 0; i < threads; i++ {
+		scan := &rangeScan{
+			tableName: relation.Name,
+			fileName:  relation.Source,
+			dialect:   relation.Dialect,
+			columns:   columns,
+		}
+		w := &converter{tables: c.tables, execution: c.execution, scan: scan}
+		br, err := w.batch(o, required, predicates)
+		if err != nil {
+			return nil, err
+		}
+		workers = append(workers, &worker{scan: scan, rows: NewRowAdapter(br)})
+	}
+	return newGather(relation, workers, c.execution), nil
+}
+
+// requiredNames lists the columns of the relation that are required, in the
+// order of the relation, or nil if all of them are.
+func requiredNames(relation *md.Relation, required []*md.Column) []string {
+	if required == nil {
+		return nil
+	}
+	names := []string{}
+	for _, c := range relation.Columns {
+		for _, r := range required {
+			if r.Name == c.Name && (r.Qualifier == "" || r.Qualifier == relation.Name) {
+				names = append(names, c.Name)
+				break
+			}
+		}
+	}
+	return names
+}
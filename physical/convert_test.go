@@ -0,0 +1,79 @@
+package physical_test
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/jacobsimpson/mtsql/lexer"
+	md "github.com/jacobsimpson/mtsql/metadata"
+	"github.com/jacobsimpson/mtsql/parser"
+	"github.com/jacobsimpson/mtsql/physical"
+	"github.com/jacobsimpson/mtsql/preprocessor"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConvertConditions(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    string
+		expected [][]string
+	}{
+		{
+			name:  "where equal to a constant",
+			query: "SELECT City FROM 'testdata/cities.csv' WHERE State = 'WA'",
+			expected: [][]string{
+				{"Yakima"}, {"Wenatchee"}, {"Walla Walla"}, {"Tacoma"}, {"Spokane"}, {"Seattle"},
+			},
+		},
+		{
+			name:     "where nothing matches",
+			query:    "SELECT City FROM 'testdata/cities.csv' WHERE State = 'ZZ'",
+			expected: [][]string{},
+		},
+		{
+			name:  "inner join on equal columns",
+			query: "SELECT City, Name FROM 'testdata/cities.csv' INNER JOIN 'testdata/states.csv' ON cities.State = states.State",
+			expected: [][]string{
+				{"Wilmington", "Delaware"},
+				{"Twin Falls", "Idaho"},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert := assert.New(t)
+			q, err := parser.Parse(lexer.NewFilterWhitespace(strings.NewReader(test.query)))
+			if !assert.Nil(err) {
+				return
+			}
+			tables := map[string]*md.Relation{}
+			o, err := preprocessor.Convert(q, tables)
+			if !assert.Nil(err) {
+				return
+			}
+			rowReader, err := physical.Convert(o, tables)
+			if !assert.Nil(err) {
+				return
+			}
+			defer rowReader.Close()
+			if !assert.Nil(rowReader.Open()) {
+				return
+			}
+
+			rows := [][]string{}
+			for {
+				row, err := rowReader.Read()
+				if err == io.EOF {
+					break
+				}
+				if !assert.Nil(err) {
+					return
+				}
+				rows = append(rows, row)
+			}
+			assert.Equal(test.expected, rows)
+		})
+	}
+}
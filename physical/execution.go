@@ -0,0 +1,185 @@
+package physical
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"github.com/jacobsimpson/mtsql/metadata"
+)
+
+// Limits bound the work a query can do. Zero is no limit.
+type Limits struct {
+	// MaxRowsScanned is the most rows the query can read from its tables,
+	// including rows that are read again, like the inner side of a join.
+	MaxRowsScanned int64
+	// MaxMemory is the most bytes the query can hold in memory at once, in
+	// operators like sorts that keep rows.
+	MaxMemory int64
+	// Threads is the most goroutines that scan a large CSV file at the same
+	// time. Zero or one scans it in the goroutine reading the results.
+	Threads int
+}
+
+// LimitError is returned when a query goes over one of its Limits.
+type LimitError struct {
+	// Limit is "rows scanned" or "memory".
+	Limit string
+	Max   int64
+}
+
+func (e *LimitError) Error() string {
+	if e.Limit == "memory" {
+		return fmt.Sprintf("query stopped after going over the memory limit of %d bytes", e.Max)
+	}
+	return fmt.Sprintf("query stopped after going over the limit of %d %s", e.Max, e.Limit)
+}
+
+// Execution is shared by the operators of a plan while it runs. It stops the
+// plan between rows once its context is done, or once it goes over its limits.
+// It is safe for the goroutines of a parallel scan to use at the same time.
+type Execution struct {
+	// scanned and memory are first, where atomic can rely on them being 64
+	// bit aligned.
+	scanned int64
+	memory  int64
+	ctx     context.Context
+	limits  Limits
+	mu      sync.Mutex
+	err     error
+}
+
+// NewExecution creates an Execution for a run of a plan.
+func NewExecution(ctx context.Context, limits Limits) *Execution {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return &Execution{ctx: ctx, limits: limits}
+}
+
+// threads is how many goroutines scan a large file.
+func (e *Execution) threads() int {
+	if e == nil || e.limits.Threads < 1 {
+		return 1
+	}
+	return e.limits.Threads
+}
+
+// Restart begins a new run of a plan with a new context, for plans that are
+// kept and run more than once.
+func (e *Execution) Restart(ctx context.Context) {
+	e.ctx = ctx
+	atomic.StoreInt64(&e.scanned, 0)
+	atomic.StoreInt64(&e.memory, 0)
+	e.mu.Lock()
+	e.err = nil
+	e.mu.Unlock()
+}
+
+// Err is the reason the plan was stopped, or nil if it wasn't. Some ways of
+// reading a plan only report errors as text, so this is how to tell whether
+// the run failed.
+func (e *Execution) Err() error {
+	if e == nil {
+		return nil
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.err
+}
+
+// stop records why the plan was stopped.
+func (e *Execution) stop(err error) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.err == nil {
+		e.err = err
+	}
+	return err
+}
+
+// count is called for each row read from a table.
+func (e *Execution) count() error {
+	scanned := atomic.AddInt64(&e.scanned, 1)
+	if e.limits.MaxRowsScanned > 0 && scanned > e.limits.MaxRowsScanned {
+		return e.stop(&LimitError{Limit: "rows scanned", Max: e.limits.MaxRowsScanned})
+	}
+	return nil
+}
+
+// Scanned records a row read from a table by a statement that reads it
+// outside of a plan, and returns an error once the statement should stop.
+func (e *Execution) Scanned() error {
+	if e == nil {
+		return nil
+	}
+	if err := e.ctx.Err(); err != nil {
+		return e.stop(err)
+	}
+	return e.count()
+}
+
+// Allocate records that bytes more memory are being held.
+func (e *Execution) Allocate(bytes int64) error {
+	if e == nil {
+		return nil
+	}
+	memory := atomic.AddInt64(&e.memory, bytes)
+	if e.limits.MaxMemory > 0 && memory > e.limits.MaxMemory {
+		return e.stop(&LimitError{Limit: "memory", Max: e.limits.MaxMemory})
+	}
+	return nil
+}
+
+// Release records that bytes of memory are no longer held.
+func (e *Execution) Release(bytes int64) {
+	if e != nil {
+		atomic.AddInt64(&e.memory, -bytes)
+	}
+}
+
+// rowSize estimates the memory a row holds.
+func rowSize(row []string) int64 {
+	size := int64(24 + 16*len(row))
+	for _, v := range row {
+		size += int64(len(v))
+	}
+	return size
+}
+
+// guard checks the Execution before each row of a table is read. It doesn't
+// appear in the plan, since it doesn't change the rows.
+type guard struct {
+	rowReader RowReader
+	execution *Execution
+}
+
+func newGuard(rowReader RowReader, execution *Execution) RowReader {
+	if execution == nil {
+		return rowReader
+	}
+	return &guard{rowReader: rowReader, execution: execution}
+}
+
+func (g *guard) Columns() []*metadata.Column { return g.rowReader.Columns() }
+
+func (g *guard) Read() ([]string, error) {
+	if err := g.execution.ctx.Err(); err != nil {
+		return nil, g.execution.stop(err)
+	}
+	row, err := g.rowReader.Read()
+	if err != nil || row == nil {
+		return row, err
+	}
+	if err := g.execution.count(); err != nil {
+		return nil, err
+	}
+	return row, nil
+}
+
+func (g *guard) Open() error                       { return g.rowReader.Open() }
+func (g *guard) Reset() error                      { return g.rowReader.Reset() }
+func (g *guard) Close()                            { g.rowReader.Close() }
+func (g *guard) PlanDescription() *PlanDescription { return g.rowReader.PlanDescription() }
+func (g *guard) Children() []RowReader             { return g.rowReader.Children() }
@@ -0,0 +1,73 @@
+package physical
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGuardMaxRowsScanned(t *testing.T) {
+	assert := assert.New(t)
+	execution := NewExecution(context.Background(), Limits{MaxRowsScanned: 2})
+	rowReader := newGuard(NewMemoryScan(people, [][]string{
+		{"ann", "WA"},
+		{"bob", "OR"},
+		{"cat", "WA"},
+	}), execution)
+	assert.Nil(rowReader.Open())
+
+	for i := 0; i < 2; i++ {
+		_, err := rowReader.Read()
+		assert.Nil(err)
+	}
+	_, err := rowReader.Read()
+
+	assert.Equal(&LimitError{Limit: "rows scanned", Max: 2}, err)
+	assert.Equal(err, execution.Err())
+	assert.Equal("query stopped after going over the limit of 2 rows scanned", err.Error())
+}
+
+func TestGuardCancelled(t *testing.T) {
+	assert := assert.New(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	execution := NewExecution(ctx, Limits{})
+	rowReader := newGuard(NewMemoryScan(people, [][]string{{"ann", "WA"}, {"bob", "OR"}}), execution)
+	assert.Nil(rowReader.Open())
+
+	_, err := rowReader.Read()
+	assert.Nil(err)
+	cancel()
+	_, err = rowReader.Read()
+
+	assert.Equal(context.Canceled, err)
+	assert.Equal(context.Canceled, execution.Err())
+
+	execution.Restart(context.Background())
+	assert.Nil(execution.Err())
+	assert.Nil(rowReader.Reset())
+	assert.Equal([][]string{{"ann", "WA"}, {"bob", "OR"}}, readAll(t, rowReader))
+}
+
+func TestSortScanMaxMemory(t *testing.T) {
+	assert := assert.New(t)
+	execution := NewExecution(context.Background(), Limits{MaxMemory: 100})
+	rows := [][]string{}
+	for i := 0; i < 10; i++ {
+		rows = append(rows, []string{"ann", "WA"})
+	}
+
+	sortScan, err := newSortScan(NewMemoryScan(people, rows), []SortScanCriteria{}, execution)
+	assert.Nil(err)
+	err = sortScan.Open()
+
+	assert.Equal(&LimitError{Limit: "memory", Max: 100}, err)
+	assert.Equal("query stopped after going over the memory limit of 100 bytes", err.Error())
+}
+
+func TestNoExecution(t *testing.T) {
+	rowReader := NewMemoryScan(people, [][]string{{"ann", "WA"}})
+
+	assert.Equal(t, rowReader, newGuard(rowReader, nil))
+	assert.Nil(t, (*Execution)(nil).Allocate(1<<40))
+}
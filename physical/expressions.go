@@ -0,0 +1,69 @@
+package physical
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/jacobsimpson/mtsql/logical"
+	"github.com/jacobsimpson/mtsql/metadata"
+)
+
+// evaluator computes the value of an expression for a row.
+type evaluator func(row []string) string
+
+// function is a scalar function that can be called in an expression.
+type function struct {
+	args int
+	call func(args []string) string
+}
+
+var functions = map[string]*function{
+	"UPPER":  {1, func(args []string) string { return strings.ToUpper(args[0]) }},
+	"LOWER":  {1, func(args []string) string { return strings.ToLower(args[0]) }},
+	"TRIM":   {1, func(args []string) string { return strings.TrimSpace(args[0]) }},
+	"LENGTH": {1, func(args []string) string { return strconv.Itoa(len([]rune(args[0]))) }},
+}
+
+// compile turns an expression into an evaluator for rows with the columns.
+func compile(e logical.Expression, columns []*metadata.Column) (evaluator, error) {
+	switch e := e.(type) {
+	case *logical.ColumnValue:
+		i, err := findColumn(e.Column, columns)
+		if err != nil {
+			return nil, err
+		}
+		return func(row []string) string { return row[i] }, nil
+	case *logical.ConstantValue:
+		if e.Value.Parameter != nil {
+			// The value of a placeholder can change between runs of the plan.
+			return func(row []string) string { return fmt.Sprintf("%v", e.Value.Value) }, nil
+		}
+		value := fmt.Sprintf("%v", e.Value.Value)
+		return func(row []string) string { return value }, nil
+	case *logical.FunctionCall:
+		f := functions[e.Name]
+		if f == nil {
+			return nil, fmt.Errorf("unknown function %s", e.Name)
+		}
+		if len(e.Args) != f.args {
+			return nil, fmt.Errorf("%s takes %d argument(s), found %d", e.Name, f.args, len(e.Args))
+		}
+		args := []evaluator{}
+		for _, a := range e.Args {
+			arg, err := compile(a, columns)
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, arg)
+		}
+		return func(row []string) string {
+			values := make([]string, len(args))
+			for i, a := range args {
+				values[i] = a(row)
+			}
+			return f.call(values)
+		}, nil
+	}
+	return nil, fmt.Errorf("unsupported expression %s", e)
+}
@@ -28,21 +28,38 @@ func (t *filter) Read() ([]string, error) {
 		if row == nil {
 			return nil, nil
 		}
-		switch t.value.Type {
-		case ast.StringType:
-			if row[t.columnNumber] == t.value.Value.(string) {
-				return row, nil
-			}
-		case ast.IntegerType:
-			i, err := strconv.Atoi(row[t.columnNumber])
-			if err == nil && i == t.value.Value.(int) {
-				return row, nil
-			}
+		if t.matches(row) {
+			return row, nil
 		}
 	}
 }
 
-func (t *filter) Close()       {}
+// matches checks whether the column of a row is equal to the value.
+func (t *filter) matches(row []string) bool {
+	return equalsConstant(row[t.columnNumber], t.value)
+}
+
+// equalsConstant checks whether a value read from a file is equal to a
+// constant, comparing them as the type of the constant.
+func equalsConstant(s string, value *ast.Constant) bool {
+	switch value.Type {
+	case ast.StringType:
+		return s == value.Value.(string)
+	case ast.IntegerType:
+		i, err := strconv.Atoi(s)
+		return err == nil && i == value.Value.(int)
+	case ast.FloatType:
+		f, err := strconv.ParseFloat(s, 64)
+		return err == nil && f == value.Value.(float64)
+	case ast.BooleanType:
+		b, err := strconv.ParseBool(s)
+		return err == nil && b == value.Value.(bool)
+	}
+	return false
+}
+
+func (t *filter) Open() error  { return t.rowReader.Open() }
+func (t *filter) Close()       { t.rowReader.Close() }
 func (t *filter) Reset() error { return t.rowReader.Reset() }
 
 func (t *filter) PlanDescription() *PlanDescription {
@@ -55,20 +72,30 @@ func (t *filter) PlanDescription() *PlanDescription {
 func (t *filter) Children() []RowReader { return []RowReader{t.rowReader} }
 
 func NewFilter(rowReader RowReader, column *metadata.Column, value *ast.Constant) (RowReader, error) {
+	n, err := filterColumn(column, rowReader.Columns())
+	if err != nil {
+		return nil, err
+	}
+	return &filter{
+		rowReader:    rowReader,
+		column:       column,
+		columnNumber: n,
+		value:        value,
+	}, nil
+}
+
+// filterColumn finds the column a filter compares. Unlike findColumn, the
+// last match is used when there are several.
+func filterColumn(column *metadata.Column, columns []*metadata.Column) (int, error) {
 	n := -1
-	for i, c := range rowReader.Columns() {
+	for i, c := range columns {
 		if (column.Qualifier == "" && column.Name == c.Name) ||
 			(column.Qualifier == c.Qualifier && column.Name == c.Name) {
 			n = i
 		}
 	}
 	if n < 0 {
-		return nil, fmt.Errorf("column %q does not exist in relation", column.QualifiedName())
+		return 0, fmt.Errorf("column %q does not exist in relation", column.QualifiedName())
 	}
-	return &filter{
-		rowReader:    rowReader,
-		column:       column,
-		columnNumber: n,
-		value:        value,
-	}, nil
+	return n, nil
 }
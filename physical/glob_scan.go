@@ -0,0 +1,128 @@
+package physical
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/jacobsimpson/mtsql/csvfile"
+	"github.com/jacobsimpson/mtsql/metadata"
+)
+
+// globScan reads every file matching a pattern, one after the other, as a
+// single relation. Each row has the name of its file appended in the
+// _filename column.
+type globScan struct {
+	tableName string
+	pattern   string
+	dialect   *metadata.CsvDialect
+	files     []string
+	current   int
+	reader    *csvfile.Reader
+	header    []string
+	columns   []*metadata.Column
+}
+
+func NewGlobScan(tableName, pattern string, dialect *metadata.CsvDialect) (RowReader, error) {
+	files, err := csvfile.Glob(pattern)
+	if err != nil {
+		return nil, err
+	}
+	gs := &globScan{
+		tableName: tableName,
+		pattern:   pattern,
+		dialect:   dialect,
+		files:     files,
+	}
+	if err := gs.open(0); err != nil {
+		return nil, err
+	}
+	defer gs.Close()
+	gs.header = gs.reader.Header()
+	for _, c := range gs.header {
+		gs.columns = append(gs.columns, &metadata.Column{
+			Qualifier: tableName,
+			Name:      c,
+		})
+	}
+	gs.columns = append(gs.columns, &metadata.Column{
+		Qualifier: tableName,
+		Name:      csvfile.FilenameColumn,
+	})
+	return gs, nil
+}
+
+func (t *globScan) open(n int) error {
+	reader, err := csvfile.Open(t.files[n], t.dialect)
+	if err != nil {
+		return err
+	}
+	if t.header != nil && !equalHeaders(t.header, reader.Header()) {
+		reader.Close()
+		return fmt.Errorf("file %q has different columns than %q", t.files[n], t.files[0])
+	}
+	t.current = n
+	t.reader = reader
+	return nil
+}
+
+func equalHeaders(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func (t *globScan) Columns() []*metadata.Column {
+	return t.columns
+}
+
+func (t *globScan) Open() error {
+	t.Close()
+	return t.open(0)
+}
+
+func (t *globScan) Read() ([]string, error) {
+	if t.reader == nil {
+		return nil, errNotOpen
+	}
+	for {
+		row, err := t.reader.Read()
+		if err == io.EOF {
+			if t.current+1 >= len(t.files) {
+				return nil, io.EOF
+			}
+			t.Close()
+			if err := t.open(t.current + 1); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		return append(row, t.files[t.current]), nil
+	}
+}
+
+func (t *globScan) Close() {
+	if t.reader != nil {
+		t.reader.Close()
+		t.reader = nil
+	}
+}
+
+func (t *globScan) Reset() error { return t.Open() }
+
+func (t *globScan) PlanDescription() *PlanDescription {
+	return &PlanDescription{
+		Name:        "GlobScan",
+		Description: fmt.Sprintf("%s, %s (%d files)", t.tableName, t.pattern, len(t.files)),
+	}
+}
+
+func (t *globScan) Children() []RowReader { return []RowReader{} }
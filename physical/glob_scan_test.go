@@ -0,0 +1,44 @@
+package physical_test
+
+import (
+	"io"
+	"testing"
+
+	"github.com/jacobsimpson/mtsql/metadata"
+	"github.com/jacobsimpson/mtsql/physical"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGlobScanReadsAllFiles(t *testing.T) {
+	assert := assert.New(t)
+
+	rowReader, err := physical.NewGlobScan("logs", "testdata/logs/*.csv", nil)
+	assert.Nil(err)
+
+	assert.Equal([]*metadata.Column{
+		{Qualifier: "logs", Name: "level"},
+		{Qualifier: "logs", Name: "message"},
+		{Qualifier: "logs", Name: "_filename"},
+	}, rowReader.Columns())
+	assert.Nil(rowReader.Open())
+
+	rows := [][]string{}
+	for {
+		row, err := rowReader.Read()
+		if err == io.EOF {
+			break
+		}
+		assert.Nil(err)
+		rows = append(rows, row)
+	}
+	assert.Equal([][]string{
+		{"INFO", "started", "testdata/logs/2024-01-01.csv"},
+		{"WARN", "slow", "testdata/logs/2024-01-02.csv"},
+		{"INFO", "stopped", "testdata/logs/2024-01-02.csv"},
+	}, rows)
+
+	assert.Nil(rowReader.Reset())
+	row, err := rowReader.Read()
+	assert.Nil(err)
+	assert.Equal([]string{"INFO", "started", "testdata/logs/2024-01-01.csv"}, row)
+}
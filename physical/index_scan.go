@@ -0,0 +1,281 @@
+package physical
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+
+	"github.com/jacobsimpson/mtsql/ast"
+	"github.com/jacobsimpson/mtsql/index"
+	"github.com/jacobsimpson/mtsql/metadata"
+)
+
+// indexScan reads the rows of a table that an index says have a value, or a
+// range of values, in a column. The rows come out in the order they are in
+// the file, the same as a table scan, but the index may return rows that
+// don't quite match, like 7.0 for 7, so it is always used under a filter.
+type indexScan struct {
+	scan     *tableScan
+	index    *index.Index
+	operator string
+	values   []*ast.Constant
+	offsets  []int64
+	next     int
+	// whole is set when the index can't answer the lookup, like a number on
+	// an index of strings, or has gone stale, and every row is read instead.
+	whole bool
+}
+
+// NewIndexScan reads the rows of a table where the indexed column compares
+// with the values by the operator: =, <, <=, >, >= or IN. The values are only
+// looked up when the scan is opened, so they can be placeholders bound after
+// the plan is built.
+func NewIndexScan(tableName, fileName string, dialect *metadata.CsvDialect, idx *index.Index, operator string, values []*ast.Constant) (RowReader, error) {
+	return newIndexScan(tableName, fileName, dialect, idx, operator, values)
+}
+
+func newIndexScan(tableName, fileName string, dialect *metadata.CsvDialect, idx *index.Index, operator string, values []*ast.Constant) (*indexScan, error) {
+	rr, err := NewDialectTableScan(tableName, fileName, dialect)
+	if err != nil {
+		return nil, err
+	}
+	return &indexScan{
+		scan:     rr.(*tableScan),
+		index:    idx,
+		operator: operator,
+		values:   values,
+	}, nil
+}
+
+func (t *indexScan) Columns() []*metadata.Column {
+	return t.scan.Columns()
+}
+
+func (t *indexScan) Open() error {
+	if err := t.scan.Open(); err != nil {
+		return err
+	}
+	t.offsets, t.next, t.whole = nil, 0, false
+	if t.values == nil {
+		// The values of an index join are looked up a row at a time.
+		return nil
+	}
+	if !t.index.Fresh() {
+		t.whole = true
+		return nil
+	}
+	offsets, ok, err := t.lookup()
+	if err != nil {
+		return err
+	}
+	t.offsets = offsets
+	t.whole = !ok
+	return nil
+}
+
+// lookup finds the offsets of the rows matching the values. It returns false
+// when the index can't be used for them.
+func (t *indexScan) lookup() ([]int64, bool, error) {
+	if t.operator != "IN" {
+		lower, upper, ok := bounds(t.index, t.operator, t.values[0])
+		if !ok {
+			return nil, false, nil
+		}
+		if lower == nil && upper == nil {
+			return []int64{}, true, nil
+		}
+		offsets, err := t.index.Range(lower, upper)
+		return offsets, err == nil, err
+	}
+
+	seen := map[int64]bool{}
+	result := []int64{}
+	for _, v := range t.values {
+		lower, upper, ok := bounds(t.index, "=", v)
+		if !ok {
+			return nil, false, nil
+		}
+		if lower == nil {
+			continue
+		}
+		offsets, err := t.index.Range(lower, upper)
+		if err != nil {
+			return nil, false, err
+		}
+		for _, o := range offsets {
+			if !seen[o] {
+				seen[o] = true
+				result = append(result, o)
+			}
+		}
+	}
+	sort.Slice(result, func(a, b int) bool { return result[a] < result[b] })
+	return result, true, nil
+}
+
+// bounds works out the range of keys in an index that compare with the value
+// by the operator. It returns false if the index can't be used, and nil
+// bounds if no key can match.
+func bounds(idx *index.Index, operator string, value *ast.Constant) (*index.Bound, *index.Bound, bool) {
+	var key string
+	switch {
+	case value.Type == ast.StringType && !idx.Numeric:
+		key = value.Value.(string)
+	case (value.Type == ast.IntegerType || value.Type == ast.FloatType) && idx.Numeric:
+		key = strconv.FormatFloat(number(value), 'g', -1, 64)
+	case value.Type == ast.StringType && operator == "=":
+		// A string is only equal to one of the numbers of a numeric index
+		// if it is a number itself.
+		f, err := strconv.ParseFloat(value.Value.(string), 64)
+		if err != nil {
+			return nil, nil, true
+		}
+		key = strconv.FormatFloat(f, 'g', -1, 64)
+	default:
+		return nil, nil, false
+	}
+	switch operator {
+	case "=":
+		return &index.Bound{Key: key, Inclusive: true}, &index.Bound{Key: key, Inclusive: true}, true
+	case "<", "<=":
+		return nil, &index.Bound{Key: key, Inclusive: operator == "<="}, true
+	case ">", ">=":
+		return &index.Bound{Key: key, Inclusive: operator == ">="}, nil, true
+	}
+	return nil, nil, false
+}
+
+// find looks up the rows with a value, for an index join.
+func (t *indexScan) find(value string) error {
+	if t.whole {
+		return t.scan.Reset()
+	}
+	t.offsets, t.next = nil, 0
+	lower, upper, _ := bounds(t.index, "=", &ast.Constant{Type: ast.StringType, Value: value})
+	if lower == nil {
+		return nil
+	}
+	offsets, err := t.index.Range(lower, upper)
+	t.offsets = offsets
+	return err
+}
+
+func (t *indexScan) Read() ([]string, error) {
+	if t.whole {
+		return t.scan.Read()
+	}
+	if t.next >= len(t.offsets) {
+		return nil, io.EOF
+	}
+	if err := t.scan.SeekRecord(t.offsets[t.next]); err != nil {
+		return nil, err
+	}
+	t.next++
+	return t.scan.Read()
+}
+
+func (t *indexScan) Reset() error {
+	t.next = 0
+	if t.whole {
+		return t.scan.Reset()
+	}
+	return nil
+}
+
+func (t *indexScan) Close() { t.scan.Close() }
+
+func (t *indexScan) PlanDescription() *PlanDescription {
+	return &PlanDescription{
+		Name:        "IndexScan",
+		Description: fmt.Sprintf("%s, %s, %s (%s)", t.scan.tableName, t.scan.fileName, t.index.Name, t.index.Column),
+	}
+}
+
+func (t *indexScan) Children() []RowReader { return []RowReader{} }
+
+// indexJoin joins each row of the outer side to the rows of a table with the
+// same value in an indexed column, looking them up in the index rather than
+// reading the whole table for each row.
+type indexJoin struct {
+	outer RowReader
+	inner *indexScan
+	// innerRows reads inner, through the guard of the execution.
+	innerRows RowReader
+	column    int
+	// innerFirst is set when the inner table is on the left of the join, and
+	// its columns come first.
+	innerFirst bool
+	outerRow   []string
+}
+
+func (t *indexJoin) Columns() []*metadata.Column {
+	if t.innerFirst {
+		return append(append([]*metadata.Column{}, t.inner.Columns()...), t.outer.Columns()...)
+	}
+	return append(append([]*metadata.Column{}, t.outer.Columns()...), t.inner.Columns()...)
+}
+
+func (t *indexJoin) Open() error {
+	t.outerRow = nil
+	if err := t.outer.Open(); err != nil {
+		return err
+	}
+	if err := t.innerRows.Open(); err != nil {
+		return err
+	}
+	// An index that has gone stale since the plan was built is not used, and
+	// the whole table is read for each row instead, as by a nested loop join.
+	t.inner.whole = !t.inner.index.Fresh()
+	return nil
+}
+
+func (t *indexJoin) Read() ([]string, error) {
+	for {
+		if t.outerRow != nil {
+			row, err := t.innerRows.Read()
+			if err == nil {
+				result := make([]string, 0, len(t.outerRow)+len(row))
+				if t.innerFirst {
+					return append(append(result, row...), t.outerRow...), nil
+				}
+				return append(append(result, t.outerRow...), row...), nil
+			}
+			if err != io.EOF {
+				return nil, err
+			}
+		}
+		row, err := t.outer.Read()
+		if err != nil {
+			return nil, err
+		}
+		t.outerRow = row
+		if err := t.inner.find(row[t.column]); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (t *indexJoin) Reset() error {
+	t.outerRow = nil
+	return t.outer.Reset()
+}
+
+func (t *indexJoin) Close() {
+	t.outer.Close()
+	t.innerRows.Close()
+}
+
+func (t *indexJoin) PlanDescription() *PlanDescription {
+	return &PlanDescription{
+		Name:        "IndexJoin",
+		Description: fmt.Sprintf("%s = %s.%s", t.outer.Columns()[t.column].QualifiedName(), t.inner.scan.tableName, t.inner.index.Column),
+	}
+}
+
+func (t *indexJoin) Children() []RowReader {
+	if t.innerFirst {
+		return []RowReader{t.innerRows, t.outer}
+	}
+	return []RowReader{t.outer, t.innerRows}
+}
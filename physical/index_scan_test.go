@@ -0,0 +1,275 @@
+package physical
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jacobsimpson/mtsql/ast"
+	"github.com/jacobsimpson/mtsql/index"
+	"github.com/jacobsimpson/mtsql/logical"
+	"github.com/jacobsimpson/mtsql/metadata"
+	"github.com/stretchr/testify/assert"
+)
+
+// indexedCities copies the cities table to a new directory, and indexes its
+// State and LatD columns.
+func indexedCities(t *testing.T) (string, map[string]*index.Index) {
+	dir, err := ioutil.TempDir("", "mtsql-index-scan-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := ioutil.ReadFile("testdata/cities.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	source := filepath.Join(dir, "cities.csv")
+	if err := ioutil.WriteFile(source, b, 0644); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(source)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	indexes := map[string]*index.Index{}
+	for _, column := range []struct {
+		name   string
+		number int
+	}{{"State", 9}, {"LatD", 0}} {
+		rowReader, err := NewTableScan("cities", source)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := rowReader.Open(); err != nil {
+			t.Fatal(err)
+		}
+		builder := index.NewBuilder(column.name)
+		for {
+			row, err := rowReader.Read()
+			if err != nil {
+				break
+			}
+			builder.Add(row[column.number], rowReader.(Seeker).Offset())
+		}
+		rowReader.Close()
+		if indexes[column.name], err = builder.Write(source, column.name, info); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return source, indexes
+}
+
+func TestIndexScan(t *testing.T) {
+	source, indexes := indexedCities(t)
+	defer os.RemoveAll(filepath.Dir(source))
+
+	str := func(s string) *ast.Constant { return &ast.Constant{Type: ast.StringType, Value: s} }
+	integer := func(i int) *ast.Constant { return &ast.Constant{Type: ast.IntegerType, Value: i} }
+	tests := []struct {
+		name     string
+		index    string
+		operator string
+		values   []*ast.Constant
+		cities   []string
+	}{
+		{"equal", "State", "=", []*ast.Constant{str("OR")}, []string{"Salem"}},
+		{"in", "State", "IN", []*ast.Constant{str("OR"), str("ID"), str("XX")}, []string{"Twin Falls", "Salem"}},
+		{"range", "LatD", ">=", []*ast.Constant{integer(48)}, []string{"Winnipeg", "Williston", "Vancouver", "Regina"}},
+		{"number as string", "LatD", "=", []*ast.Constant{str("48")}, []string{"Williston"}},
+		{"not a number", "LatD", "=", []*ast.Constant{str("x")}, []string{}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rowReader, err := NewIndexScan("cities", source, nil, indexes[test.index], test.operator, test.values)
+			assert.Nil(t, err)
+			defer rowReader.Close()
+			cities := []string{}
+			for _, row := range openAll(t, rowReader) {
+				cities = append(cities, row[8])
+			}
+			assert.Equal(t, test.cities, cities)
+		})
+	}
+}
+
+func TestIndexScanWholeTable(t *testing.T) {
+	assert := assert.New(t)
+	source, indexes := indexedCities(t)
+	defer os.RemoveAll(filepath.Dir(source))
+
+	// A number can't be looked up in an index of strings, so every row is read.
+	rowReader, err := NewIndexScan("cities", source, nil, indexes["State"], "<", []*ast.Constant{{Type: ast.IntegerType, Value: 1}})
+	assert.Nil(err)
+	assert.Len(openAll(t, rowReader), 128)
+	rowReader.Close()
+
+	// Nor can rows be looked up in an index that has gone stale.
+	later := time.Now().Add(time.Minute)
+	assert.Nil(os.Chtimes(source, later, later))
+	rowReader, err = NewIndexScan("cities", source, nil, indexes["State"], "=", []*ast.Constant{{Type: ast.StringType, Value: "OR"}})
+	assert.Nil(err)
+	assert.Len(openAll(t, rowReader), 128)
+	rowReader.Close()
+}
+
+func TestConvertUsesIndexes(t *testing.T) {
+	assert := assert.New(t)
+	source, _ := indexedCities(t)
+	defer os.RemoveAll(filepath.Dir(source))
+	states := filepath.Join(filepath.Dir(source), "states.csv")
+	assert.Nil(ioutil.WriteFile(states, []byte("Code,Name\nOR,Oregon\nID,Idaho\n"), 0644))
+
+	cities := &metadata.Relation{Name: "cities", Type: metadata.CsvType, Source: source}
+	for _, name := range []string{"LatD", "LatM", "LatS", "NS", "LonD", "LonM", "LonS", "EW", "City", "State"} {
+		cities.Columns = append(cities.Columns, &metadata.Column{Qualifier: "cities", Name: name})
+	}
+	statesRelation := &metadata.Relation{Name: "states", Type: metadata.CsvType, Source: states, Columns: []*metadata.Column{
+		{Qualifier: "states", Name: "Code"},
+		{Qualifier: "states", Name: "Name"},
+	}}
+	tables := map[string]*metadata.Relation{"cities": cities, "states": statesRelation}
+
+	plan := logical.NewProjection(
+		logical.NewSelection(
+			&logical.Product{LHS: &logical.Source{Name: "states", Relation: statesRelation}, RHS: &logical.Source{Name: "cities", Relation: cities}},
+			&logical.EqualColumns{Left: statesRelation.Columns[0], Right: cities.Columns[9]}),
+		[]*metadata.Column{statesRelation.Columns[1], cities.Columns[8]})
+	rowReader, err := Convert(plan, tables)
+	assert.Nil(err)
+	// The filter on the columns is kept over the join.
+	join := rowReader.Children()[0].Children()[0]
+	assert.Equal("IndexJoin", join.PlanDescription().Name)
+	assert.Equal("IndexScan", join.Children()[1].PlanDescription().Name)
+	assert.Equal([][]string{
+		{"Oregon", "Salem"},
+		{"Idaho", "Twin Falls"},
+	}, openAll(t, rowReader))
+	rowReader.Close()
+
+	selection := logical.NewSelection(&logical.Source{Name: "cities", Relation: cities}, &logical.CompareConstant{
+		Column:   cities.Columns[0],
+		Operator: ">",
+		Value:    &ast.Constant{Type: ast.IntegerType, Value: 48},
+	})
+	rowReader, err = Convert(selection, tables)
+	assert.Nil(err)
+	assert.Equal("Filter", rowReader.PlanDescription().Name)
+	assert.Equal("IndexScan", rowReader.Children()[0].PlanDescription().Name)
+	assert.Len(openAll(t, rowReader), 3)
+	rowReader.Close()
+}
+
+func TestCompareAndInFilters(t *testing.T) {
+	integer := func(i int) *ast.Constant { return &ast.Constant{Type: ast.IntegerType, Value: i} }
+	str := func(s string) *ast.Constant { return &ast.Constant{Type: ast.StringType, Value: s} }
+	rows := [][]string{{"1", "WA"}, {"2", "OR"}, {"x", "CA"}, {"10", "ID"}}
+
+	tests := []struct {
+		name     string
+		column   int
+		operator string
+		values   []*ast.Constant
+		expected [][]string
+	}{
+		{"numbers", 0, "<", []*ast.Constant{integer(10)}, [][]string{{"1", "WA"}, {"2", "OR"}}},
+		{"numbers inclusive", 0, ">=", []*ast.Constant{integer(2)}, [][]string{{"2", "OR"}, {"10", "ID"}}},
+		{"strings", 1, ">", []*ast.Constant{str("ID")}, [][]string{{"1", "WA"}, {"2", "OR"}}},
+		{"in", 1, "IN", []*ast.Constant{str("CA"), str("WA"), integer(7)}, [][]string{{"1", "WA"}, {"x", "CA"}}},
+		{"in numbers", 0, "IN", []*ast.Constant{integer(10), str("x")}, [][]string{{"x", "CA"}, {"10", "ID"}}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var filter RowReader
+			var err error
+			if test.operator == "IN" {
+				filter, err = NewInFilter(NewMemoryScan(people, rows), people[test.column], test.values)
+			} else {
+				filter, err = NewCompareFilter(NewMemoryScan(people, rows), people[test.column], test.operator, test.values[0])
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, test.expected, openAll(t, filter))
+		})
+	}
+
+	_, err := NewCompareFilter(NewMemoryScan(people, rows), people[0], "<>", integer(1))
+	assert.EqualError(t, err, "unsupported comparison <>")
+}
+
+func TestIndexScanWithNaN(t *testing.T) {
+	assert := assert.New(t)
+	dir, err := ioutil.TempDir("", "mtsql-index-scan-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// NaN parses as a number, but the index must still find the same rows as
+	// a scan of the whole table.
+	var csv strings.Builder
+	csv.WriteString("v,name\n")
+	for i := 0; i < 3000; i++ {
+		v := strconv.Itoa(i)
+		if i%11 == 0 {
+			v = "NaN"
+		}
+		fmt.Fprintf(&csv, "%s,row%d\n", v, i)
+	}
+	source := filepath.Join(dir, "values.csv")
+	if err := ioutil.WriteFile(source, []byte(csv.String()), 0644); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(source)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rowReader, err := NewTableScan("values", source)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := rowReader.Open(); err != nil {
+		t.Fatal(err)
+	}
+	builder := index.NewBuilder("v")
+	for {
+		row, err := rowReader.Read()
+		if err != nil {
+			break
+		}
+		builder.Add(row[0], rowReader.(Seeker).Offset())
+	}
+	rowReader.Close()
+	idx, err := builder.Write(source, "v", info)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.False(idx.Numeric)
+
+	integer := func(i int) *ast.Constant { return &ast.Constant{Type: ast.IntegerType, Value: i} }
+	column := &metadata.Column{Qualifier: "values", Name: "v"}
+	for _, test := range []struct {
+		operator string
+		value    *ast.Constant
+	}{{">", integer(2500)}, {"<", integer(300)}, {">=", integer(1000)}, {"<=", integer(42)}} {
+		t.Run(test.operator, func(t *testing.T) {
+			scan, err := NewTableScan("values", source)
+			assert.Nil(err)
+			filter, err := NewCompareFilter(scan, column, test.operator, test.value)
+			assert.Nil(err)
+			expected := openAll(t, filter)
+			filter.Close()
+
+			indexed, err := NewIndexScan("values", source, nil, idx, test.operator, []*ast.Constant{test.value})
+			assert.Nil(err)
+			filter, err = NewCompareFilter(indexed, column, test.operator, test.value)
+			assert.Nil(err)
+			assert.Equal(expected, openAll(t, filter))
+			filter.Close()
+		})
+	}
+}
@@ -0,0 +1,77 @@
+package physical
+
+import (
+	"fmt"
+
+	"github.com/jacobsimpson/mtsql/jsonfile"
+	"github.com/jacobsimpson/mtsql/metadata"
+)
+
+type jsonScan struct {
+	reader       *jsonfile.Reader
+	tableName    string
+	fileName     string
+	relationType metadata.RelationType
+	columns      []*metadata.Column
+}
+
+// NewJsonScan reads a JSON array of objects, or a newline delimited JSON
+// file, with one column for each top level key.
+func NewJsonScan(tableName, fileName string, relationType metadata.RelationType) (RowReader, error) {
+	reader, err := jsonfile.Open(fileName, relationType)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	js := &jsonScan{
+		tableName:    tableName,
+		fileName:     fileName,
+		relationType: relationType,
+	}
+	for _, c := range reader.Header() {
+		js.columns = append(js.columns, &metadata.Column{
+			Qualifier: tableName,
+			Name:      c,
+		})
+	}
+	return js, nil
+}
+
+func (t *jsonScan) Columns() []*metadata.Column {
+	return t.columns
+}
+
+func (t *jsonScan) Open() error {
+	t.Close()
+	reader, err := jsonfile.Open(t.fileName, t.relationType)
+	if err != nil {
+		return err
+	}
+	t.reader = reader
+	return nil
+}
+
+func (t *jsonScan) Read() ([]string, error) {
+	if t.reader == nil {
+		return nil, errNotOpen
+	}
+	return t.reader.Read()
+}
+
+func (t *jsonScan) Close() {
+	if t.reader != nil {
+		t.reader.Close()
+		t.reader = nil
+	}
+}
+
+func (t *jsonScan) Reset() error { return t.Open() }
+
+func (t *jsonScan) PlanDescription() *PlanDescription {
+	return &PlanDescription{
+		Name:        "JsonScan",
+		Description: fmt.Sprintf("%s, %s", t.tableName, t.fileName),
+	}
+}
+
+func (t *jsonScan) Children() []RowReader { return []RowReader{} }
@@ -23,6 +23,11 @@ func (m *memoryScan) Columns() []*metadata.Column {
 	return m.columns
 }
 
+func (m *memoryScan) Open() error {
+	m.next = 0
+	return nil
+}
+
 func (m *memoryScan) Read() ([]string, error) {
 	if m.next >= len(m.rows) {
 		return nil, io.EOF
@@ -40,7 +45,7 @@ func (m *memoryScan) Reset() error {
 }
 
 func (m *memoryScan) PlanDescription() *PlanDescription {
-	return nil
+	return &PlanDescription{Name: "MemoryScan"}
 }
 
 func (m *memoryScan) Children() []RowReader {
@@ -0,0 +1,169 @@
+package physical
+
+import (
+	"fmt"
+
+	"github.com/jacobsimpson/mtsql/logical"
+	"github.com/jacobsimpson/mtsql/metadata"
+)
+
+// Modification is a RowReader that deletes or updates the rows of its child
+// matching a condition, and counts how many it changed.
+type Modification interface {
+	RowReader
+	// Affected is the number of rows deleted or updated so far.
+	Affected() int
+}
+
+type assignment struct {
+	index int
+	value evaluator
+}
+
+type modification struct {
+	rowReader   RowReader
+	condition   logical.Condition
+	matches     func(row []string) bool
+	delete      bool
+	assignments []*assignment
+	affected    int
+}
+
+// NewDelete creates a Modification that passes on the rows that don't match
+// the condition. A nil condition deletes every row.
+func NewDelete(rowReader RowReader, condition logical.Condition) (Modification, error) {
+	matches, err := predicate(condition, rowReader.Columns())
+	if err != nil {
+		return nil, err
+	}
+	return &modification{
+		rowReader: rowReader,
+		condition: condition,
+		matches:   matches,
+		delete:    true,
+	}, nil
+}
+
+// NewUpdate creates a Modification that sets the assigned columns in the rows
+// that match the condition. A nil condition updates every row.
+func NewUpdate(rowReader RowReader, condition logical.Condition, assignments []*logical.Assignment) (Modification, error) {
+	matches, err := predicate(condition, rowReader.Columns())
+	if err != nil {
+		return nil, err
+	}
+	result := &modification{
+		rowReader: rowReader,
+		condition: condition,
+		matches:   matches,
+	}
+	for _, a := range assignments {
+		index, err := findColumn(a.Column, rowReader.Columns())
+		if err != nil {
+			return nil, err
+		}
+		value, err := compile(a.Value, rowReader.Columns())
+		if err != nil {
+			return nil, err
+		}
+		result.assignments = append(result.assignments, &assignment{index: index, value: value})
+	}
+	return result, nil
+}
+
+// predicate builds the test for whether a row matches a condition, using
+// the same comparisons as the filters.
+func predicate(condition logical.Condition, columns []*metadata.Column) (func(row []string) bool, error) {
+	switch c := condition.(type) {
+	case nil:
+		return func(row []string) bool { return true }, nil
+	case *logical.EqualConstant:
+		n, err := findColumn(c.Column, columns)
+		if err != nil {
+			return nil, err
+		}
+		f := &filter{column: c.Column, columnNumber: n, value: c.Value}
+		return f.matches, nil
+	case *logical.CompareConstant:
+		n, err := findColumn(c.Column, columns)
+		if err != nil {
+			return nil, err
+		}
+		f := &compareFilter{column: c.Column, columnNumber: n, operator: c.Operator, value: c.Value}
+		return f.matches, nil
+	case *logical.InConstants:
+		n, err := findColumn(c.Column, columns)
+		if err != nil {
+			return nil, err
+		}
+		f := &inFilter{column: c.Column, columnNumber: n, values: c.Values}
+		return f.matches, nil
+	case *logical.EqualColumns:
+		left, err := findColumn(c.Left, columns)
+		if err != nil {
+			return nil, err
+		}
+		right, err := findColumn(c.Right, columns)
+		if err != nil {
+			return nil, err
+		}
+		f := &columnFilter{left: c.Left, leftIndex: left, right: c.Right, rightIndex: right}
+		return f.matches, nil
+	}
+	return nil, fmt.Errorf("unsupported condition %s", condition)
+}
+
+func (t *modification) Columns() []*metadata.Column {
+	return t.rowReader.Columns()
+}
+
+func (t *modification) Read() ([]string, error) {
+	for {
+		row, err := t.rowReader.Read()
+		if err != nil {
+			return nil, err
+		}
+		if row == nil {
+			return nil, nil
+		}
+		if !t.matches(row) {
+			return row, nil
+		}
+		t.affected++
+		if t.delete {
+			continue
+		}
+		updated := append([]string{}, row...)
+		for _, a := range t.assignments {
+			updated[a.index] = a.value(row)
+		}
+		return updated, nil
+	}
+}
+
+func (t *modification) Affected() int { return t.affected }
+
+func (t *modification) Open() error {
+	t.affected = 0
+	return t.rowReader.Open()
+}
+
+func (t *modification) Close() { t.rowReader.Close() }
+
+func (t *modification) Reset() error {
+	t.affected = 0
+	return t.rowReader.Reset()
+}
+
+func (t *modification) PlanDescription() *PlanDescription {
+	name := "Update"
+	if t.delete {
+		name = "Delete"
+	}
+	description := "all rows"
+	if t.condition != nil {
+		description = t.condition.String()
+	}
+	return &PlanDescription{Name: name, Description: description}
+}
+
+func (t *modification) Children() []RowReader { return []RowReader{t.rowReader} }
@@ -0,0 +1,102 @@
+package physical
+
+import (
+	"io"
+	"testing"
+
+	"github.com/jacobsimpson/mtsql/ast"
+	"github.com/jacobsimpson/mtsql/logical"
+	"github.com/jacobsimpson/mtsql/metadata"
+	"github.com/stretchr/testify/assert"
+)
+
+var people = []*metadata.Column{
+	{Qualifier: "people", Name: "name"},
+	{Qualifier: "people", Name: "state"},
+}
+
+// openAll opens a RowReader, and reads every row.
+func openAll(t *testing.T, rowReader RowReader) [][]string {
+	if err := rowReader.Open(); err != nil {
+		t.Fatal(err)
+	}
+	return readAll(t, rowReader)
+}
+
+func readAll(t *testing.T, rowReader RowReader) [][]string {
+	rows := [][]string{}
+	for {
+		row, err := rowReader.Read()
+		if err == io.EOF {
+			return rows
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		rows = append(rows, row)
+	}
+}
+
+func TestDelete(t *testing.T) {
+	assert := assert.New(t)
+	rowReader := NewMemoryScan(people, [][]string{
+		{"ann", "WA"},
+		{"bob", "OR"},
+		{"cat", "WA"},
+	})
+
+	d, err := NewDelete(rowReader, &logical.EqualConstant{
+		Column: people[1],
+		Value:  &ast.Constant{Type: ast.StringType, Value: "WA", Raw: "'WA'"},
+	})
+
+	assert.Nil(err)
+	assert.Equal([][]string{{"bob", "OR"}}, openAll(t, d))
+	assert.Equal(2, d.Affected())
+}
+
+func TestDeleteEveryRow(t *testing.T) {
+	assert := assert.New(t)
+	rowReader := NewMemoryScan(people, [][]string{{"ann", "WA"}, {"bob", "OR"}})
+
+	d, err := NewDelete(rowReader, nil)
+
+	assert.Nil(err)
+	assert.Equal([][]string{}, openAll(t, d))
+	assert.Equal(2, d.Affected())
+}
+
+func TestUpdate(t *testing.T) {
+	assert := assert.New(t)
+	rows := [][]string{
+		{"ann", "WA"},
+		{"bob", "OR"},
+	}
+	rowReader := NewMemoryScan(people, rows)
+
+	u, err := NewUpdate(rowReader,
+		&logical.EqualConstant{
+			Column: people[1],
+			Value:  &ast.Constant{Type: ast.StringType, Value: "WA", Raw: "'WA'"},
+		},
+		[]*logical.Assignment{
+			{Column: people[0], Value: &logical.FunctionCall{Name: "UPPER", Args: []logical.Expression{&logical.ColumnValue{Column: people[0]}}}},
+			{Column: people[1], Value: &logical.ConstantValue{Value: &ast.Constant{Type: ast.StringType, Value: "ID", Raw: "'ID'"}}},
+		})
+
+	assert.Nil(err)
+	assert.Equal([][]string{{"ANN", "ID"}, {"bob", "OR"}}, openAll(t, u))
+	assert.Equal(1, u.Affected())
+	assert.Equal([]string{"ann", "WA"}, rows[0])
+}
+
+func TestUpdateUnknownColumn(t *testing.T) {
+	assert := assert.New(t)
+	rowReader := NewMemoryScan(people, [][]string{})
+
+	_, err := NewUpdate(rowReader, nil, []*logical.Assignment{
+		{Column: &metadata.Column{Name: "age"}, Value: &logical.ConstantValue{Value: &ast.Constant{Type: ast.IntegerType, Value: 1, Raw: "1"}}},
+	})
+
+	assert.EqualError(err, `column "age" does not exist in relation`)
+}
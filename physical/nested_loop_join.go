@@ -50,8 +50,21 @@ func (t *nestedLoopJoin) Read() ([]string, error) {
 	}
 }
 
-func (t *nestedLoopJoin) Close() {}
+func (t *nestedLoopJoin) Open() error {
+	t.leftRow = nil
+	if err := t.left.Open(); err != nil {
+		return err
+	}
+	return t.right.Open()
+}
+
+func (t *nestedLoopJoin) Close() {
+	t.left.Close()
+	t.right.Close()
+}
+
 func (t *nestedLoopJoin) Reset() error {
+	t.leftRow = nil
 	if err := t.left.Reset(); err != nil {
 		return err
 	}
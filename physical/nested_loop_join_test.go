@@ -34,6 +34,7 @@ func TestNestedLoopJoin(t *testing.T) {
 	rr, err := NewNestedLoopJoin(left, right)
 	assert.Nil(err)
 	assert.NotNil(rr)
+	assert.Nil(rr.Open())
 
 	row, err := rr.Read()
 	assert.Nil(err)
@@ -0,0 +1,307 @@
+package physical
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/jacobsimpson/mtsql/csvfile"
+	"github.com/jacobsimpson/mtsql/decompress"
+	"github.com/jacobsimpson/mtsql/metadata"
+	"github.com/jacobsimpson/mtsql/spool"
+)
+
+// A file is split into about four parts for each thread, so threads that
+// finish early have more to do, but the parts are kept between these sizes.
+// Files smaller than two parts are scanned without threads.
+var (
+	minPartSize int64 = 1 << 20
+	maxPartSize int64 = 16 << 20
+)
+
+// splittable checks whether the relation is a file that can be scanned in
+// parts, which must be an uncompressed CSV file, large enough to be worth it.
+// Compression is found from the magic bytes as well as the extension, since a
+// compressed file can be named .csv.
+func splittable(relation *metadata.Relation) bool {
+	if relation.Type != metadata.CsvType ||
+		csvfile.IsGlob(relation.Source) ||
+		relation.Source == spool.StdinName {
+		return false
+	}
+	info, err := os.Stat(relation.Source)
+	if err != nil || !info.Mode().IsRegular() || info.Size() < 2*minPartSize {
+		return false
+	}
+	f, err := decompress.OpenPlain(relation.Source)
+	if err != nil || f == nil {
+		return false
+	}
+	f.Close()
+	return true
+}
+
+// rangeScan reads the records between two offsets of a CSV file. The offsets
+// are set before each Reset.
+type rangeScan struct {
+	reader    *csvfile.Reader
+	tableName string
+	fileName  string
+	dialect   *metadata.CsvDialect
+	columns   []*metadata.Column
+	start     int64
+	end       int64
+}
+
+func (t *rangeScan) Columns() []*metadata.Column { return t.columns }
+
+func (t *rangeScan) Read() ([]string, error) {
+	if t.reader == nil {
+		return nil, errNotOpen
+	}
+	return t.reader.Read()
+}
+
+func (t *rangeScan) Close() {
+	if t.reader != nil {
+		t.reader.Close()
+		t.reader = nil
+	}
+}
+
+func (t *rangeScan) Reset() error { return t.Open() }
+
+func (t *rangeScan) Open() error {
+	t.Close()
+	reader, err := csvfile.OpenRange(t.fileName, t.dialect, t.start, t.end)
+	if err != nil {
+		return err
+	}
+	t.reader = reader
+	return nil
+}
+
+func (t *rangeScan) PlanDescription() *PlanDescription {
+	return &PlanDescription{
+		Name:        "TableScan",
+		Description: fmt.Sprintf("%s, %s, in parts", t.tableName, t.fileName),
+	}
+}
+
+func (t *rangeScan) Children() []RowReader { return []RowReader{} }
+
+// worker is the plan that one thread runs over parts of a file.
+type worker struct {
+	scan *rangeScan
+	rows RowReader
+}
+
+// run reads every row from a part of the file. The rows are held until the
+// gather is done with the part, so they count against the memory limit.
+func (w *worker) run(start, end int64, execution *Execution) *part {
+	w.scan.start, w.scan.end = start, end
+	defer w.rows.Close()
+	if err := w.rows.Open(); err != nil {
+		return &part{err: err}
+	}
+	p := &part{rows: [][]string{}}
+	for {
+		row, err := w.rows.Read()
+		if err == nil && row == nil {
+			err = io.EOF
+		}
+		if err == io.EOF {
+			return p
+		}
+		if err != nil {
+			p.err = err
+			return p
+		}
+		size := rowSize(row)
+		p.size += size
+		if err := execution.Allocate(size); err != nil {
+			p.err = err
+			return p
+		}
+		p.rows = append(p.rows, row)
+	}
+}
+
+// part is the rows from a part of the file, and the error that stopped them
+// from being read to the end, if there was one. size is the memory allocated
+// for the rows.
+type part struct {
+	rows [][]string
+	size int64
+	err  error
+}
+
+// gather runs the same plan over the parts of a file in several threads, and
+// returns the rows in the order they are in the file. Threads can only get
+// so far ahead of the reader, which limits the rows held in memory.
+type gather struct {
+	fileName  string
+	dialect   *metadata.CsvDialect
+	workers   []*worker
+	execution *Execution
+
+	results []chan *part
+	done    chan struct{}
+	wg      sync.WaitGroup
+	window  chan struct{}
+	next    int
+	rows    [][]string
+	row     int
+	// held is the memory allocated for the rows being read.
+	held    int64
+	pending error
+	err     error
+}
+
+func newGather(relation *metadata.Relation, workers []*worker, execution *Execution) RowReader {
+	return &gather{
+		fileName:  relation.Source,
+		dialect:   relation.Dialect,
+		workers:   workers,
+		execution: execution,
+	}
+}
+
+func (g *gather) Columns() []*metadata.Column { return g.workers[0].rows.Columns() }
+
+// Open starts the threads reading the parts of the file.
+func (g *gather) Open() error {
+	g.Close()
+	return g.start()
+}
+
+func (g *gather) Read() ([]string, error) {
+	if g.err != nil {
+		return nil, g.err
+	}
+	if g.done == nil {
+		return nil, errNotOpen
+	}
+	for g.row >= len(g.rows) {
+		if g.pending != nil {
+			g.err = g.pending
+			g.stop()
+			return nil, g.err
+		}
+		if g.next >= len(g.results) {
+			return nil, io.EOF
+		}
+		p := <-g.results[g.next]
+		<-g.window
+		g.next++
+		g.execution.Release(g.held)
+		g.rows, g.row, g.held, g.pending = p.rows, 0, p.size, p.err
+	}
+	row := g.rows[g.row]
+	g.row++
+	return row, nil
+}
+
+// start splits the file into parts, and starts the threads reading them.
+func (g *gather) start() error {
+	info, err := os.Stat(g.fileName)
+	if err != nil {
+		return err
+	}
+	size := info.Size() / int64(4*len(g.workers))
+	if size < minPartSize {
+		size = minPartSize
+	}
+	if size > maxPartSize {
+		size = maxPartSize
+	}
+	offsets, err := csvfile.Split(g.fileName, g.dialect, size)
+	if err != nil {
+		return err
+	}
+
+	parts := len(offsets) - 1
+	g.results = make([]chan *part, parts)
+	for i := range g.results {
+		g.results[i] = make(chan *part, 1)
+	}
+	g.done = make(chan struct{})
+	g.window = make(chan struct{}, 2*len(g.workers))
+	jobs := make(chan int)
+
+	g.wg.Add(1)
+	go func(done chan struct{}) {
+		defer g.wg.Done()
+		defer close(jobs)
+		for i := 0; i < parts; i++ {
+			select {
+			case g.window <- struct{}{}:
+			case <-done:
+				return
+			}
+			select {
+			case jobs <- i:
+			case <-done:
+				return
+			}
+		}
+	}(g.done)
+
+	for _, w := range g.workers {
+		g.wg.Add(1)
+		go func(w *worker, done chan struct{}) {
+			defer g.wg.Done()
+			for i := range jobs {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				g.results[i] <- w.run(offsets[i], offsets[i+1], g.execution)
+			}
+		}(w, g.done)
+	}
+	return nil
+}
+
+// stop waits for the threads to finish the parts they are reading, and
+// discards what they have read.
+func (g *gather) stop() {
+	if g.done == nil {
+		return
+	}
+	close(g.done)
+	g.wg.Wait()
+	g.done = nil
+	for i := g.next; i < len(g.results); i++ {
+		select {
+		case p := <-g.results[i]:
+			g.execution.Release(p.size)
+		default:
+		}
+	}
+}
+
+func (g *gather) Reset() error { return g.Open() }
+
+func (g *gather) Close() {
+	g.stop()
+	g.execution.Release(g.held)
+	g.held = 0
+	g.results = nil
+	g.next = 0
+	g.rows = nil
+	g.row = 0
+	g.pending = nil
+	g.err = nil
+}
+
+func (g *gather) PlanDescription() *PlanDescription {
+	return &PlanDescription{
+		Name:        "Gather",
+		Description: fmt.Sprintf("%d threads", len(g.workers)),
+	}
+}
+
+func (g *gather) Children() []RowReader { return []RowReader{g.workers[0].rows} }
@@ -0,0 +1,173 @@
+package physical
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/jacobsimpson/mtsql/ast"
+	"github.com/jacobsimpson/mtsql/logical"
+	"github.com/jacobsimpson/mtsql/metadata"
+	"github.com/stretchr/testify/assert"
+)
+
+// withPartSize makes files split into small parts, so a test file doesn't
+// have to be large to be scanned in parallel.
+func withPartSize(size int64, f func()) {
+	min, max := minPartSize, maxPartSize
+	minPartSize, maxPartSize = size, size
+	defer func() { minPartSize, maxPartSize = min, max }()
+	f()
+}
+
+func TestParallelScan(t *testing.T) {
+	assert := assert.New(t)
+	dir, err := ioutil.TempDir("", "physical")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var csv strings.Builder
+	csv.WriteString("name,state\n")
+	expected := [][]string{}
+	for i, row := range numbered(5000) {
+		// Some records span lines, which the parts must not split.
+		if i%7 == 0 {
+			row[0] = fmt.Sprintf("line\n%d", i)
+		}
+		fmt.Fprintf(&csv, "\"%s\",%s\n", row[0], row[1])
+		if row[1] == "OR" {
+			expected = append(expected, []string{strings.ToLower(row[0])})
+		}
+	}
+	relation := &metadata.Relation{
+		Name:   "people",
+		Type:   metadata.CsvType,
+		Source: filepath.Join(dir, "people.csv"),
+	}
+	if err := ioutil.WriteFile(relation.Source, []byte(csv.String()), 0644); err != nil {
+		t.Fatal(err)
+	}
+	plan := logical.NewComputedProjection(
+		logical.NewSelection(&logical.Source{Name: "people", Relation: relation}, &logical.EqualConstant{
+			Column: people[1],
+			Value:  &ast.Constant{Type: ast.StringType, Value: "OR", Raw: "'OR'"},
+		}),
+		[]*metadata.Column{{Name: "name"}},
+		[]logical.Expression{&logical.FunctionCall{
+			Name: "LOWER",
+			Args: []logical.Expression{&logical.ColumnValue{Column: people[0]}},
+		}})
+
+	withPartSize(1000, func() {
+		execution := NewExecution(context.Background(), Limits{Threads: 4})
+		rowReader, err := ConvertWith(plan, map[string]*metadata.Relation{"people": relation}, execution)
+		if !assert.Nil(err) {
+			return
+		}
+		defer rowReader.Close()
+
+		assert.Equal(&PlanDescription{Name: "Gather", Description: "4 threads"}, rowReader.PlanDescription())
+		assert.Equal(expected, openAll(t, rowReader))
+		assert.Nil(rowReader.Reset())
+		assert.Equal(expected, readAll(t, rowReader))
+
+		execution = NewExecution(context.Background(), Limits{Threads: 4, MaxRowsScanned: 100})
+		rowReader, err = ConvertWith(plan, map[string]*metadata.Relation{"people": relation}, execution)
+		if !assert.Nil(err) {
+			return
+		}
+		defer rowReader.Close()
+		assert.Nil(rowReader.Open())
+		for {
+			if _, err = rowReader.Read(); err != nil {
+				break
+			}
+		}
+		assert.Equal(&LimitError{Limit: "rows scanned", Max: 100}, err)
+
+		// The parts waiting to be read count against the memory limit, and
+		// are released once they have been read, or the scan is closed.
+		execution = NewExecution(context.Background(), Limits{Threads: 4})
+		rowReader, err = ConvertWith(plan, map[string]*metadata.Relation{"people": relation}, execution)
+		if !assert.Nil(err) {
+			return
+		}
+		assert.Nil(rowReader.Open())
+		_, err = rowReader.Read()
+		assert.Nil(err)
+		assert.True(atomic.LoadInt64(&execution.memory) > 0)
+		rowReader.Close()
+		assert.Equal(int64(0), atomic.LoadInt64(&execution.memory))
+
+		execution = NewExecution(context.Background(), Limits{Threads: 4, MaxMemory: 1000})
+		rowReader, err = ConvertWith(plan, map[string]*metadata.Relation{"people": relation}, execution)
+		if !assert.Nil(err) {
+			return
+		}
+		defer rowReader.Close()
+		assert.Nil(rowReader.Open())
+		for {
+			if _, err = rowReader.Read(); err != nil {
+				break
+			}
+		}
+		assert.Equal(&LimitError{Limit: "memory", Max: 1000}, err)
+	})
+}
+
+func TestParallelScanCompressed(t *testing.T) {
+	assert := assert.New(t)
+	dir, err := ioutil.TempDir("", "physical")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// A gzip file named .csv is read by its magic bytes, and can't be split
+	// into byte ranges.
+	var b bytes.Buffer
+	w := gzip.NewWriter(&b)
+	fmt.Fprintln(w, "name,state")
+	expected := [][]string{}
+	for _, row := range numbered(5000) {
+		fmt.Fprintf(w, "%s,%s\n", row[0], row[1])
+		if row[1] == "OR" {
+			expected = append(expected, row)
+		}
+	}
+	w.Close()
+	relation := &metadata.Relation{
+		Name:   "people",
+		Type:   metadata.CsvType,
+		Source: filepath.Join(dir, "people.csv"),
+	}
+	if err := ioutil.WriteFile(relation.Source, b.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	withPartSize(1000, func() {
+		assert.True(int64(b.Len()) >= 2*minPartSize)
+		execution := NewExecution(context.Background(), Limits{Threads: 4})
+		plan := logical.NewSelection(&logical.Source{Name: "people", Relation: relation}, &logical.EqualConstant{
+			Column: people[1],
+			Value:  &ast.Constant{Type: ast.StringType, Value: "OR", Raw: "'OR'"},
+		})
+		rowReader, err := ConvertWith(plan, map[string]*metadata.Relation{"people": relation}, execution)
+		if !assert.Nil(err) {
+			return
+		}
+		defer rowReader.Close()
+
+		assert.NotEqual("Gather", rowReader.PlanDescription().Name)
+		assert.Equal(expected, openAll(t, rowReader))
+	})
+}
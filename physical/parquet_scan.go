@@ -0,0 +1,143 @@
+package physical
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/jacobsimpson/mtsql/ast"
+	"github.com/jacobsimpson/mtsql/metadata"
+	"github.com/jacobsimpson/mtsql/parquetfile"
+)
+
+// ScanPredicate is an equality a scan can use to avoid reading data that
+// can not match. The rows a scan returns still have to be filtered.
+type ScanPredicate struct {
+	Column *metadata.Column
+	Value  *ast.Constant
+}
+
+type parquetScan struct {
+	reader     *parquetfile.Reader
+	tableName  string
+	fileName   string
+	names      []string
+	predicates []*ScanPredicate
+	columns    []*metadata.Column
+}
+
+// NewParquetScan reads the named columns of a Parquet file, or all of them if
+// there are none. Row groups whose statistics show they can not satisfy the
+// predicates are skipped.
+func NewParquetScan(tableName, fileName string, names []string, predicates []*ScanPredicate) (RowReader, error) {
+	ps := &parquetScan{
+		tableName:  tableName,
+		fileName:   fileName,
+		names:      names,
+		predicates: predicates,
+	}
+	if err := ps.Open(); err != nil {
+		return nil, err
+	}
+	defer ps.Close()
+	for i, c := range ps.reader.Header() {
+		ps.columns = append(ps.columns, &metadata.Column{
+			Qualifier: tableName,
+			Name:      c,
+			Type:      ps.reader.Types()[i],
+		})
+	}
+	return ps, nil
+}
+
+func (t *parquetScan) Open() error {
+	t.Close()
+	reader, err := parquetfile.Open(t.fileName, t.names, t.mightMatch)
+	if err != nil {
+		return err
+	}
+	t.reader = reader
+	return nil
+}
+
+// mightMatch is false when the minimum and maximum of a column show no row
+// in the row group can equal the value of a predicate.
+func (t *parquetScan) mightMatch(stats map[string]*parquetfile.Statistics) bool {
+	types := map[string]metadata.ColumnType{}
+	for _, c := range t.columns {
+		types[c.Name] = c.Type
+	}
+	for _, p := range t.predicates {
+		s := stats[p.Column.Name]
+		if s == nil || s.Min == nil || s.Max == nil {
+			continue
+		}
+		// Dates, timestamps and decimals are stored as numbers, so their
+		// statistics are not comparable with the text of the column.
+		switch min := s.Min.(type) {
+		case int64:
+			v, ok := integerValue(p.Value)
+			if types[p.Column.Name] == metadata.IntegerType && ok && (v < min || v > s.Max.(int64)) {
+				return false
+			}
+		case string:
+			if types[p.Column.Name] != metadata.StringType || p.Value.Type != ast.StringType {
+				continue
+			}
+			v := p.Value.Value.(string)
+			if v < min || v > s.Max.(string) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func integerValue(c *ast.Constant) (int64, bool) {
+	switch c.Type {
+	case ast.IntegerType:
+		return int64(c.Value.(int)), true
+	case ast.StringType:
+		// A string constant is compared with the text of the column, which
+		// only has one spelling for an integer.
+		v, err := strconv.ParseInt(c.Value.(string), 10, 64)
+		return v, err == nil && strconv.FormatInt(v, 10) == c.Value.(string)
+	}
+	return 0, false
+}
+
+func (t *parquetScan) Columns() []*metadata.Column {
+	return t.columns
+}
+
+func (t *parquetScan) Read() ([]string, error) {
+	if t.reader == nil {
+		return nil, errNotOpen
+	}
+	return t.reader.Read()
+}
+
+func (t *parquetScan) Close() {
+	if t.reader != nil {
+		t.reader.Close()
+		t.reader = nil
+	}
+}
+
+func (t *parquetScan) Reset() error { return t.Open() }
+
+func (t *parquetScan) PlanDescription() *PlanDescription {
+	description := fmt.Sprintf("%s, %s", t.tableName, t.fileName)
+	if len(t.names) > 0 {
+		description += fmt.Sprintf(", columns: %s", strings.Join(t.names, ", "))
+	}
+	for _, p := range t.predicates {
+		description += fmt.Sprintf(", skip unless %s = %s", p.Column.Name, p.Value.Raw)
+	}
+	return &PlanDescription{
+		Name:        "ParquetScan",
+		Description: description,
+	}
+}
+
+func (t *parquetScan) Children() []RowReader { return []RowReader{} }
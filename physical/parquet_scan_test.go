@@ -0,0 +1,74 @@
+package physical_test
+
+import (
+	"io"
+	"testing"
+
+	"github.com/jacobsimpson/mtsql/ast"
+	"github.com/jacobsimpson/mtsql/logical"
+	"github.com/jacobsimpson/mtsql/metadata"
+	"github.com/jacobsimpson/mtsql/physical"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParquetScanSkipsRowGroups(t *testing.T) {
+	assert := assert.New(t)
+
+	rowReader, err := physical.NewParquetScan("cities", "testdata/cities.parquet",
+		[]string{"id", "city"},
+		[]*physical.ScanPredicate{{
+			Column: &metadata.Column{Qualifier: "cities", Name: "id"},
+			Value:  &ast.Constant{Type: ast.IntegerType, Value: 5, Raw: "5"},
+		}})
+	assert.Nil(err)
+
+	assert.Equal([]*metadata.Column{
+		{Qualifier: "cities", Name: "id", Type: metadata.IntegerType},
+		{Qualifier: "cities", Name: "city", Type: metadata.StringType},
+	}, rowReader.Columns())
+	assert.Nil(rowReader.Open())
+
+	row, err := rowReader.Read()
+	assert.Nil(err)
+	assert.Equal([]string{"5", "Boise"}, row)
+	_, err = rowReader.Read()
+	assert.Equal(io.EOF, err)
+}
+
+func TestConvertPrunesParquetColumns(t *testing.T) {
+	assert := assert.New(t)
+
+	relation := &metadata.Relation{
+		Name:   "cities",
+		Type:   metadata.ParquetType,
+		Source: "testdata/cities.parquet",
+		Columns: []*metadata.Column{
+			{Qualifier: "cities", Name: "id", Type: metadata.IntegerType},
+			{Qualifier: "cities", Name: "city", Type: metadata.StringType},
+			{Qualifier: "cities", Name: "state", Type: metadata.StringType},
+		},
+	}
+	source := &logical.Source{Name: "cities", Relation: relation}
+	plan := logical.NewProjection(
+		logical.NewSelection(source, &logical.EqualConstant{
+			Column: relation.Columns[0],
+			Value:  &ast.Constant{Type: ast.IntegerType, Value: 3, Raw: "3"},
+		}),
+		[]*metadata.Column{relation.Columns[1]})
+
+	rowReader, err := physical.Convert(plan, map[string]*metadata.Relation{"cities": relation})
+	assert.Nil(err)
+
+	scan := rowReader.Children()[0].Children()[0]
+	assert.Equal(&physical.PlanDescription{
+		Name:        "ParquetScan",
+		Description: "cities, testdata/cities.parquet, columns: id, city, skip unless id = 3",
+	}, scan.PlanDescription())
+
+	assert.Nil(rowReader.Open())
+	row, err := rowReader.Read()
+	assert.Nil(err)
+	assert.Equal([]string{"Portland"}, row)
+	_, err = rowReader.Read()
+	assert.Equal(io.EOF, err)
+}
@@ -4,43 +4,85 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/jacobsimpson/mtsql/jsonfile"
+	"github.com/jacobsimpson/mtsql/logical"
 	"github.com/jacobsimpson/mtsql/metadata"
 )
 
 type projection struct {
 	rowReader     RowReader
 	columnIndexes []int
+	evaluators    []evaluator
+	expressions   []logical.Expression
+	columns       []*metadata.Column
 }
 
 func NewProjection(rowReader RowReader, columns []*metadata.Column) (RowReader, error) {
+	return NewComputedProjection(rowReader, columns, nil)
+}
+
+// NewComputedProjection creates a projection where the columns that have an
+// expression at the same position are computed from it.
+func NewComputedProjection(rowReader RowReader, columns []*metadata.Column, expressions []logical.Expression) (RowReader, error) {
+	p, err := project(rowReader.Columns(), columns, expressions)
+	if err != nil {
+		return nil, err
+	}
+	p.rowReader = rowReader
+	return p, nil
+}
+
+// project works out where each column of a projection comes from, in rows
+// with the input columns.
+func project(input, columns []*metadata.Column, expressions []logical.Expression) (*projection, error) {
 	columnMap := map[string]int{}
-	for i, c := range rowReader.Columns() {
+	for i, c := range input {
 		columnMap[c.QualifiedName()] = i
 		columnMap[c.Name] = i
 	}
 	cols := []int{}
-	for _, c := range columns {
-		if i, ok := columnMap[c.QualifiedName()]; ok {
-			cols = append(cols, i)
-		} else if i, ok := columnMap[c.Name]; ok {
-			cols = append(cols, i)
-		} else {
+	evaluators := make([]evaluator, len(columns))
+	provides := []*metadata.Column{}
+	for n, c := range columns {
+		if expressions != nil && expressions[n] != nil {
+			e, err := compile(expressions[n], input)
+			if err != nil {
+				return nil, err
+			}
+			evaluators[n] = e
+			cols = append(cols, -1)
+			provides = append(provides, c)
+			continue
+		}
+		// A column with a path is extracted from the JSON in its base column.
+		base := c
+		if len(c.Path) > 0 {
+			base = &metadata.Column{Qualifier: c.Qualifier, Name: c.Name}
+		}
+		i, ok := columnMap[base.QualifiedName()]
+		if !ok {
+			i, ok = columnMap[base.Name]
+		}
+		if !ok {
 			return nil, fmt.Errorf("unable to find column %q in dataset", c.QualifiedName())
 		}
+		cols = append(cols, i)
+		if len(c.Path) > 0 {
+			provides = append(provides, c)
+		} else {
+			provides = append(provides, input[i])
+		}
 	}
 	return &projection{
-		rowReader:     rowReader,
 		columnIndexes: cols,
+		evaluators:    evaluators,
+		expressions:   expressions,
+		columns:       provides,
 	}, nil
 }
 
 func (t *projection) Columns() []*metadata.Column {
-	c := t.rowReader.Columns()
-	r := []*metadata.Column{}
-	for _, col := range t.columnIndexes {
-		r = append(r, c[col])
-	}
-	return r
+	return t.columns
 }
 
 func (t *projection) Read() ([]string, error) {
@@ -49,19 +91,30 @@ func (t *projection) Read() ([]string, error) {
 		return nil, err
 	}
 	r := []string{}
-	for _, col := range t.columnIndexes {
-		r = append(r, row[col])
+	for i, col := range t.columnIndexes {
+		if e := t.evaluators[i]; e != nil {
+			r = append(r, e(row))
+		} else if c := t.columns[i]; len(c.Path) > 0 {
+			r = append(r, jsonfile.Extract(row[col], c.Path, c.PathJSON))
+		} else {
+			r = append(r, row[col])
+		}
 	}
 	return r, nil
 }
 
-func (t *projection) Close()       {}
+func (t *projection) Open() error  { return t.rowReader.Open() }
+func (t *projection) Close()       { t.rowReader.Close() }
 func (t *projection) Reset() error { return t.rowReader.Reset() }
 
 func (t *projection) PlanDescription() *PlanDescription {
 	columnNames := []string{}
-	for _, c := range t.Columns() {
-		columnNames = append(columnNames, c.QualifiedName())
+	for i, c := range t.Columns() {
+		if t.evaluators[i] != nil {
+			columnNames = append(columnNames, fmt.Sprintf("%s AS %s", t.expressions[i], c.Name))
+		} else {
+			columnNames = append(columnNames, c.QualifiedName())
+		}
 	}
 	return &PlanDescription{
 		Name:        "Projection",
@@ -3,6 +3,7 @@ package physical
 import (
 	"testing"
 
+	"github.com/jacobsimpson/mtsql/logical"
 	"github.com/jacobsimpson/mtsql/metadata"
 	"github.com/stretchr/testify/assert"
 )
@@ -32,6 +33,7 @@ func TestProjectOneColumn(t *testing.T) {
 		&metadata.Column{Qualifier: "tb1", Name: "col3"},
 	}, columns)
 
+	assert.Nil(proj.Open())
 	r, err := proj.Read()
 	assert.Nil(err)
 	assert.Equal([]string{"row1-col3"}, r)
@@ -64,7 +66,52 @@ func TestProjectTwoColumn(t *testing.T) {
 		&metadata.Column{Qualifier: "tb1", Name: "col1"},
 	}, columns)
 
+	assert.Nil(proj.Open())
 	r, err := proj.Read()
 	assert.Nil(err)
 	assert.Equal([]string{"row1-col3", "row1-col1"}, r)
 }
+
+func TestProjectComputedColumn(t *testing.T) {
+	assert := assert.New(t)
+	col1 := &metadata.Column{Qualifier: "tb1", Name: "col1"}
+	rowReader := memoryScan{
+		columns: []*metadata.Column{col1, &metadata.Column{Qualifier: "tb1", Name: "col2"}},
+		rows: [][]string{
+			[]string{" row1-col1 ", "row1-col2"},
+		},
+	}
+
+	proj, err := NewComputedProjection(&rowReader,
+		[]*metadata.Column{
+			&metadata.Column{Qualifier: "tb1", Name: "col1"},
+			&metadata.Column{Qualifier: "tb1", Name: "col2"},
+		},
+		[]logical.Expression{
+			&logical.FunctionCall{Name: "UPPER", Args: []logical.Expression{
+				&logical.FunctionCall{Name: "TRIM", Args: []logical.Expression{&logical.ColumnValue{Column: col1}}},
+			}},
+			nil,
+		})
+	assert.Nil(err)
+
+	assert.Nil(proj.Open())
+	r, err := proj.Read()
+	assert.Nil(err)
+	assert.Equal([]string{"ROW1-COL1", "row1-col2"}, r)
+	assert.Equal("UPPER(TRIM(tb1.col1)) AS col1, tb1.col2", proj.PlanDescription().Description)
+}
+
+func TestProjectUnknownFunction(t *testing.T) {
+	assert := assert.New(t)
+	col1 := &metadata.Column{Qualifier: "tb1", Name: "col1"}
+	rowReader := memoryScan{columns: []*metadata.Column{col1}}
+
+	_, err := NewComputedProjection(&rowReader,
+		[]*metadata.Column{col1},
+		[]logical.Expression{
+			&logical.FunctionCall{Name: "SHOUT", Args: []logical.Expression{&logical.ColumnValue{Column: col1}}},
+		})
+
+	assert.EqualError(err, "unknown function SHOUT")
+}
@@ -1,14 +1,25 @@
 package physical
 
-import "github.com/jacobsimpson/mtsql/metadata"
+import (
+	"errors"
+
+	"github.com/jacobsimpson/mtsql/metadata"
+)
 
 type PlanDescription struct {
 	Name        string
 	Description string
 }
 
+// RowReader is an operator in a physical plan. Creating one only works out
+// its columns, so a plan can be built, and described, without reading any
+// data. Open starts reading, and is where blocking operators like sorts do
+// their work. Read then returns rows until io.EOF, Reset goes back to the
+// first row, and Close releases the files and memory held. Open and Close
+// apply to the children as well, and a closed reader can be opened again.
 type RowReader interface {
 	Columns() []*metadata.Column
+	Open() error
 	Read() ([]string, error)
 	Reset() error
 	Close()
@@ -16,3 +27,17 @@ type RowReader interface {
 	PlanDescription() *PlanDescription
 	Children() []RowReader
 }
+
+// errNotOpen is returned by scans that are read before they are opened.
+var errNotOpen = errors.New("the plan has not been opened")
+
+// Seeker is a scan that knows where each of its rows starts in its file, and
+// can go back to one, like a scan of a CSV table.
+type Seeker interface {
+	RowReader
+	// Offset is where the row last read starts.
+	Offset() int64
+	// SeekRecord goes to the row starting at offset, so it is the next one
+	// read.
+	SeekRecord(offset int64) error
+}
@@ -12,49 +12,79 @@ import (
 type sortScan struct {
 	rowReader     RowReader
 	columnIndexes []int
+	sortOrder     []SortOrder
+	execution     *Execution
 	rows          [][]string
+	held          int64
 	next          int
 }
 
+// NewSortScan sorts the rows of rowReader. The rows are read and sorted when
+// the sort is opened.
 func NewSortScan(rowReader RowReader, columns []SortScanCriteria) (RowReader, error) {
-	columnMap := map[metadata.Column]int{}
-	for i, c := range rowReader.Columns() {
-		columnMap[*c] = i
-	}
+	return newSortScan(rowReader, columns, nil)
+}
+
+// newSortScan is a sort that counts the rows it holds against the memory
+// limit of the execution.
+func newSortScan(rowReader RowReader, columns []SortScanCriteria, execution *Execution) (RowReader, error) {
 	cols := []int{}
 	sortOrder := []SortOrder{}
 	for _, c := range columns {
-		cols = append(cols, columnMap[*c.Column])
+		i, err := findColumn(c.Column, rowReader.Columns())
+		if err != nil {
+			return nil, err
+		}
+		cols = append(cols, i)
 		sortOrder = append(sortOrder, c.SortOrder)
 	}
+	return &sortScan{
+		rowReader:     rowReader,
+		columnIndexes: cols,
+		sortOrder:     sortOrder,
+		execution:     execution,
+	}, nil
+}
 
+func (t *sortScan) Columns() []*metadata.Column {
+	return t.rowReader.Columns()
+}
+
+// Open reads every row of the child, and sorts them.
+func (t *sortScan) Open() error {
+	t.Close()
+	if err := t.rowReader.Open(); err != nil {
+		return err
+	}
 	rows := [][]string{}
 	for {
-		row, err := rowReader.Read()
+		row, err := t.rowReader.Read()
 		if err != nil {
 			if err != io.EOF {
-				return nil, err
+				return err
 			}
 			break
 		}
 		if row == nil {
 			break
 		}
+		size := rowSize(row)
+		t.held += size
+		if err := t.execution.Allocate(size); err != nil {
+			return err
+		}
 		rows = append(rows, row)
 	}
-	sort.Sort(&columnSorter{rows: rows, columns: cols, sortOrder: sortOrder})
-	return &sortScan{
-		rowReader:     rowReader,
-		rows:          rows,
-		columnIndexes: cols,
-	}, nil
-}
-
-func (t *sortScan) Columns() []*metadata.Column {
-	return t.rowReader.Columns()
+	sort.Sort(&columnSorter{rows: rows, columns: t.columnIndexes, sortOrder: t.sortOrder})
+	t.rows = rows
+	t.next = 0
+	return nil
 }
 
 func (t *sortScan) Read() ([]string, error) {
+	if t.rows == nil {
+		return nil, errNotOpen
+	}
 	if t.next >= len(t.rows) {
 		return nil, io.EOF
 	}
@@ -63,7 +93,13 @@ func (t *sortScan) Read() ([]string, error) {
 	return row, nil
 }
 
-func (t *sortScan) Close() {}
+func (t *sortScan) Close() {
+	t.rowReader.Close()
+	t.execution.Release(t.held)
+	t.held = 0
+	t.rows = nil
+}
+
 func (t *sortScan) Reset() error {
 	t.next = 0
 	return nil
@@ -0,0 +1,29 @@
+package physical
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSortScan(t *testing.T) {
+	assert := assert.New(t)
+	rowReader := &memoryScan{columns: people, rows: [][]string{
+		{"bob", "OR"},
+		{"ann", "WA"},
+		{"cat", "ID"},
+	}}
+
+	sortScan, err := NewSortScan(rowReader, []SortScanCriteria{{Column: people[1], SortOrder: Desc}})
+	assert.Nil(err)
+	// Nothing is read until the sort is opened.
+	assert.Equal(0, rowReader.next)
+
+	assert.Equal([][]string{{"ann", "WA"}, {"bob", "OR"}, {"cat", "ID"}}, openAll(t, sortScan))
+	assert.Nil(sortScan.Reset())
+	assert.Equal([][]string{{"ann", "WA"}, {"bob", "OR"}, {"cat", "ID"}}, readAll(t, sortScan))
+
+	sortScan.Close()
+	_, err = sortScan.Read()
+	assert.Equal(errNotOpen, err)
+}
@@ -1,26 +1,31 @@
 package physical
 
 import (
-	"encoding/csv"
 	"fmt"
-	"os"
 
+	"github.com/jacobsimpson/mtsql/csvfile"
 	"github.com/jacobsimpson/mtsql/metadata"
 )
 
 type tableScan struct {
-	file      *os.File
-	reader    *csv.Reader
+	reader    *csvfile.Reader
 	tableName string
 	fileName  string
+	dialect   *metadata.CsvDialect
 	columns   []*metadata.Column
-	firstRow  int64
 }
 
 func NewTableScan(tableName, fileName string) (RowReader, error) {
+	return NewDialectTableScan(tableName, fileName, nil)
+}
+
+// NewDialectTableScan scans a CSV file laid out according to the dialect. A
+// nil dialect is the default comma separated file with a header row.
+func NewDialectTableScan(tableName, fileName string, dialect *metadata.CsvDialect) (RowReader, error) {
 	ts := &tableScan{
 		tableName: tableName,
 		fileName:  fileName,
+		dialect:   dialect,
 	}
 	if err := ts.init(); err != nil {
 		return nil, err
@@ -28,52 +33,67 @@ func NewTableScan(tableName, fileName string) (RowReader, error) {
 	return ts, nil
 }
 
+func (t *tableScan) Open() error {
+	t.Close()
+	reader, err := csvfile.Open(t.fileName, t.dialect)
+	if err != nil {
+		return err
+	}
+	t.reader = reader
+	return nil
+}
+
 func (t *tableScan) Columns() []*metadata.Column {
 	return t.columns
 }
 
 func (t *tableScan) Read() ([]string, error) {
-	for {
-		r, err := t.reader.Read()
-		if err != nil {
-			return nil, err
-		}
-		// After the CSV reader has read all the lines in a file, it will
-		// return an extra line, a 0 length array.
-		if len(r) != 0 {
-			return r, err
-		}
+	if t.reader == nil {
+		return nil, errNotOpen
 	}
+	return t.reader.Read()
 }
 
 func (t *tableScan) Close() {
-	t.file.Close()
+	if t.reader != nil {
+		t.reader.Close()
+		t.reader = nil
+	}
 }
 
+// Reset goes back to the first row, seeking rather than opening the file
+// again when it can.
 func (t *tableScan) Reset() error {
-	_, err := t.file.Seek(t.firstRow, 0)
-	return err
+	if t.reader == nil {
+		return t.Open()
+	}
+	return t.reader.Rewind()
 }
 
-func (t *tableScan) init() error {
-	f, err := os.Open(t.fileName)
-	if err != nil {
-		return err
+// Offset is where in the file the row last read starts.
+func (t *tableScan) Offset() int64 {
+	if t.reader == nil {
+		return 0
 	}
-	t.file = f
-	reader := csv.NewReader(f)
-	columns, err := reader.Read()
-	if err != nil {
-		return err
+	return t.reader.Offset()
+}
+
+// SeekRecord goes to the row starting at offset, so it is the next one read.
+func (t *tableScan) SeekRecord(offset int64) error {
+	if t.reader == nil {
+		return errNotOpen
 	}
-	firstRow, err := f.Seek(0, 1)
+	return t.reader.SeekRecord(offset)
+}
+
+// init reads the header of the file for the columns.
+func (t *tableScan) init() error {
+	reader, err := csvfile.Open(t.fileName, t.dialect)
 	if err != nil {
 		return err
 	}
-	t.firstRow = firstRow
-
-	t.reader = reader
-	for _, c := range columns {
+	defer reader.Close()
+	for _, c := range reader.Header() {
 		t.columns = append(t.columns, &metadata.Column{
 			Qualifier: t.tableName,
 			Name:      c,
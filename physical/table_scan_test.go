@@ -20,9 +20,60 @@ func TestReadOneRow(t *testing.T) {
 	assert.Equal(
 		&metadata.Column{Qualifier: "cities", Name: "City"},
 		rowReader.Columns()[8])
+	assert.Nil(rowReader.Open())
 
 	row, err := rowReader.Read()
 	assert.Nil(err)
 	assert.Equal("41", row[0])
 	assert.Equal("5", row[1])
 }
+
+func TestResetCompressedTableScan(t *testing.T) {
+	assert := assert.New(t)
+
+	rowReader, err := physical.NewTableScan("cities", "testdata/cities.csv.gz")
+	assert.Nil(err)
+	assert.Equal(
+		&metadata.Column{Qualifier: "cities", Name: "City"},
+		rowReader.Columns()[8])
+	assert.Nil(rowReader.Open())
+
+	_, err = rowReader.Read()
+	assert.Nil(err)
+	row, err := rowReader.Read()
+	assert.Nil(err)
+	assert.Equal("Yankton", row[8])
+
+	assert.Nil(rowReader.Reset())
+	row, err = rowReader.Read()
+	assert.Nil(err)
+	assert.Equal("Youngstown", row[8])
+}
+
+func TestSeekTableScan(t *testing.T) {
+	assert := assert.New(t)
+
+	rowReader, err := physical.NewTableScan("cities", "testdata/cities.csv")
+	assert.Nil(err)
+	assert.Nil(rowReader.Open())
+	defer rowReader.Close()
+	seeker := rowReader.(physical.Seeker)
+
+	first, err := rowReader.Read()
+	assert.Nil(err)
+	second, err := rowReader.Read()
+	assert.Nil(err)
+	offset := seeker.Offset()
+	_, err = rowReader.Read()
+	assert.Nil(err)
+
+	assert.Nil(seeker.SeekRecord(offset))
+	row, err := rowReader.Read()
+	assert.Nil(err)
+	assert.Equal(second, row)
+
+	assert.Nil(rowReader.Reset())
+	row, err = rowReader.Read()
+	assert.Nil(err)
+	assert.Equal(first, row)
+}
@@ -2,38 +2,85 @@ package preprocessor
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/jacobsimpson/mtsql/ast"
 	md "github.com/jacobsimpson/mtsql/metadata"
+	"github.com/jacobsimpson/mtsql/suggest"
 )
 
+// mapper resolves the attributes in a query to the columns available. Names
+// that were quoted must match exactly, while others ignore case.
 type mapper struct {
-	names     map[string][]*md.Column
-	qualified map[string][]*md.Column
-	aliases   map[string][]*md.Column
+	columns []*md.Column
 }
 
 func newMapper(columns []*md.Column) *mapper {
-	result := &mapper{
-		names:     map[string][]*md.Column{},
-		qualified: map[string][]*md.Column{},
-		aliases:   map[string][]*md.Column{},
-	}
-	for _, c := range columns {
-		result.names[c.Name] = append(result.names[c.Name], c)
-		if c.Qualifier != "" {
-			result.qualified[c.QualifiedName()] = append(result.qualified[c.QualifiedName()], c)
-		}
-		if c.Alias != "" {
-			result.aliases[c.Alias] = append(result.aliases[c.Alias], c)
+	return &mapper{columns: columns}
+}
+
+// matching returns the columns the test accepts, in order.
+func (m *mapper) matching(test func(c *md.Column) bool) []*md.Column {
+	var result []*md.Column
+	for _, c := range m.columns {
+		if test(c) {
+			result = append(result, c)
 		}
 	}
 	return result
 }
 
+// byName returns the columns with a name. An exact match is preferred over
+// one that only differs in case.
+func (m *mapper) byName(name string, quoted bool, field func(c *md.Column) string) []*md.Column {
+	r := m.matching(func(c *md.Column) bool { return field(c) == name })
+	if r == nil && !quoted {
+		r = m.matching(func(c *md.Column) bool { return strings.EqualFold(field(c), name) })
+	}
+	return r
+}
+
+// qualified returns the columns with a name from the relation the qualifier
+// of an attribute names.
+func (m *mapper) qualified(a *ast.Attribute) []*md.Column {
+	relation := newMapper(m.relation(a))
+	return relation.byName(a.Name, a.Quoted, columnName)
+}
+
+// relation returns the columns of the relation the qualifier of an attribute
+// names, matching it the same way as names.
+func (m *mapper) relation(a *ast.Attribute) []*md.Column {
+	return m.byName(a.Qualifier, a.QualifierQuoted, func(c *md.Column) string { return c.Qualifier })
+}
+
 func (m *mapper) findMatches(a *ast.Attribute) ([]*md.Column, error) {
+	// payload.user parses as a qualified name, but when there is no such
+	// qualified column, it is a path into the payload column.
+	if a.Qualifier != "" && a.Name != "*" && m.qualified(a) == nil &&
+		m.byName(a.Qualifier, a.QualifierQuoted, columnName) != nil {
+		a = &ast.Attribute{
+			Name:     a.Qualifier,
+			Quoted:   a.QualifierQuoted,
+			Alias:    a.Alias,
+			Path:     append([]string{a.Name}, a.Path...),
+			PathJSON: a.PathJSON,
+		}
+	}
+	if len(a.Path) > 0 {
+		base := *a
+		base.Path = nil
+		base.PathJSON = false
+		r, err := m.findMatches(&base)
+		if err != nil {
+			return nil, err
+		}
+		c := *r[0]
+		c.Path = a.Path
+		c.PathJSON = a.PathJSON
+		return []*md.Column{&c}, nil
+	}
 	if a.Alias != "" {
-		r := m.aliases[a.Alias]
+		r := m.byName(a.Alias, false, func(c *md.Column) string { return c.Alias })
 		if r == nil {
 			return nil, fmt.Errorf("no matching alias for %q", a.Alias)
 		}
@@ -42,11 +89,18 @@ func (m *mapper) findMatches(a *ast.Attribute) ([]*md.Column, error) {
 		}
 		return r, nil
 	}
+	if a.Name == "*" && a.Qualifier != "" {
+		r := m.relation(a)
+		if r == nil {
+			return nil, fmt.Errorf("no table %q for %s.*", a.Qualifier, a.Qualifier)
+		}
+		return r, nil
+	}
 	if a.Qualifier != "" {
 		qualifiedName := fmt.Sprintf("%s.%s", a.Qualifier, a.Name)
-		r := m.qualified[qualifiedName]
+		r := m.qualified(a)
 		if r == nil {
-			return nil, fmt.Errorf("no matching qualified name %q", qualifiedName)
+			return nil, fmt.Errorf("no matching qualified name %q%s", qualifiedName, m.didYouMean(qualifiedName, qualifiedColumnName))
 		}
 		if len(r) > 1 {
 			return nil, fmt.Errorf("too many matching qualified names %q", qualifiedName)
@@ -54,18 +108,60 @@ func (m *mapper) findMatches(a *ast.Attribute) ([]*md.Column, error) {
 		return r, nil
 	}
 	if a.Name == "*" {
-		r := []*md.Column{}
-		for _, n := range m.names {
-			r = append(r, n...)
-		}
-		return r, nil
+		return append([]*md.Column{}, m.columns...), nil
 	}
-	r := m.names[a.Name]
+	r := m.byName(a.Name, a.Quoted, columnName)
 	if r == nil {
-		return nil, fmt.Errorf("no matching name %q", a.Name)
+		return nil, fmt.Errorf("no matching name %q%s", a.Name, m.didYouMean(a.Name, columnName))
 	}
 	if len(r) > 1 {
 		return nil, fmt.Errorf("too many matching names %q", a.Name)
 	}
 	return r, nil
 }
+
+// findMatch finds the single column an attribute in a condition refers to.
+func (m *mapper) findMatch(a *ast.Attribute) (*md.Column, error) {
+	if a.Name == "*" {
+		return nil, fmt.Errorf("* can not be used in a condition")
+	}
+	r, err := m.findMatches(a)
+	if err != nil {
+		return nil, err
+	}
+	return r[0], nil
+}
+
+func columnName(c *md.Column) string          { return c.Name }
+func qualifiedColumnName(c *md.Column) string { return c.QualifiedName() }
+
+// didYouMean suggests the columns that were most likely meant in place of a
+// misspelled name.
+func (m *mapper) didYouMean(name string, field func(c *md.Column) string) string {
+	candidates := []string{}
+	for _, c := range m.columns {
+		candidates = append(candidates, field(c))
+	}
+	return didYouMean(name, candidates)
+}
+
+// didYouMean suggests the names that were most likely meant in place of a
+// misspelled one.
+func didYouMean(name string, candidates []string) string {
+	nearest := []string{}
+	seen := map[string]bool{}
+	for _, n := range suggest.Nearest(name, candidates, len(candidates)) {
+		if !seen[n] && len(nearest) < 3 {
+			seen[n] = true
+			nearest = append(nearest, fmt.Sprintf("%q", n))
+		}
+	}
+	switch len(nearest) {
+	case 0:
+		return ""
+	case 1:
+		return fmt.Sprintf(", did you mean %s?", nearest[0])
+	}
+	return fmt.Sprintf(", did you mean %s or %s?",
+		strings.Join(nearest[:len(nearest)-1], ", "), nearest[len(nearest)-1])
+}
@@ -1,6 +1,7 @@
 package preprocessor
 
 import (
+	"fmt"
 	"testing"
 
 	"github.com/jacobsimpson/mtsql/ast"
@@ -14,12 +15,115 @@ func TestMapper(t *testing.T) {
 		columns  []*md.Column
 		input    *ast.Attribute
 		expected []*md.Column
+		err      error
 	}{
 		{
-			name:     "empty columns",
-			columns:  []*md.Column{},
-			input:    &ast.Attribute{Name: "abc"},
-			expected: []*md.Column{},
+			name:    "empty columns",
+			columns: []*md.Column{},
+			input:   &ast.Attribute{Name: "abc"},
+			err:     fmt.Errorf(`no matching name "abc"`),
+		},
+		{
+			name:    "misspelled name",
+			columns: []*md.Column{{Qualifier: "cities", Name: "City"}, {Qualifier: "cities", Name: "State"}},
+			input:   &ast.Attribute{Name: "Cty"},
+			err:     fmt.Errorf(`no matching name "Cty", did you mean "City"?`),
+		},
+		{
+			name:    "misspelled qualified name",
+			columns: []*md.Column{{Qualifier: "cities", Name: "City"}, {Qualifier: "cities", Name: "State"}},
+			input:   &ast.Attribute{Qualifier: "cites", Name: "State"},
+			err:     fmt.Errorf(`no matching qualified name "cites.State", did you mean "cities.State"?`),
+		},
+		{
+			name:     "unquoted names ignore case",
+			columns:  []*md.Column{{Qualifier: "cities", Name: "City"}, {Qualifier: "cities", Name: "State"}},
+			input:    &ast.Attribute{Qualifier: "CITIES", Name: "city"},
+			expected: []*md.Column{{Qualifier: "cities", Name: "City"}},
+		},
+		{
+			name:     "exact case is preferred",
+			columns:  []*md.Column{{Qualifier: "t", Name: "ID"}, {Qualifier: "t", Name: "id"}},
+			input:    &ast.Attribute{Name: "id"},
+			expected: []*md.Column{{Qualifier: "t", Name: "id"}},
+		},
+		{
+			name:    "names differing only in case are ambiguous",
+			columns: []*md.Column{{Qualifier: "t", Name: "ID"}, {Qualifier: "t", Name: "id"}},
+			input:   &ast.Attribute{Name: "Id"},
+			err:     fmt.Errorf(`too many matching names "Id"`),
+		},
+		{
+			name:    "quoted names match exactly",
+			columns: []*md.Column{{Qualifier: "cities", Name: "City"}},
+			input:   &ast.Attribute{Name: "city", Quoted: true},
+			err:     fmt.Errorf(`no matching name "city", did you mean "City"?`),
+		},
+		{
+			name:    "quoted qualifiers match exactly",
+			columns: []*md.Column{{Qualifier: "c", Name: "id"}},
+			input:   &ast.Attribute{Qualifier: "C", QualifierQuoted: true, Name: "id"},
+			err:     fmt.Errorf(`no matching qualified name "C.id", did you mean "c.id"?`),
+		},
+		{
+			name:     "quoted qualifier",
+			columns:  []*md.Column{{Qualifier: "C", Name: "id"}, {Qualifier: "c", Name: "id"}},
+			input:    &ast.Attribute{Qualifier: "C", QualifierQuoted: true, Name: "id"},
+			expected: []*md.Column{{Qualifier: "C", Name: "id"}},
+		},
+		{
+			name:    "quoted qualifier and star",
+			columns: []*md.Column{{Qualifier: "c", Name: "id"}},
+			input:   &ast.Attribute{Qualifier: "C", QualifierQuoted: true, Name: "*"},
+			err:     fmt.Errorf(`no table "C" for C.*`),
+		},
+		{
+			name:    "several suggestions",
+			columns: []*md.Column{{Qualifier: "t", Name: "Stats"}, {Qualifier: "t", Name: "City"}, {Qualifier: "t", Name: "State"}},
+			input:   &ast.Attribute{Name: "Stat"},
+			err:     fmt.Errorf(`no matching name "Stat", did you mean "Stats" or "State"?`),
+		},
+		{
+			name: "star keeps column order",
+			columns: []*md.Column{
+				{Qualifier: "t", Name: "e"}, {Qualifier: "t", Name: "d"}, {Qualifier: "t", Name: "c"},
+				{Qualifier: "t", Name: "b"}, {Qualifier: "t", Name: "a"},
+			},
+			input: &ast.Attribute{Name: "*"},
+			expected: []*md.Column{
+				{Qualifier: "t", Name: "e"}, {Qualifier: "t", Name: "d"}, {Qualifier: "t", Name: "c"},
+				{Qualifier: "t", Name: "b"}, {Qualifier: "t", Name: "a"},
+			},
+		},
+		{
+			name: "qualified star",
+			columns: []*md.Column{
+				{Qualifier: "c", Name: "id"}, {Qualifier: "o", Name: "id"}, {Qualifier: "c", Name: "name"},
+			},
+			input:    &ast.Attribute{Qualifier: "C", Name: "*"},
+			expected: []*md.Column{{Qualifier: "c", Name: "id"}, {Qualifier: "c", Name: "name"}},
+		},
+		{
+			name:    "qualified star without a table",
+			columns: []*md.Column{{Qualifier: "c", Name: "id"}},
+			input:   &ast.Attribute{Qualifier: "x", Name: "*"},
+			err:     fmt.Errorf(`no table "x" for x.*`),
+		},
+		{
+			name:    "json path",
+			columns: []*md.Column{{Qualifier: "events", Name: "payload"}},
+			input:   &ast.Attribute{Qualifier: "payload", Name: "user", Path: []string{"id"}},
+			expected: []*md.Column{
+				{Qualifier: "events", Name: "payload", Path: []string{"user", "id"}},
+			},
+		},
+		{
+			name:    "qualified json path",
+			columns: []*md.Column{{Qualifier: "events", Name: "payload"}},
+			input:   &ast.Attribute{Qualifier: "events", Name: "payload", Path: []string{"user"}, PathJSON: true},
+			expected: []*md.Column{
+				{Qualifier: "events", Name: "payload", Path: []string{"user"}, PathJSON: true},
+			},
 		},
 	}
 
@@ -28,8 +132,9 @@ func TestMapper(t *testing.T) {
 			assert := assert.New(t)
 
 			m := newMapper(test.columns)
-			result := m.findMatches(test.input)
+			result, err := m.findMatches(test.input)
 
+			assert.Equal(test.err, err)
 			assert.Equal(test.expected, result)
 		})
 	}
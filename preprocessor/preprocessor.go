@@ -1,92 +1,302 @@
 package preprocessor
 
 import (
-	"encoding/csv"
 	"fmt"
+	"io/ioutil"
 	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
 
 	"github.com/jacobsimpson/mtsql/ast"
+	"github.com/jacobsimpson/mtsql/csvfile"
+	"github.com/jacobsimpson/mtsql/decompress"
+	"github.com/jacobsimpson/mtsql/jsonfile"
 	"github.com/jacobsimpson/mtsql/logical"
 	md "github.com/jacobsimpson/mtsql/metadata"
+	"github.com/jacobsimpson/mtsql/parquetfile"
+	"github.com/jacobsimpson/mtsql/spool"
 )
 
+// stdinTable is the name of the table read from CSV data on standard input.
+const stdinTable = "stdin"
+
+// Convert builds the logical plan for a query, finding tables that aren't in
+// the catalog in the current directory.
 func Convert(q ast.Query, tables map[string]*md.Relation) (logical.Operation, error) {
+	return ConvertIn(q, tables, "")
+}
+
+// ConvertIn builds the logical plan for a query, with table names and
+// relative paths found in dir rather than the current directory.
+func ConvertIn(q ast.Query, tables map[string]*md.Relation, dir string) (logical.Operation, error) {
 	var sfw *ast.SFW
-	if p, ok := q.(*ast.Profile); ok {
-		sfw = p.SFW
-	} else if s, ok := q.(*ast.SFW); ok {
-		sfw = s
-	} else {
+	switch q := q.(type) {
+	case *ast.Profile:
+		sfw = q.SFW
+	case *ast.SFW:
+		sfw = q
+	case *ast.Delete:
+		return convertModification(q.Table, q.Where, nil, tables, dir)
+	case *ast.Update:
+		return convertModification(q.Table, q.Where, q.Set, tables, dir)
+	default:
 		return nil, fmt.Errorf("expected a select query, but got something else")
 	}
 
-	result, err := convertFrom(sfw.From, tables)
+	result, err := convertFrom(sfw.From, tables, dir)
 	if err != nil {
 		return nil, err
 	}
 
 	if sfw.Where != nil {
-		result = &logical.Selection{
-			Child: result,
+		condition, err := convertCondition(sfw.Where, newMapper(result.Provides()))
+		if err != nil {
+			return nil, err
 		}
+		result = logical.NewSelection(result, condition)
 	}
 
 	if sfw.SelList != nil {
 		mapper := newMapper(result.Provides())
 
 		columns := []*md.Column{}
+		var expressions []logical.Expression
 		for _, a := range sfw.SelList.Attributes {
 			matches, err := mapper.findMatches(a)
 			if err != nil {
 				return nil, err
 			}
+			exprs := make([]logical.Expression, len(matches))
+			if a.Name == "*" {
+				matches, exprs, err = modifyStar(a, matches, mapper)
+				if err != nil {
+					return nil, err
+				}
+			}
 			columns = append(columns, matches...)
+			expressions = append(expressions, exprs...)
+		}
+		if !computed(expressions) {
+			expressions = nil
 		}
 
-		result = logical.NewProjection(
+		result = logical.NewComputedProjection(
 			result,
-			columns)
+			columns,
+			expressions)
 	}
 
 	return result, nil
 }
 
-func convertFrom(from ast.From, tables map[string]*md.Relation) (logical.Operation, error) {
+func convertFrom(from ast.From, tables map[string]*md.Relation, dir string) (logical.Operation, error) {
 	if rel, ok := from.(*ast.Relation); ok {
-		return convertRelation(rel, tables)
+		return convertRelation(rel, tables, dir)
 	}
 	if ij, ok := from.(*ast.InnerJoin); ok {
-		left, err := convertRelation(ij.Left, tables)
+		left, err := convertRelation(ij.Left, tables, dir)
 		if err != nil {
 			return nil, err
 		}
-		right, err := convertRelation(ij.Right, tables)
+		right, err := convertRelation(ij.Right, tables, dir)
 		if err != nil {
 			return nil, err
 		}
-		selection := &logical.Selection{
-			Child: &logical.Product{
-				LHS: left,
-				RHS: right,
-			},
-			//requires: []*md.Column{
-			//	&md.Column{},
-			//},
+		product := &logical.Product{
+			LHS: left,
+			RHS: right,
 		}
-		return selection, nil
+		condition, err := convertCondition(ij.On, newMapper(product.Provides()))
+		if err != nil {
+			return nil, err
+		}
+		return logical.NewSelection(product, condition), nil
 	}
 	return nil, fmt.Errorf("unable to convert from relationship")
 }
 
-func convertRelation(relation *ast.Relation, tables map[string]*md.Relation) (*logical.Source, error) {
-	t := tables[relation.Name]
+// convertModification converts a DELETE, which has no assignments, or an
+// UPDATE of a table.
+func convertModification(table *ast.Relation, where ast.Condition, set []*ast.Assignment, tables map[string]*md.Relation, dir string) (logical.Operation, error) {
+	source, err := convertRelation(table, tables, dir)
+	if err != nil {
+		return nil, err
+	}
+	mapper := newMapper(source.Provides())
+	result := &logical.Modification{Child: source, Delete: set == nil}
+	if where != nil {
+		result.Condition, err = convertCondition(where, mapper)
+		if err != nil {
+			return nil, err
+		}
+	}
+	for _, a := range set {
+		column, err := mapper.findMatch(a.Column)
+		if err != nil {
+			return nil, err
+		}
+		value, err := convertExpression(a.Value, mapper)
+		if err != nil {
+			return nil, err
+		}
+		result.Assignments = append(result.Assignments, &logical.Assignment{Column: column, Value: value})
+	}
+	return result, nil
+}
+
+// modifyStar applies the EXCLUDE and REPLACE clauses of a * to the columns
+// it expanded to. It returns the remaining columns, along with the
+// expressions that compute the replaced ones.
+func modifyStar(a *ast.Attribute, columns []*md.Column, mapper *mapper) ([]*md.Column, []logical.Expression, error) {
+	for _, e := range a.Exclude {
+		excluded, err := newMapper(columns).findMatch(e)
+		if err != nil {
+			return nil, nil, fmt.Errorf("unable to exclude column: %v", err)
+		}
+		remaining := []*md.Column{}
+		for _, c := range columns {
+			if c != excluded {
+				remaining = append(remaining, c)
+			}
+		}
+		columns = remaining
+	}
+
+	expressions := make([]logical.Expression, len(columns))
+	for _, r := range a.Replace {
+		replaced, err := newMapper(columns).findMatch(&ast.Attribute{Name: r.Name, Quoted: r.Quoted})
+		if err != nil {
+			return nil, nil, fmt.Errorf("unable to replace column: %v", err)
+		}
+		e, err := convertExpression(r.Expression, mapper)
+		if err != nil {
+			return nil, nil, err
+		}
+		for i, c := range columns {
+			if c == replaced {
+				columns[i] = &md.Column{Qualifier: c.Qualifier, Name: c.Name, Type: md.StringType}
+				expressions[i] = e
+			}
+		}
+	}
+	return columns, expressions, nil
+}
+
+// computed returns true if any of the columns have an expression.
+func computed(expressions []logical.Expression) bool {
+	for _, e := range expressions {
+		if e != nil {
+			return true
+		}
+	}
+	return false
+}
+
+func convertExpression(e ast.Expression, mapper *mapper) (logical.Expression, error) {
+	switch e := e.(type) {
+	case *ast.Attribute:
+		column, err := mapper.findMatch(e)
+		if err != nil {
+			return nil, err
+		}
+		return &logical.ColumnValue{Column: column}, nil
+	case *ast.Constant:
+		return &logical.ConstantValue{Value: e}, nil
+	case *ast.FunctionCall:
+		call := &logical.FunctionCall{Name: e.Name}
+		for _, a := range e.Args {
+			arg, err := convertExpression(a, mapper)
+			if err != nil {
+				return nil, err
+			}
+			call.Args = append(call.Args, arg)
+		}
+		return call, nil
+	}
+	return nil, fmt.Errorf("unsupported expression %v", e)
+}
+
+func convertCondition(condition ast.Condition, mapper *mapper) (logical.Condition, error) {
+	switch c := condition.(type) {
+	case *ast.EqualCondition:
+		column, err := mapper.findMatch(c.LHS)
+		if err != nil {
+			return nil, err
+		}
+		return &logical.EqualConstant{Column: column, Value: c.RHS}, nil
+	case *ast.CompareCondition:
+		column, err := mapper.findMatch(c.LHS)
+		if err != nil {
+			return nil, err
+		}
+		return &logical.CompareConstant{Column: column, Operator: c.Operator, Value: c.RHS}, nil
+	case *ast.InCondition:
+		column, err := mapper.findMatch(c.LHS)
+		if err != nil {
+			return nil, err
+		}
+		return &logical.InConstants{Column: column, Values: c.Values}, nil
+	case *ast.EqualColumnCondition:
+		left, err := mapper.findMatch(c.Left)
+		if err != nil {
+			return nil, err
+		}
+		right, err := mapper.findMatch(c.Right)
+		if err != nil {
+			return nil, err
+		}
+		return &logical.EqualColumns{Left: left, Right: right}, nil
+	}
+	return nil, fmt.Errorf("unsupported condition %T", condition)
+}
+
+// FindRelation finds the relation a table in a query refers to, loading it
+// into the catalog if it isn't already there.
+func FindRelation(relation *ast.Relation, tables map[string]*md.Relation) (*md.Relation, error) {
+	source, err := convertRelation(relation, tables, "")
+	if err != nil {
+		return nil, err
+	}
+	return source.Relation, nil
+}
+
+func convertRelation(relation *ast.Relation, tables map[string]*md.Relation, dir string) (*logical.Source, error) {
+	if relation.Function != nil {
+		return convertTableFunction(relation.Function, tables, dir)
+	}
+	if relation.Path != "" {
+		return convertFile(inDir(dir, relation.Path), RelationType(relation.Path), nil, tables)
+	}
+	t, err := findTable(relation, tables)
+	if err != nil {
+		return nil, err
+	}
+	if t == nil && relation.Name == stdinTable {
+		return convertFile(spool.StdinName, md.CsvType, nil, tables)
+	}
 	if t == nil {
+		source, err := findTableFile(relation, dir)
+		if err != nil {
+			// A table or file of the same name takes the place of a system
+			// table. The system tables are made again for each query, so
+			// they are up to date, and aren't kept in the catalog, the
+			// physical plan reads them from the source.
+			system, systemErr := systemTable(relation, tables, dir)
+			if systemErr != nil {
+				return nil, systemErr
+			}
+			if system != nil {
+				return &logical.Source{Name: system.Name, Relation: system}, nil
+			}
+			return nil, err
+		}
 		t = &md.Relation{
 			Name:   relation.Name,
-			Type:   md.CsvType,
-			Source: relation.Name + ".csv",
+			Type:   RelationType(source),
+			Source: source,
 		}
-		columns, err := loadColumns(t.Name, t.Source)
+		columns, err := loadColumns(t)
 		if err != nil {
 			return nil, err
 		}
@@ -94,24 +304,374 @@ func convertRelation(relation *ast.Relation, tables map[string]*md.Relation) (*l
 
 		tables[t.Name] = t
 	}
-	return &logical.Source{t.Name, t}, nil
+	return &logical.Source{Name: t.Name, Relation: t}, nil
+}
+
+// RelationType determines the type of a file from its extension, ignoring
+// any compression extension.
+func RelationType(source string) md.RelationType {
+	switch strings.ToLower(filepath.Ext(decompress.TrimExt(source))) {
+	case ".json":
+		return md.JsonType
+	case ".ndjson", ".jsonl":
+		return md.NdjsonType
+	case ".parquet":
+		return md.ParquetType
+	}
+	return md.CsvType
+}
+
+// inDir resolves a relative path against dir. Paths are left as they are
+// when dir is empty.
+func inDir(dir, path string) string {
+	if dir == "" || filepath.IsAbs(path) || path == spool.StdinName {
+		return path
+	}
+	return filepath.Join(dir, path)
+}
+
+// findTable returns the relation in the catalog a name refers to, ignoring
+// case unless the name was quoted, or nil if there is none. A table with
+// exactly the name is preferred, and otherwise the name must not match tables
+// that differ only in case.
+func findTable(relation *ast.Relation, tables map[string]*md.Relation) (*md.Relation, error) {
+	if t := tables[relation.Name]; t != nil || relation.Quoted {
+		return t, nil
+	}
+	var found *md.Relation
+	for name, t := range tables {
+		if strings.EqualFold(name, relation.Name) {
+			if found != nil {
+				return nil, fmt.Errorf("too many matching tables %q", relation.Name)
+			}
+			found = t
+		}
+	}
+	return found, nil
 }
 
-func loadColumns(tableName, file string) ([]*md.Column, error) {
-	f, err := os.Open(file)
+// tableExtensions are the extensions, in order of preference, of the files
+// that hold a table referred to by name.
+var tableExtensions = []string{".csv", ".csv.gz", ".csv.zst", ".csv.bz2", ".parquet", ".ndjson", ".jsonl", ".json"}
+
+// findTableFile finds the file in dir, or the current directory if dir is
+// empty, holding a table. The file name must match exactly if the table name
+// was quoted, and otherwise it can differ in case.
+func findTableFile(relation *ast.Relation, dir string) (string, error) {
+	for _, ext := range tableExtensions {
+		if _, err := os.Stat(inDir(dir, relation.Name+ext)); err == nil {
+			return inDir(dir, relation.Name+ext), nil
+		}
+	}
+	if dir == "" {
+		dir = "."
+	}
+	files, err := ioutil.ReadDir(dir)
 	if err != nil {
-		return nil, fmt.Errorf("table %q could not be located at %q", tableName, file)
+		return "", err
+	}
+	names := []string{}
+	for _, ext := range tableExtensions {
+		for _, f := range files {
+			if f.IsDir() || !strings.HasSuffix(strings.ToLower(f.Name()), ext) {
+				continue
+			}
+			name := f.Name()[:len(f.Name())-len(ext)]
+			if !relation.Quoted && strings.EqualFold(name, relation.Name) {
+				return inDir(dir, f.Name()), nil
+			}
+			names = append(names, name)
+		}
+	}
+	return "", fmt.Errorf("no table named %q%s", relation.Name, didYouMean(relation.Name, names))
+}
+
+// TableFiles finds the files in dir, or the current directory if dir is
+// empty, that can be queried as tables by name. It returns the file for each
+// table name, picking the same file a query would when there is more than
+// one.
+func TableFiles(dir string) (map[string]string, error) {
+	if dir == "" {
+		dir = "."
+	}
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	result := map[string]string{}
+	for _, ext := range tableExtensions {
+		for _, f := range files {
+			if f.IsDir() || !strings.HasSuffix(strings.ToLower(f.Name()), ext) {
+				continue
+			}
+			name := f.Name()[:len(f.Name())-len(ext)]
+			if _, ok := result[name]; !ok && name != "" {
+				result[name] = inDir(dir, f.Name())
+			}
+		}
+	}
+	return result, nil
+}
+
+// convertTableFunction creates a relation for a table valued function in the
+// FROM clause.
+func convertTableFunction(function *ast.TableFunction, tables map[string]*md.Relation, dir string) (*logical.Source, error) {
+	var relationType md.RelationType
+	switch strings.ToLower(function.Name) {
+	case "read_csv":
+		relationType = md.CsvType
+	case "read_json":
+		relationType = md.JsonType
+	case "read_ndjson":
+		relationType = md.NdjsonType
+	case "read_parquet":
+		relationType = md.ParquetType
+	default:
+		return nil, fmt.Errorf("unknown table function %q", function.Name)
+	}
+	if len(function.Args) != 1 || function.Args[0].Type != ast.StringType {
+		return nil, fmt.Errorf("%s requires a single file path argument", function.Name)
+	}
+
+	var dialect *md.CsvDialect
+	if relationType == md.CsvType {
+		d, err := CsvDialect(function.Options)
+		if err != nil {
+			return nil, err
+		}
+		dialect = d
+	} else if len(function.Options) > 0 {
+		return nil, fmt.Errorf("unknown option %q", function.Options[0].Name)
+	}
+	return convertFile(inDir(dir, function.Args[0].Value.(string)), relationType, dialect, tables)
+}
+
+// convertFile creates a relation for a file, or a glob of CSV files,
+// referenced by path. The relation is named after the file, without
+// directory or extension, or after the pattern for a glob, and made unique
+// among the tables in the catalog.
+func convertFile(source string, relationType md.RelationType, dialect *md.CsvDialect, tables map[string]*md.Relation) (*logical.Source, error) {
+	name := filepath.Base(source)
+	if source == spool.StdinName {
+		if relationType == md.ParquetType {
+			return nil, fmt.Errorf("parquet files can't be read from stdin")
+		}
+		name = stdinTable
+	}
+	if csvfile.IsGlob(name) {
+		if relationType != md.CsvType {
+			return nil, fmt.Errorf("file patterns are only supported for CSV files, not %q", source)
+		}
+		name = globName(source)
+	}
+	if i := strings.Index(name, "."); i > 0 {
+		name = name[:i]
+	}
+	t, err := NewRelation(name, source, relationType, dialect)
+	if err != nil {
+		return nil, err
+	}
+	t.Name = uniqueName(t, tables)
+	for _, c := range t.Columns {
+		c.Qualifier = t.Name
+	}
+	tables[t.Name] = t
+	return &logical.Source{Name: t.Name, Relation: t}, nil
+}
+
+var (
+	// wildcards are the parts of a glob that match file names.
+	wildcards = regexp.MustCompile(`\*|\?|\[[^\]]*\]`)
+	// plainName is a name that can be used in a query without quotes.
+	plainName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+)
+
+// globName names the relation for a glob after the file names it matches,
+// without the wildcards, so sales_*.csv is sales. When only wildcards are
+// left, as in logs/*.csv, it is named after the directory, if that can be
+// used in a query without quotes, and otherwise is called glob.
+func globName(source string) string {
+	name := wildcards.ReplaceAllString(filepath.Base(source), "")
+	if i := strings.Index(name, "."); i >= 0 {
+		name = name[:i]
+	}
+	if name = strings.Trim(name, "_- "); name != "" {
+		return name
+	}
+	if dir := filepath.Base(filepath.Dir(source)); plainName.MatchString(dir) {
+		return dir
+	}
+	return "glob"
+}
+
+// uniqueName returns the name of a relation for a file, with a number added,
+// as in x_2, if a table for a different file, or the same file read another
+// way, already has the name. The same file read the same way keeps the name
+// it was given first.
+func uniqueName(t *md.Relation, tables map[string]*md.Relation) string {
+	name := t.Name
+	for i := 2; ; i++ {
+		existing, err := findTable(&ast.Relation{Name: name}, tables)
+		if err == nil && existing == nil {
+			return name
+		}
+		if existing != nil && sameFile(existing, t) {
+			return existing.Name
+		}
+		name = fmt.Sprintf("%s_%d", t.Name, i)
 	}
-	defer f.Close()
+}
+
+// sameFile checks whether two relations read the same file in the same way.
+func sameFile(a, b *md.Relation) bool {
+	if a.Source != b.Source || a.Type != b.Type {
+		return false
+	}
+	if a.Type != md.CsvType {
+		return true
+	}
+	da, db := a.Dialect, b.Dialect
+	if da == nil {
+		da = md.NewCsvDialect()
+	}
+	if db == nil {
+		db = md.NewCsvDialect()
+	}
+	return *da == *db
+}
 
-	reader := csv.NewReader(f)
-	columnNames, err := reader.Read()
+// NewRelation creates a relation for a table stored in a file, and reads its
+// columns. dialect is only used for CSV files, and can be nil for the
+// default.
+func NewRelation(name, source string, relationType md.RelationType, dialect *md.CsvDialect) (*md.Relation, error) {
+	t := &md.Relation{
+		Name:    name,
+		Type:    relationType,
+		Source:  source,
+		Dialect: dialect,
+	}
+	columns, err := loadColumns(t)
 	if err != nil {
-		return nil, fmt.Errorf("unable to read columns for table %q at %q", tableName, file)
+		return nil, err
+	}
+	t.Columns = columns
+	return t, nil
+}
+
+// CsvDialect builds the CSV dialect described by the options of read_csv,
+// or COPY.
+func CsvDialect(options []*ast.Option) (*md.CsvDialect, error) {
+	dialect := md.NewCsvDialect()
+	for _, o := range options {
+		switch strings.ToLower(o.Name) {
+		case "delim", "delimiter":
+			r, err := singleCharacter(o)
+			if err != nil {
+				return nil, err
+			}
+			dialect.Delimiter = r
+		case "quote":
+			r, err := singleCharacter(o)
+			if err != nil {
+				return nil, err
+			}
+			dialect.Quote = r
+		case "comment":
+			r, err := singleCharacter(o)
+			if err != nil {
+				return nil, err
+			}
+			dialect.Comment = r
+		case "header":
+			if o.Value.Type != ast.BooleanType {
+				return nil, fmt.Errorf("option %q must be true or false, found %s", o.Name, o.Value.Raw)
+			}
+			dialect.Header = o.Value.Value.(bool)
+		case "encoding":
+			if o.Value.Type != ast.StringType {
+				return nil, fmt.Errorf("option %q must be a string, found %s", o.Name, o.Value.Raw)
+			}
+			switch strings.ToLower(o.Value.Value.(string)) {
+			case "utf-8", "utf8":
+				dialect.Encoding = md.Utf8Encoding
+			case "latin1", "latin-1", "iso-8859-1":
+				dialect.Encoding = md.Latin1Encoding
+			default:
+				return nil, fmt.Errorf("unsupported encoding %s", o.Value.Raw)
+			}
+		default:
+			return nil, fmt.Errorf("unknown option %q", o.Name)
+		}
+	}
+	return dialect, nil
+}
+
+func singleCharacter(o *ast.Option) (rune, error) {
+	if o.Value.Type == ast.StringType {
+		if r := []rune(o.Value.Value.(string)); len(r) == 1 {
+			return r[0], nil
+		}
+	}
+	return 0, fmt.Errorf("option %q must be a single character, found %s", o.Name, o.Value.Raw)
+}
+
+func loadColumns(t *md.Relation) ([]*md.Column, error) {
+	switch t.Type {
+	case md.JsonType, md.NdjsonType:
+		return loadJsonColumns(t.Name, t.Source, t.Type)
+	case md.ParquetType:
+		return loadParquetColumns(t.Name, t.Source)
+	}
+	return loadCsvColumns(t.Name, t.Source, t.Dialect)
+}
+
+func loadCsvColumns(tableName, file string, dialect *md.CsvDialect) ([]*md.Column, error) {
+	glob := csvfile.IsGlob(file)
+	if glob {
+		files, err := csvfile.Glob(file)
+		if err != nil {
+			return nil, fmt.Errorf("table %q could not be located: %v", tableName, err)
+		}
+		file = files[0]
+	}
+
+	reader, err := csvfile.Open(file, dialect)
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("table %q could not be located at %q", tableName, file)
+	} else if err != nil {
+		return nil, fmt.Errorf("unable to read columns for table %q at %q: %v", tableName, file, err)
+	}
+	defer reader.Close()
+
+	var columns []*md.Column
+	for _, cn := range reader.Header() {
+		columns = append(columns, &md.Column{
+			Qualifier: tableName,
+			Name:      cn,
+			Type:      md.StringType,
+		})
+	}
+	if glob {
+		columns = append(columns, &md.Column{
+			Qualifier: tableName,
+			Name:      csvfile.FilenameColumn,
+			Type:      md.StringType,
+		})
+	}
+	return columns, nil
+}
+
+func loadJsonColumns(tableName, file string, relationType md.RelationType) ([]*md.Column, error) {
+	reader, err := jsonfile.Open(file, relationType)
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("table %q could not be located at %q", tableName, file)
+	} else if err != nil {
+		return nil, fmt.Errorf("unable to read columns for table %q at %q: %v", tableName, file, err)
 	}
+	defer reader.Close()
 
 	var columns []*md.Column
-	for _, cn := range columnNames {
+	for _, cn := range reader.Header() {
 		columns = append(columns, &md.Column{
 			Qualifier: tableName,
 			Name:      cn,
@@ -120,3 +680,23 @@ func loadColumns(tableName, file string) ([]*md.Column, error) {
 	}
 	return columns, nil
 }
+
+func loadParquetColumns(tableName, file string) ([]*md.Column, error) {
+	reader, err := parquetfile.Open(file, nil, nil)
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("table %q could not be located at %q", tableName, file)
+	} else if err != nil {
+		return nil, fmt.Errorf("unable to read columns for table %q at %q: %v", tableName, file, err)
+	}
+	defer reader.Close()
+
+	var columns []*md.Column
+	for i, cn := range reader.Header() {
+		columns = append(columns, &md.Column{
+			Qualifier: tableName,
+			Name:      cn,
+			Type:      reader.Types()[i],
+		})
+	}
+	return columns, nil
+}
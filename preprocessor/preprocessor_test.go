@@ -33,6 +33,38 @@ func TestConvert(t *testing.T) {
 			},
 			expected: &logical.Source{Name: "this", Relation: &md.Relation{Name: "this"}},
 		},
+		{
+			name: "table names ignore case",
+			query: &ast.SFW{
+				From: &ast.Relation{Name: "THIS"},
+			},
+			tables: map[string]*md.Relation{
+				"this": &md.Relation{Name: "this"},
+			},
+			expected: &logical.Source{Name: "this", Relation: &md.Relation{Name: "this"}},
+		},
+		{
+			name: "exact table name is preferred",
+			query: &ast.SFW{
+				From: &ast.Relation{Name: "This"},
+			},
+			tables: map[string]*md.Relation{
+				"this": &md.Relation{Name: "this"},
+				"This": &md.Relation{Name: "This"},
+			},
+			expected: &logical.Source{Name: "This", Relation: &md.Relation{Name: "This"}},
+		},
+		{
+			name: "table names differing only in case are ambiguous",
+			query: &ast.SFW{
+				From: &ast.Relation{Name: "THIS"},
+			},
+			tables: map[string]*md.Relation{
+				"this": &md.Relation{Name: "this"},
+				"This": &md.Relation{Name: "This"},
+			},
+			err: fmt.Errorf(`too many matching tables "THIS"`),
+		},
 		{
 			name: "only select clause",
 			query: &ast.SFW{
@@ -88,8 +120,8 @@ func TestConvert(t *testing.T) {
 					Type:   md.CsvType,
 					Source: "this",
 					Columns: []*md.Column{
-						{Name: "id", Type: md.StringType},
-						{Name: "name", Type: md.StringType},
+						{Qualifier: "this", Name: "id", Type: md.StringType},
+						{Qualifier: "this", Name: "name", Type: md.StringType},
 					},
 				},
 				"that": &md.Relation{
@@ -97,33 +129,124 @@ func TestConvert(t *testing.T) {
 					Type:   md.CsvType,
 					Source: "that",
 					Columns: []*md.Column{
-						{Name: "id", Type: md.StringType},
+						{Qualifier: "that", Name: "id", Type: md.StringType},
 					},
 				},
 			},
 			expected: logical.NewProjection(
 				logical.NewSelection(
 					&logical.Product{
-						LHS: &logical.Source{"this", &md.Relation{
+						LHS: &logical.Source{Name: "this", Relation: &md.Relation{
 							Name:   "this",
 							Type:   md.CsvType,
 							Source: "this",
 							Columns: []*md.Column{
-								{Name: "id", Type: md.StringType},
-								{Name: "name", Type: md.StringType},
+								{Qualifier: "this", Name: "id", Type: md.StringType},
+								{Qualifier: "this", Name: "name", Type: md.StringType},
 							}}},
-						RHS: &logical.Source{"that", &md.Relation{
+						RHS: &logical.Source{Name: "that", Relation: &md.Relation{
 							Name:   "that",
 							Type:   md.CsvType,
 							Source: "that",
 							Columns: []*md.Column{
-								{Name: "id", Type: md.StringType},
+								{Qualifier: "that", Name: "id", Type: md.StringType},
 							}}},
 					},
-					nil,
+					&logical.EqualColumns{
+						Left:  &md.Column{Qualifier: "this", Name: "id", Type: md.StringType},
+						Right: &md.Column{Qualifier: "that", Name: "id", Type: md.StringType},
+					},
+				),
+				[]*md.Column{
+					{Qualifier: "this", Name: "name", Type: md.StringType},
+				},
+			),
+		},
+		{
+			name: "where clause",
+			query: &ast.SFW{
+				SelList: &ast.SelList{
+					Attributes: []*ast.Attribute{
+						{Name: "name"},
+					},
+				},
+				From: &ast.Relation{Name: "this"},
+				Where: &ast.EqualCondition{
+					LHS: &ast.Attribute{Name: "id"},
+					RHS: &ast.Constant{Type: ast.IntegerType, Value: 3, Raw: "3"},
+				},
+			},
+			tables: map[string]*md.Relation{
+				"this": &md.Relation{
+					Name: "this",
+					Columns: []*md.Column{
+						{Qualifier: "this", Name: "id"},
+						{Qualifier: "this", Name: "name"},
+					},
+				},
+			},
+			expected: logical.NewProjection(
+				logical.NewSelection(
+					&logical.Source{Name: "this", Relation: &md.Relation{
+						Name: "this",
+						Columns: []*md.Column{
+							{Qualifier: "this", Name: "id"},
+							{Qualifier: "this", Name: "name"},
+						}}},
+					&logical.EqualConstant{
+						Column: &md.Column{Qualifier: "this", Name: "id"},
+						Value:  &ast.Constant{Type: ast.IntegerType, Value: 3, Raw: "3"},
+					},
 				),
 				[]*md.Column{
-					{Name: "name", Type: md.StringType},
+					{Qualifier: "this", Name: "name"},
+				},
+			),
+		},
+		{
+			name: "star exclude and replace",
+			query: &ast.SFW{
+				SelList: &ast.SelList{
+					Attributes: []*ast.Attribute{
+						{
+							Name:    "*",
+							Exclude: []*ast.Attribute{{Name: "SSN"}},
+							Replace: []*ast.Replacement{{
+								Expression: &ast.FunctionCall{Name: "UPPER", Args: []ast.Expression{&ast.Attribute{Name: "name"}}},
+								Name:       "name",
+							}},
+						},
+					},
+				},
+				From: &ast.Relation{Name: "this"},
+			},
+			tables: map[string]*md.Relation{
+				"this": &md.Relation{
+					Name: "this",
+					Columns: []*md.Column{
+						{Qualifier: "this", Name: "id"},
+						{Qualifier: "this", Name: "ssn"},
+						{Qualifier: "this", Name: "name"},
+					},
+				},
+			},
+			expected: logical.NewComputedProjection(
+				&logical.Source{Name: "this", Relation: &md.Relation{
+					Name: "this",
+					Columns: []*md.Column{
+						{Qualifier: "this", Name: "id"},
+						{Qualifier: "this", Name: "ssn"},
+						{Qualifier: "this", Name: "name"},
+					}}},
+				[]*md.Column{
+					{Qualifier: "this", Name: "id"},
+					{Qualifier: "this", Name: "name", Type: md.StringType},
+				},
+				[]logical.Expression{
+					nil,
+					&logical.FunctionCall{Name: "UPPER", Args: []logical.Expression{
+						&logical.ColumnValue{Column: &md.Column{Qualifier: "this", Name: "name"}},
+					}},
 				},
 			),
 		},
@@ -140,3 +263,88 @@ func TestConvert(t *testing.T) {
 		})
 	}
 }
+
+func TestConvertReadCsv(t *testing.T) {
+	assert := assert.New(t)
+
+	tables := map[string]*md.Relation{}
+	op, err := Convert(&ast.SFW{
+		From: &ast.Relation{
+			Function: &ast.TableFunction{
+				Name: "read_csv",
+				Args: []*ast.Constant{{Type: ast.StringType, Value: "testdata/cities.txt"}},
+				Options: []*ast.Option{
+					{Name: "delim", Value: &ast.Constant{Type: ast.StringType, Value: "|"}},
+					{Name: "header", Value: &ast.Constant{Type: ast.BooleanType, Value: false}},
+				},
+			},
+		},
+	}, tables)
+
+	assert.Nil(err)
+	dialect := md.NewCsvDialect()
+	dialect.Delimiter = '|'
+	dialect.Header = false
+	expected := &md.Relation{
+		Name:    "cities",
+		Type:    md.CsvType,
+		Source:  "testdata/cities.txt",
+		Dialect: dialect,
+		Columns: []*md.Column{
+			{Qualifier: "cities", Name: "c1", Type: md.StringType},
+			{Qualifier: "cities", Name: "c2", Type: md.StringType},
+		},
+	}
+	assert.Equal(&logical.Source{Name: "cities", Relation: expected}, op)
+	assert.Equal(expected, tables["cities"])
+}
+
+func TestConvertFileNames(t *testing.T) {
+	assert := assert.New(t)
+	cities := &md.Relation{Name: "cities", Type: md.CsvType, Source: "elsewhere/cities.csv"}
+	tables := map[string]*md.Relation{"cities": cities}
+	readCsv := func(options ...*ast.Option) *ast.SFW {
+		return &ast.SFW{From: &ast.Relation{Function: &ast.TableFunction{
+			Name:    "read_csv",
+			Args:    []*ast.Constant{{Type: ast.StringType, Value: "testdata/cities.txt"}},
+			Options: options,
+		}}}
+	}
+	pipe := &ast.Option{Name: "delim", Value: &ast.Constant{Type: ast.StringType, Value: "|"}}
+
+	tests := []struct {
+		name     string
+		query    *ast.SFW
+		expected string
+	}{
+		{"a table already has the name", readCsv(pipe), "cities_2"},
+		{"the same file keeps its name", readCsv(pipe), "cities_2"},
+		{"the same file read another way", readCsv(), "cities_3"},
+	}
+	for _, test := range tests {
+		op, err := Convert(test.query, tables)
+		if assert.Nil(err, test.name) {
+			source := op.(*logical.Source)
+			assert.Equal(test.expected, source.Name, test.name)
+			assert.Equal(test.expected, source.Relation.Columns[0].Qualifier, test.name)
+		}
+	}
+	assert.Equal(cities, tables["cities"])
+	assert.Len(tables, 3)
+}
+
+func TestGlobName(t *testing.T) {
+	tests := []struct {
+		glob     string
+		expected string
+	}{
+		{"data/sales_*.csv", "sales"},
+		{"data/2024/sales-[0-9]?.csv", "sales"},
+		{"logs/*.csv", "logs"},
+		{"data/2024/*.csv", "glob"},
+		{"*.csv", "glob"},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.expected, globName(test.glob), test.glob)
+	}
+}
@@ -0,0 +1,198 @@
+package preprocessor
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jacobsimpson/mtsql/ast"
+	"github.com/jacobsimpson/mtsql/csvfile"
+	"github.com/jacobsimpson/mtsql/index"
+	md "github.com/jacobsimpson/mtsql/metadata"
+	"github.com/jacobsimpson/mtsql/spool"
+)
+
+// The system tables describe the tables that can be queried, and how mtsql is
+// set up, so they can be queried with SQL like any other table.
+const (
+	TablesTable   = "mtsql_tables"
+	ColumnsTable  = "mtsql_columns"
+	IndexesTable  = "mtsql_indexes"
+	SettingsTable = "mtsql_settings"
+)
+
+// systemTables are the columns of each system table.
+var systemTables = map[string][]*md.Column{
+	TablesTable: systemColumns(TablesTable,
+		"name", md.StringType,
+		"type", md.StringType,
+		"source", md.StringType,
+		"columns", md.IntegerType),
+	ColumnsTable: systemColumns(ColumnsTable,
+		"table_name", md.StringType,
+		"column_name", md.StringType,
+		"position", md.IntegerType,
+		"type", md.StringType),
+	IndexesTable: systemColumns(IndexesTable,
+		"table_name", md.StringType,
+		"index_name", md.StringType,
+		"column_name", md.StringType,
+		"path", md.StringType,
+		"rows", md.IntegerType,
+		"fresh", md.BooleanType),
+	SettingsTable: systemColumns(SettingsTable,
+		"name", md.StringType,
+		"value", md.StringType,
+		"description", md.StringType),
+}
+
+// systemColumns makes the columns of a system table from pairs of names and
+// types.
+func systemColumns(table string, namesAndTypes ...interface{}) []*md.Column {
+	columns := []*md.Column{}
+	for i := 0; i < len(namesAndTypes); i += 2 {
+		columns = append(columns, &md.Column{
+			Qualifier: table,
+			Name:      namesAndTypes[i].(string),
+			Type:      namesAndTypes[i+1].(md.ColumnType),
+		})
+	}
+	return columns
+}
+
+// SystemTables lists the names of the system tables.
+func SystemTables() []string {
+	names := []string{}
+	for name := range systemTables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Setting is a row of the mtsql_settings table.
+type Setting struct {
+	Name        string
+	Value       string
+	Description string
+}
+
+// NewSettings creates the mtsql_settings table, for the catalog to hold.
+func NewSettings(settings []Setting) *md.Relation {
+	rows := [][]string{}
+	for _, s := range settings {
+		rows = append(rows, []string{s.Name, s.Value, s.Description})
+	}
+	return &md.Relation{
+		Name:    SettingsTable,
+		Type:    md.VirtualType,
+		Columns: systemTables[SettingsTable],
+		Rows:    rows,
+	}
+}
+
+// systemTable creates the system table a name refers to, describing the
+// catalog and the files in dir as they are now, or returns nil if the name
+// isn't a system table. mtsql_settings comes from the catalog, and is empty
+// if it hasn't been put there.
+func systemTable(relation *ast.Relation, tables map[string]*md.Relation, dir string) (*md.Relation, error) {
+	name := strings.ToLower(relation.Name)
+	if _, ok := systemTables[name]; !ok || (relation.Quoted && name != relation.Name) {
+		return nil, nil
+	}
+	if name == SettingsTable {
+		if t := tables[SettingsTable]; t != nil {
+			return t, nil
+		}
+		return NewSettings(nil), nil
+	}
+
+	catalog, err := listTables(tables, dir)
+	if err != nil {
+		return nil, err
+	}
+	rows := [][]string{}
+	for _, t := range catalog {
+		switch name {
+		case TablesTable:
+			columns := ""
+			if t.Columns != nil {
+				columns = strconv.Itoa(len(t.Columns))
+			}
+			rows = append(rows, []string{t.Name, string(t.Type), t.Source, columns})
+		case ColumnsTable:
+			for i, c := range t.Columns {
+				rows = append(rows, []string{t.Name, c.Name, strconv.Itoa(i + 1), string(c.Type)})
+			}
+		case IndexesTable:
+			if t.Type != md.CsvType || csvfile.IsGlob(t.Source) || t.Source == spool.StdinName {
+				continue
+			}
+			indexes, err := index.List(t.Source)
+			if err != nil {
+				return nil, err
+			}
+			for _, i := range indexes {
+				rows = append(rows, []string{
+					t.Name, i.Name, i.Column, i.Path,
+					strconv.FormatInt(i.Rows, 10),
+					strconv.FormatBool(i.Fresh()),
+				})
+			}
+		}
+	}
+	return &md.Relation{
+		Name:    name,
+		Type:    md.VirtualType,
+		Columns: systemTables[name],
+		Rows:    rows,
+	}, nil
+}
+
+// Shadowed checks whether a table in the catalog, or one of files, as found
+// by TableFiles, takes the place of the system table name in queries.
+func Shadowed(name string, tables map[string]*md.Relation, files map[string]string) bool {
+	for n := range tables {
+		if strings.EqualFold(n, name) {
+			return true
+		}
+	}
+	for n := range files {
+		if strings.EqualFold(n, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// listTables lists the tables in the catalog, the files in dir that can be
+// queried by name, and the system tables that no table or file takes the
+// place of, in order of their names. Files that can't be read are listed
+// without columns.
+func listTables(tables map[string]*md.Relation, dir string) ([]*md.Relation, error) {
+	files, err := TableFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+	result := []*md.Relation{}
+	for _, t := range tables {
+		result = append(result, t)
+	}
+	for name, file := range files {
+		if t, err := findTable(&ast.Relation{Name: name}, tables); t != nil || err != nil {
+			continue
+		}
+		t, err := NewRelation(name, file, RelationType(file), nil)
+		if err != nil {
+			t = &md.Relation{Name: name, Type: RelationType(file), Source: file}
+		}
+		result = append(result, t)
+	}
+	for _, name := range SystemTables() {
+		if !Shadowed(name, tables, files) {
+			result = append(result, &md.Relation{Name: name, Type: md.VirtualType, Columns: systemTables[name]})
+		}
+	}
+	sort.Slice(result, func(a, b int) bool { return result[a].Name < result[b].Name })
+	return result, nil
+}
@@ -0,0 +1,147 @@
+package preprocessor
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jacobsimpson/mtsql/ast"
+	"github.com/jacobsimpson/mtsql/index"
+	"github.com/jacobsimpson/mtsql/logical"
+	md "github.com/jacobsimpson/mtsql/metadata"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSystemTables(t *testing.T) {
+	dir, err := ioutil.TempDir("", "mtsql-system-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	people := filepath.Join(dir, "people.csv")
+	if err := ioutil.WriteFile(people, []byte("name,age\nann,31\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(people)
+	if err != nil {
+		t.Fatal(err)
+	}
+	builder := index.NewBuilder("name")
+	builder.Add("ann", 9)
+	if _, err := builder.Write(people, "by_name", info); err != nil {
+		t.Fatal(err)
+	}
+	tables := map[string]*md.Relation{
+		"wa": {Name: "wa", Type: md.CsvType, Source: "wa.csv", Columns: []*md.Column{
+			{Qualifier: "wa", Name: "City", Type: md.StringType},
+		}},
+		SettingsTable: NewSettings([]Setting{{Name: "threads", Value: "4", Description: "threads"}}),
+	}
+
+	tests := []struct {
+		name     string
+		relation *ast.Relation
+		rows     [][]string
+	}{
+		{
+			name:     "tables",
+			relation: &ast.Relation{Name: "mtsql_tables"},
+			rows: [][]string{
+				{"mtsql_columns", "virtual", "", "4"},
+				{"mtsql_indexes", "virtual", "", "6"},
+				{"mtsql_settings", "virtual", "", "3"},
+				{"mtsql_tables", "virtual", "", "4"},
+				{"people", "csv", people, "2"},
+				{"wa", "csv", "wa.csv", "1"},
+			},
+		},
+		{
+			name:     "columns ignoring case",
+			relation: &ast.Relation{Name: "MTSQL_COLUMNS"},
+			rows: [][]string{
+				{"mtsql_columns", "table_name", "1", "string"},
+				{"mtsql_columns", "column_name", "2", "string"},
+				{"mtsql_columns", "position", "3", "integer"},
+				{"mtsql_columns", "type", "4", "string"},
+				{"mtsql_indexes", "table_name", "1", "string"},
+				{"mtsql_indexes", "index_name", "2", "string"},
+				{"mtsql_indexes", "column_name", "3", "string"},
+				{"mtsql_indexes", "path", "4", "string"},
+				{"mtsql_indexes", "rows", "5", "integer"},
+				{"mtsql_indexes", "fresh", "6", "boolean"},
+				{"mtsql_settings", "name", "1", "string"},
+				{"mtsql_settings", "value", "2", "string"},
+				{"mtsql_settings", "description", "3", "string"},
+				{"mtsql_tables", "name", "1", "string"},
+				{"mtsql_tables", "type", "2", "string"},
+				{"mtsql_tables", "source", "3", "string"},
+				{"mtsql_tables", "columns", "4", "integer"},
+				{"people", "name", "1", "string"},
+				{"people", "age", "2", "string"},
+				{"wa", "City", "1", "string"},
+			},
+		},
+		{
+			name:     "indexes",
+			relation: &ast.Relation{Name: "mtsql_indexes"},
+			rows: [][]string{
+				{"people", "by_name", "name", index.Path(people, "by_name"), "1", "true"},
+			},
+		},
+		{
+			name:     "settings",
+			relation: &ast.Relation{Name: "mtsql_settings"},
+			rows:     [][]string{{"threads", "4", "threads"}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			op, err := ConvertIn(&ast.SFW{From: test.relation}, tables, dir)
+
+			assert.Nil(err)
+			if source, ok := op.(*logical.Source); assert.True(ok) {
+				assert.Equal(md.VirtualType, source.Relation.Type)
+				assert.Equal(test.rows, source.Relation.Rows)
+				// Only the settings the caller put there are in the catalog.
+				assert.Len(tables, 2)
+			}
+		})
+	}
+
+	_, err = ConvertIn(&ast.SFW{From: &ast.Relation{Name: "MTSQL_TABLES", Quoted: true}}, tables, dir)
+	assert.NotNil(t, err)
+}
+
+func TestSystemTableShadowedByFile(t *testing.T) {
+	assert := assert.New(t)
+	dir, err := ioutil.TempDir("", "mtsql-system-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "mtsql_tables.csv")
+	if err := ioutil.WriteFile(file, []byte("id\n1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	tables := map[string]*md.Relation{}
+
+	// The file is queried by name, rather than the system table.
+	op, err := ConvertIn(&ast.SFW{From: &ast.Relation{Name: "mtsql_tables"}}, tables, dir)
+	assert.Nil(err)
+	if source, ok := op.(*logical.Source); assert.True(ok) {
+		assert.Equal(md.CsvType, source.Relation.Type)
+		assert.Equal(file, source.Relation.Source)
+	}
+
+	// And the other system tables list it in place of the system table.
+	op, err = ConvertIn(&ast.SFW{From: &ast.Relation{Name: "mtsql_columns"}}, map[string]*md.Relation{}, dir)
+	assert.Nil(err)
+	if source, ok := op.(*logical.Source); assert.True(ok) {
+		assert.Contains(source.Relation.Rows, []string{"mtsql_tables", "id", "1", "string"})
+		assert.NotContains(source.Relation.Rows, []string{"mtsql_tables", "name", "1", "string"})
+	}
+}
@@ -0,0 +1,166 @@
+// Package schema works out the types of the columns of a table from the
+// values in them, since CSV files only name their columns. Every row is read,
+// so the schema also counts the empty values in each column, and keeps a few
+// samples of the others.
+package schema
+
+import (
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	md "github.com/jacobsimpson/mtsql/metadata"
+	"github.com/jacobsimpson/mtsql/physical"
+)
+
+// Samples is the number of distinct values kept from each column.
+const Samples = 3
+
+// Schema describes the columns of a table.
+type Schema struct {
+	Rows    int64
+	Columns []*Column
+}
+
+// Column is what was found in a column of a table. Nulls counts the empty
+// values, which don't count against any type.
+type Column struct {
+	Name    string
+	Type    md.ColumnType
+	Nulls   int64
+	Samples []string
+}
+
+// types are the types a column can be inferred to have, from the most to the
+// least specific. A column is the first type all of its values are.
+var types = []struct {
+	columnType md.ColumnType
+	is         func(string) bool
+}{
+	{md.IntegerType, isInteger},
+	{md.FloatType, isFloat},
+	{md.BooleanType, isBoolean},
+	{md.DateType, isDate},
+	{md.TimestampType, isTimestamp},
+}
+
+// timestampLayouts are the formats of the values of timestamp columns. Dates
+// are midnight, so a column of dates and timestamps is a timestamp column.
+var timestampLayouts = []string{
+	"2006-01-02",
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04:05Z07:00",
+}
+
+func isInteger(s string) bool {
+	_, err := strconv.ParseInt(s, 10, 64)
+	return err == nil
+}
+
+// isFloat checks for a number, but not the infinities and NaN ParseFloat
+// also reads, which are more likely to be words.
+func isFloat(s string) bool {
+	if !strings.ContainsAny(s[:1], "0123456789+-.") || strings.ContainsAny(s, "nN") {
+		return false
+	}
+	_, err := strconv.ParseFloat(s, 64)
+	return err == nil
+}
+
+func isBoolean(s string) bool {
+	return strings.EqualFold(s, "true") || strings.EqualFold(s, "false")
+}
+
+func isDate(s string) bool {
+	_, err := time.Parse("2006-01-02", s)
+	return err == nil
+}
+
+func isTimestamp(s string) bool {
+	for _, layout := range timestampLayouts {
+		if _, err := time.Parse(layout, s); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// Infer reads every row of a table, and works out the schema of its columns.
+// The rowReader is opened and closed.
+func Infer(rowReader physical.RowReader) (*Schema, error) {
+	if err := rowReader.Open(); err != nil {
+		return nil, err
+	}
+	defer rowReader.Close()
+
+	s := &Schema{}
+	// possible holds, for each column, whether each of the types could still
+	// be its type, and seen whether it has had a value that isn't empty.
+	possible := [][]bool{}
+	seen := []bool{}
+	for _, c := range rowReader.Columns() {
+		s.Columns = append(s.Columns, &Column{Name: c.Name, Samples: []string{}})
+		p := make([]bool, len(types))
+		for i := range p {
+			p[i] = true
+		}
+		possible = append(possible, p)
+		seen = append(seen, false)
+	}
+
+	for {
+		row, err := rowReader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		s.Rows++
+		for i, v := range row {
+			if i >= len(s.Columns) {
+				break
+			}
+			c := s.Columns[i]
+			if v == "" {
+				c.Nulls++
+				continue
+			}
+			seen[i] = true
+			for t := range types {
+				if possible[i][t] && !types[t].is(v) {
+					possible[i][t] = false
+				}
+			}
+			if len(c.Samples) < Samples && !contains(c.Samples, v) {
+				c.Samples = append(c.Samples, v)
+			}
+		}
+	}
+
+	for i, c := range s.Columns {
+		c.Type = md.StringType
+		if !seen[i] {
+			continue
+		}
+		for t := range types {
+			if possible[i][t] {
+				c.Type = types[t].columnType
+				break
+			}
+		}
+	}
+	return s, nil
+}
+
+func contains(values []string, v string) bool {
+	for _, s := range values {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,56 @@
+package schema
+
+import (
+	"testing"
+
+	md "github.com/jacobsimpson/mtsql/metadata"
+	"github.com/jacobsimpson/mtsql/physical"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInfer(t *testing.T) {
+	tests := []struct {
+		name     string
+		values   []string
+		expected md.ColumnType
+	}{
+		{"integers", []string{"1", "-20", "300"}, md.IntegerType},
+		{"floats", []string{"1", "2.5", "-1e3"}, md.FloatType},
+		{"booleans", []string{"true", "FALSE"}, md.BooleanType},
+		{"dates", []string{"2020-01-31", "1999-12-01"}, md.DateType},
+		{"timestamps", []string{"2020-01-31T10:00:00Z", "2020-01-31 10:00:00", "2020-02-01"}, md.TimestampType},
+		{"strings", []string{"1", "x"}, md.StringType},
+		{"words like numbers", []string{"nan", "inf"}, md.StringType},
+		{"empty values are ignored", []string{"", "1", ""}, md.IntegerType},
+		{"all empty", []string{"", ""}, md.StringType},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rows := [][]string{}
+			for _, v := range test.values {
+				rows = append(rows, []string{v})
+			}
+			s, err := Infer(physical.NewMemoryScan([]*md.Column{{Qualifier: "t", Name: "a"}}, rows))
+
+			assert.Nil(t, err)
+			assert.Equal(t, int64(len(test.values)), s.Rows)
+			if assert.Len(t, s.Columns, 1) {
+				assert.Equal(t, "a", s.Columns[0].Name)
+				assert.Equal(t, test.expected, s.Columns[0].Type)
+			}
+		})
+	}
+}
+
+func TestInferNullsAndSamples(t *testing.T) {
+	assert := assert.New(t)
+	rows := [][]string{{"a", ""}, {"b", "1"}, {"a", ""}, {"c", "2"}, {"d", "3"}}
+
+	s, err := Infer(physical.NewMemoryScan([]*md.Column{{Name: "x"}, {Name: "y"}}, rows))
+
+	assert.Nil(err)
+	assert.Equal(&Schema{Rows: 5, Columns: []*Column{
+		{Name: "x", Type: md.StringType, Nulls: 0, Samples: []string{"a", "b", "c"}},
+		{Name: "y", Type: md.IntegerType, Nulls: 2, Samples: []string{"1", "2", "3"}},
+	}}, s)
+}
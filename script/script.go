@@ -0,0 +1,116 @@
+package script
+
+import (
+	"io"
+	"io/ioutil"
+	"strings"
+)
+
+// Statement is one statement from a script, along with the line and column,
+// both starting at 1, where it begins.
+type Statement struct {
+	Text   string
+	Line   int
+	Column int
+}
+
+// Split reads a script and splits it into statements separated by ';'.
+// Semicolons within quoted strings and comments do not end a statement.
+// Comments are replaced with spaces, so positions within the text of a
+// statement still line up with the script. Empty statements are dropped.
+// A statement starting with a backslash is a meta-command, which also ends at
+// the end of its line.
+func Split(r io.Reader) ([]*Statement, error) {
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	input := []rune(string(b))
+
+	result := []*Statement{}
+	var text strings.Builder
+	line, column := 1, 1
+	start := &Statement{Line: line, Column: column}
+	started := false
+	end := func() {
+		if started {
+			start.Text = text.String()
+			result = append(result, start)
+		}
+		text.Reset()
+		started = false
+	}
+	add := func(r rune) {
+		if !started && r != ' ' && r != '\t' && r != '\r' && r != '\n' {
+			started = true
+			start = &Statement{Line: line, Column: column}
+		}
+		if started {
+			text.WriteRune(r)
+		}
+		if r == '\n' {
+			line++
+			column = 1
+		} else {
+			column++
+		}
+	}
+
+	for i := 0; i < len(input); i++ {
+		r := input[i]
+		switch {
+		case r == ';':
+			end()
+			column++
+		case r == '\\' && !started:
+			for ; i < len(input) && input[i] != '\n' && input[i] != ';'; i++ {
+				add(input[i])
+			}
+			end()
+			i--
+		case r == '\'' || r == '"':
+			// Copy the quoted text as is. A doubled quote is an escaped
+			// quote, which works out the same as two strings in a row.
+			add(r)
+			for i++; i < len(input); i++ {
+				add(input[i])
+				if input[i] == r {
+					break
+				}
+			}
+		case r == '-' && i+1 < len(input) && input[i+1] == '-':
+			for ; i < len(input) && input[i] != '\n'; i++ {
+				blank(add, input[i])
+			}
+			if i < len(input) {
+				add('\n')
+			}
+		case r == '/' && i+1 < len(input) && input[i+1] == '*':
+			blank(add, input[i])
+			blank(add, input[i+1])
+			for i += 2; i < len(input); i++ {
+				if input[i] == '*' && i+1 < len(input) && input[i+1] == '/' {
+					blank(add, input[i])
+					blank(add, input[i+1])
+					i++
+					break
+				}
+				blank(add, input[i])
+			}
+		default:
+			add(r)
+		}
+	}
+	end()
+	return result, nil
+}
+
+// blank adds a space in place of a commented out character, keeping line
+// breaks so the lines of the statement are unchanged.
+func blank(add func(rune), r rune) {
+	if r == '\n' {
+		add('\n')
+	} else {
+		add(' ')
+	}
+}
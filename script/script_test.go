@@ -0,0 +1,87 @@
+package script_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jacobsimpson/mtsql/script"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplit(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		result []*script.Statement
+	}{
+		{
+			"single statement without a semicolon",
+			"SELECT a FROM t",
+			[]*script.Statement{{Text: "SELECT a FROM t", Line: 1, Column: 1}},
+		},
+		{
+			"statements on separate lines",
+			"SELECT a FROM t;\n  SELECT b\n  FROM u;\n",
+			[]*script.Statement{
+				{Text: "SELECT a FROM t", Line: 1, Column: 1},
+				{Text: "SELECT b\n  FROM u", Line: 2, Column: 3},
+			},
+		},
+		{
+			"empty statements are dropped",
+			";; SELECT a FROM t; ;",
+			[]*script.Statement{{Text: "SELECT a FROM t", Line: 1, Column: 4}},
+		},
+		{
+			"semicolons in strings",
+			`SELECT a FROM t WHERE a = 'x;y'; SELECT "b;" FROM t`,
+			[]*script.Statement{
+				{Text: "SELECT a FROM t WHERE a = 'x;y'", Line: 1, Column: 1},
+				{Text: `SELECT "b;" FROM t`, Line: 1, Column: 34},
+			},
+		},
+		{
+			"escaped quotes",
+			"SELECT a FROM t WHERE a = 'it''s;'; SELECT b FROM t",
+			[]*script.Statement{
+				{Text: "SELECT a FROM t WHERE a = 'it''s;'", Line: 1, Column: 1},
+				{Text: "SELECT b FROM t", Line: 1, Column: 37},
+			},
+		},
+		{
+			"line comments",
+			"-- report; nightly\nSELECT a -- first;\nFROM t;",
+			[]*script.Statement{{Text: "SELECT a          \nFROM t", Line: 2, Column: 1}},
+		},
+		{
+			"block comments",
+			"/* one;\n two */ SELECT a /*;*/ FROM t",
+			[]*script.Statement{{Text: "SELECT a       FROM t", Line: 2, Column: 9}},
+		},
+		{
+			"meta-commands end at the end of the line",
+			"\\cache\nSELECT a FROM t; \\cache clear;\n",
+			[]*script.Statement{
+				{Text: `\cache`, Line: 1, Column: 1},
+				{Text: "SELECT a FROM t", Line: 2, Column: 1},
+				{Text: `\cache clear`, Line: 2, Column: 18},
+			},
+		},
+		{
+			"only comments",
+			"-- nothing here\n/* or here */",
+			[]*script.Statement{},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			result, err := script.Split(strings.NewReader(test.input))
+
+			assert.NoError(err)
+			assert.Equal(test.result, result)
+		})
+	}
+}
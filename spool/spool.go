@@ -0,0 +1,116 @@
+package spool
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"sync"
+)
+
+// StdinName is the file name that stands for standard input.
+const StdinName = "-"
+
+// Spool streams data from a source that can only be read once, keeping a copy
+// of everything read in a temporary file so it can be read again from the
+// start by any number of readers.
+type Spool struct {
+	source io.Reader
+	file   *os.File
+	size   int64
+	err    error
+}
+
+var (
+	stdin     *Spool
+	stdinOnce sync.Once
+)
+
+// Stdin returns the spool for standard input.
+func Stdin() *Spool {
+	stdinOnce.Do(func() {
+		stdin = New(os.Stdin)
+	})
+	return stdin
+}
+
+// CloseStdin removes the spool for standard input, if it was ever used.
+func CloseStdin() error {
+	if stdin == nil {
+		return nil
+	}
+	return stdin.Close()
+}
+
+// New creates a spool that reads from source as its readers need more data.
+func New(source io.Reader) *Spool {
+	return &Spool{source: source}
+}
+
+// NewReader returns a reader that starts at the beginning of the data.
+func (s *Spool) NewReader() io.ReadCloser {
+	return &reader{spool: s}
+}
+
+// Close removes the temporary file.
+func (s *Spool) Close() error {
+	if s.file == nil {
+		return nil
+	}
+	s.file.Close()
+	err := os.Remove(s.file.Name())
+	s.file = nil
+	return err
+}
+
+// fill reads the next chunk of the source into the temporary file.
+func (s *Spool) fill() error {
+	if s.err != nil {
+		return s.err
+	}
+	if s.file == nil {
+		f, err := ioutil.TempFile("", "mtsql-spool-")
+		if err != nil {
+			return err
+		}
+		s.file = f
+	}
+	buf := make([]byte, 64*1024)
+	n, err := s.source.Read(buf)
+	if n > 0 {
+		if _, werr := s.file.WriteAt(buf[:n], s.size); werr != nil {
+			return werr
+		}
+		s.size += int64(n)
+	}
+	if err != nil {
+		s.err = err
+		if n == 0 {
+			return err
+		}
+	}
+	return nil
+}
+
+type reader struct {
+	spool  *Spool
+	offset int64
+}
+
+func (r *reader) Read(p []byte) (int, error) {
+	for r.offset >= r.spool.size {
+		if err := r.spool.fill(); err != nil {
+			return 0, err
+		}
+	}
+	if remaining := r.spool.size - r.offset; int64(len(p)) > remaining {
+		p = p[:remaining]
+	}
+	n, err := r.spool.file.ReadAt(p, r.offset)
+	r.offset += int64(n)
+	if err == io.EOF && n > 0 {
+		err = nil
+	}
+	return n, err
+}
+
+func (r *reader) Close() error { return nil }
@@ -0,0 +1,55 @@
+package spool_test
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/jacobsimpson/mtsql/spool"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadTwice(t *testing.T) {
+	assert := assert.New(t)
+	s := spool.New(strings.NewReader("a,b\n1,2\n3,4\n"))
+	defer s.Close()
+
+	first, err := ioutil.ReadAll(s.NewReader())
+	assert.NoError(err)
+	assert.Equal("a,b\n1,2\n3,4\n", string(first))
+
+	second, err := ioutil.ReadAll(s.NewReader())
+	assert.NoError(err)
+	assert.Equal("a,b\n1,2\n3,4\n", string(second))
+}
+
+func TestInterleavedReaders(t *testing.T) {
+	assert := assert.New(t)
+	s := spool.New(iotest.OneByteReader(strings.NewReader("abcdef")))
+	defer s.Close()
+
+	r1 := s.NewReader()
+	b := make([]byte, 3)
+	n, err := r1.Read(b)
+	assert.NoError(err)
+	assert.Equal("a", string(b[:n]))
+
+	r2, err := ioutil.ReadAll(s.NewReader())
+	assert.NoError(err)
+	assert.Equal("abcdef", string(r2))
+
+	rest, err := ioutil.ReadAll(r1)
+	assert.NoError(err)
+	assert.Equal("bcdef", string(rest))
+}
+
+func TestEmpty(t *testing.T) {
+	assert := assert.New(t)
+	s := spool.New(strings.NewReader(""))
+	defer s.Close()
+
+	b, err := ioutil.ReadAll(s.NewReader())
+	assert.NoError(err)
+	assert.Empty(b)
+}
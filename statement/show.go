@@ -0,0 +1,163 @@
+package statement
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jacobsimpson/mtsql/ast"
+	"github.com/jacobsimpson/mtsql/cache"
+	"github.com/jacobsimpson/mtsql/logical"
+	md "github.com/jacobsimpson/mtsql/metadata"
+	"github.com/jacobsimpson/mtsql/physical"
+	"github.com/jacobsimpson/mtsql/preprocessor"
+	"github.com/jacobsimpson/mtsql/schema"
+)
+
+// ShowTables lists the tables in the catalog, the files in dir, or the
+// current directory if dir is empty, that can be queried as tables by name,
+// and the system tables no table or file takes the place of.
+func ShowTables(tables map[string]*md.Relation, dir string) (physical.RowReader, error) {
+	files, err := preprocessor.TableFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+	rows := [][]string{}
+	for _, t := range tables {
+		rows = append(rows, []string{t.Name, string(t.Type), t.Source})
+	}
+	for name, file := range files {
+		if findTable(name, tables) == nil {
+			rows = append(rows, []string{name, string(preprocessor.RelationType(file)), file})
+		}
+	}
+	for _, name := range preprocessor.SystemTables() {
+		if !preprocessor.Shadowed(name, tables, files) {
+			rows = append(rows, []string{name, string(md.VirtualType), ""})
+		}
+	}
+	sort.Slice(rows, func(a, b int) bool { return rows[a][0] < rows[b][0] })
+	return physical.NewMemoryScan(showColumns("name", "type", "source"), rows), nil
+}
+
+// findTable finds a table in the catalog, ignoring case, as an unquoted name
+// in a query would.
+func findTable(name string, tables map[string]*md.Relation) *md.Relation {
+	for n, t := range tables {
+		if strings.EqualFold(n, name) {
+			return t
+		}
+	}
+	return nil
+}
+
+func showColumns(names ...string) []*md.Column {
+	columns := []*md.Column{}
+	for _, n := range names {
+		columns = append(columns, &md.Column{Name: n, Type: md.StringType})
+	}
+	return columns
+}
+
+// Describe lists the columns of a table, with the type inferred from the
+// values in each, the number of empty values, and samples of the others. The
+// schema is inferred by reading the whole table, unless it is in the cache,
+// which can be nil.
+func Describe(d *ast.Describe, tables map[string]*md.Relation, c *cache.Cache, execution *physical.Execution) (physical.RowReader, error) {
+	relation, err := preprocessor.FindRelation(d.Table, tables)
+	if err != nil {
+		return nil, err
+	}
+	s, err := inferSchema(relation, tables, c, execution)
+	if err != nil {
+		return nil, err
+	}
+	rows := [][]string{}
+	for _, column := range s.Columns {
+		rows = append(rows, []string{
+			column.Name,
+			string(column.Type),
+			strconv.FormatInt(column.Nulls, 10),
+			strings.Join(column.Samples, ", "),
+		})
+	}
+	return physical.NewMemoryScan(showColumns("column", "type", "nulls", "samples"), rows), nil
+}
+
+// ShowCreateTable describes a table as a CREATE TABLE statement, with the
+// types of its columns inferred as by Describe, and the file and format it is
+// read from as options. The statement is only for display, mtsql can't run
+// it. System tables aren't read from a file, so they can't be described this
+// way.
+func ShowCreateTable(t *ast.ShowCreateTable, tables map[string]*md.Relation, c *cache.Cache, execution *physical.Execution) (string, error) {
+	relation, err := preprocessor.FindRelation(t.Table, tables)
+	if err != nil {
+		return "", err
+	}
+	if relation.Type == md.VirtualType {
+		return "", fmt.Errorf("%s is a system table, it isn't read from a file", relation.Name)
+	}
+	s, err := inferSchema(relation, tables, c, execution)
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "CREATE TABLE %s (\n", quoteIdentifier(relation.Name))
+	for i, column := range s.Columns {
+		separator := ","
+		if i == len(s.Columns)-1 {
+			separator = ""
+		}
+		fmt.Fprintf(&b, "    %s %s%s\n", quoteIdentifier(column.Name), column.Type, separator)
+	}
+	options := []string{"path " + quoteString(relation.Source), "format " + string(relation.Type)}
+	if relation.Type == md.CsvType {
+		dialect := relation.Dialect
+		if dialect == nil {
+			dialect = md.NewCsvDialect()
+		}
+		options = append(options,
+			"delimiter "+quoteString(string(dialect.Delimiter)),
+			"quote "+quoteString(string(dialect.Quote)))
+		if dialect.Comment != 0 {
+			options = append(options, "comment "+quoteString(string(dialect.Comment)))
+		}
+		encoding := dialect.Encoding
+		if encoding == "" {
+			encoding = md.Utf8Encoding
+		}
+		options = append(options,
+			fmt.Sprintf("header %t", dialect.Header),
+			"encoding "+quoteString(string(encoding)))
+	}
+	fmt.Fprintf(&b, ") WITH (%s);", strings.Join(options, ", "))
+	return b.String(), nil
+}
+
+// inferSchema infers the schema of the columns of a table.
+func inferSchema(relation *md.Relation, tables map[string]*md.Relation, c *cache.Cache, execution *physical.Execution) (*schema.Schema, error) {
+	return c.Schema(relation, func() (*schema.Schema, error) {
+		rowReader, err := physical.ConvertWith(&logical.Source{Name: relation.Name, Relation: relation}, tables, execution)
+		if err != nil {
+			return nil, err
+		}
+		return schema.Infer(rowReader)
+	})
+}
+
+var plainIdentifier = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// quoteIdentifier puts an identifier in double quotes, if it needs them to
+// be read back as the same name.
+func quoteIdentifier(name string) string {
+	if plainIdentifier.MatchString(name) {
+		return name
+	}
+	return `"` + strings.Replace(name, `"`, `""`, -1) + `"`
+}
+
+func quoteString(s string) string {
+	return "'" + strings.Replace(s, "'", "''", -1) + "'"
+}
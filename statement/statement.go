@@ -0,0 +1,414 @@
+package statement
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/jacobsimpson/mtsql/ast"
+	"github.com/jacobsimpson/mtsql/csvfile"
+	"github.com/jacobsimpson/mtsql/decompress"
+	"github.com/jacobsimpson/mtsql/formatter"
+	"github.com/jacobsimpson/mtsql/index"
+	md "github.com/jacobsimpson/mtsql/metadata"
+	"github.com/jacobsimpson/mtsql/physical"
+	"github.com/jacobsimpson/mtsql/preprocessor"
+	"github.com/jacobsimpson/mtsql/spool"
+)
+
+// Copy writes the results of a query to a file, and returns the number of
+// rows written. The format is csv, json or ndjson, taken from the format
+// option, or the extension of the file.
+func Copy(c *ast.Copy, tables map[string]*md.Relation, execution *physical.Execution) (int, error) {
+	relationType := preprocessor.RelationType(c.Path)
+	csvOptions := []*ast.Option{}
+	for _, o := range c.Options {
+		if !strings.EqualFold(o.Name, "format") {
+			csvOptions = append(csvOptions, o)
+			continue
+		}
+		switch strings.ToLower(fmt.Sprintf("%v", o.Value.Value)) {
+		case "csv":
+			relationType = md.CsvType
+		case "json":
+			relationType = md.JsonType
+		case "ndjson", "jsonl":
+			relationType = md.NdjsonType
+		default:
+			return 0, fmt.Errorf("unsupported format %s", o.Value.Raw)
+		}
+	}
+	if relationType == md.ParquetType {
+		return 0, fmt.Errorf("COPY can not write parquet files")
+	}
+	if relationType != md.CsvType && len(csvOptions) > 0 {
+		return 0, fmt.Errorf("unknown option %q", csvOptions[0].Name)
+	}
+	dialect, err := preprocessor.CsvDialect(csvOptions)
+	if err != nil {
+		return 0, err
+	}
+
+	rowReader, err := plan(c.Query, tables, execution)
+	if err != nil {
+		return 0, err
+	}
+	defer rowReader.Close()
+
+	var writer formatter.Writer
+	if relationType == md.CsvType {
+		writer = formatter.NewCsvWriter(rowReader, dialect)
+	} else {
+		writer = formatter.NewJsonWriter(rowReader, relationType)
+	}
+	return writeFile(c.Path, writer, 0644)
+}
+
+// CreateTableAs writes the results of a query to a new CSV file named after
+// the table, and adds the table to the catalog.
+func CreateTableAs(c *ast.CreateTableAs, tables map[string]*md.Relation, execution *physical.Execution) (int, error) {
+	name := c.Table.Name
+	source := name + ".csv"
+	if _, err := preprocessor.FindRelation(c.Table, tables); err == nil {
+		return 0, fmt.Errorf("table %q already exists", name)
+	}
+	if _, err := os.Stat(source); err == nil {
+		return 0, fmt.Errorf("table %q already exists", name)
+	}
+
+	rowReader, err := plan(c.Query, tables, execution)
+	if err != nil {
+		return 0, err
+	}
+	defer rowReader.Close()
+
+	count, err := writeFile(source, formatter.NewCsvWriter(rowReader, nil), 0644)
+	if err != nil {
+		return 0, err
+	}
+
+	relation := &md.Relation{
+		Name:   name,
+		Type:   md.CsvType,
+		Source: source,
+	}
+	for _, column := range rowReader.Columns() {
+		relation.Columns = append(relation.Columns, &md.Column{
+			Qualifier: name,
+			Name:      column.Name,
+			Type:      column.Type,
+		})
+	}
+	tables[name] = relation
+	return count, nil
+}
+
+// CreateIndex builds an index on a column of a CSV table, in a file next to
+// the table, and returns the number of rows indexed. An index that has gone
+// stale, because the table changed after it was built, is built again.
+func CreateIndex(c *ast.CreateIndex, tables map[string]*md.Relation, execution *physical.Execution) (int, error) {
+	relation, err := preprocessor.FindRelation(c.Table, tables)
+	if err != nil {
+		return 0, err
+	}
+	if err := writable(relation, "index"); err != nil {
+		return 0, err
+	}
+	column := ""
+	for _, col := range relation.Columns {
+		if col.Name == c.Column.Name || (!c.Column.Quoted && strings.EqualFold(col.Name, c.Column.Name)) {
+			column = col.Name
+			break
+		}
+	}
+	if column == "" {
+		return 0, fmt.Errorf("table %q has no column %q", relation.Name, c.Column.Name)
+	}
+	if i, err := index.Open(index.Path(relation.Source, c.Name)); err == nil {
+		i.Source = relation.Source
+		if i.Fresh() {
+			return 0, fmt.Errorf("index %q already exists on %q", c.Name, relation.Name)
+		}
+	}
+
+	info, err := os.Stat(relation.Source)
+	if err != nil {
+		return 0, err
+	}
+	rr, err := physical.NewDialectTableScan(relation.Name, relation.Source, relation.Dialect)
+	if err != nil {
+		return 0, err
+	}
+	scan := rr.(physical.Seeker)
+	n := 0
+	for i, col := range scan.Columns() {
+		if col.Name == column {
+			n = i
+		}
+	}
+	if err := scan.Open(); err != nil {
+		return 0, err
+	}
+	defer scan.Close()
+
+	builder := index.NewBuilder(column)
+	var held int64
+	defer func() { execution.Release(held) }()
+	for {
+		row, err := scan.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return 0, err
+		}
+		if err := execution.Scanned(); err != nil {
+			return 0, err
+		}
+		key := ""
+		if n < len(row) {
+			key = row[n]
+		}
+		size := int64(len(key) + 32)
+		held += size
+		if err := execution.Allocate(size); err != nil {
+			return 0, err
+		}
+		builder.Add(key, scan.Offset())
+	}
+	i, err := builder.Write(relation.Source, c.Name, info)
+	if err != nil {
+		return 0, err
+	}
+	return int(i.Rows), nil
+}
+
+// Insert appends the results of a query to the CSV file of a table, and
+// returns the number of rows appended.
+func Insert(i *ast.Insert, tables map[string]*md.Relation, execution *physical.Execution) (int, error) {
+	relation, err := preprocessor.FindRelation(i.Table, tables)
+	if err != nil {
+		return 0, err
+	}
+	if err := writable(relation, "insert into"); err != nil {
+		return 0, err
+	}
+
+	rowReader, err := plan(i.Query, tables, execution)
+	if err != nil {
+		return 0, err
+	}
+	defer rowReader.Close()
+	if len(rowReader.Columns()) != len(relation.Columns) {
+		return 0, fmt.Errorf("table %q has %d columns, but the query has %d",
+			relation.Name, len(relation.Columns), len(rowReader.Columns()))
+	}
+
+	dialect := md.NewCsvDialect()
+	if relation.Dialect != nil {
+		d := *relation.Dialect
+		dialect = &d
+	}
+	dialect.Header = false
+
+	// Read every row before appending any, in case the query reads the table
+	// being appended to. The rows end their lines the way the file does.
+	style, err := csvfile.DetectStyle(relation.Source)
+	if err != nil {
+		return 0, err
+	}
+	style.BOM = false
+	var buf bytes.Buffer
+	count, err := formatter.NewStyledCsvWriter(rowReader, dialect, style).Write(&heldWriter{&buf, execution})
+	if err != nil {
+		return 0, err
+	}
+
+	f, err := os.OpenFile(relation.Source, os.O_RDWR|os.O_APPEND, 0)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	if err := endLine(f, style); err != nil {
+		return 0, err
+	}
+	if _, err := f.Write(buf.Bytes()); err != nil {
+		return 0, err
+	}
+	return count, f.Close()
+}
+
+// compressed checks whether a file is compressed, by its extension or, as it
+// is read, by its magic bytes. A file that can't be opened is left for the
+// change to report.
+func compressed(fileName string) bool {
+	f, err := decompress.OpenPlain(fileName)
+	if err != nil {
+		return false
+	}
+	if f == nil {
+		return true
+	}
+	f.Close()
+	return false
+}
+
+// writable checks that a relation is a single plain CSV file, that can be
+// changed in place. verb describes the change for the error messages.
+func writable(relation *md.Relation, verb string) error {
+	switch {
+	case relation.Type == md.VirtualType:
+		return fmt.Errorf("can not %s system table %q", verb, relation.Name)
+	case relation.Type != md.CsvType:
+		return fmt.Errorf("can only %s CSV tables, not %q", verb, relation.Source)
+	case csvfile.IsGlob(relation.Source), relation.Source == spool.StdinName:
+		return fmt.Errorf("can not %s %q", verb, relation.Source)
+	case compressed(relation.Source):
+		return fmt.Errorf("can not %s compressed file %q", verb, relation.Source)
+	case relation.Dialect != nil && relation.Dialect.Encoding != md.Utf8Encoding:
+		return fmt.Errorf("can only %s UTF-8 files, not %q", verb, relation.Source)
+	}
+	return nil
+}
+
+// Delete removes the rows of a table that match the condition, and returns
+// the number of rows removed. With dryRun the rows are counted, but the
+// table is left as it is.
+func Delete(d *ast.Delete, tables map[string]*md.Relation, execution *physical.Execution, dryRun bool) (int, error) {
+	return rewrite(d, d.Table, "delete from", tables, execution, dryRun)
+}
+
+// Update sets columns in the rows of a table that match the condition, and
+// returns the number of rows changed. With dryRun the rows are counted, but
+// the table is left as it is.
+func Update(u *ast.Update, tables map[string]*md.Relation, execution *physical.Execution, dryRun bool) (int, error) {
+	return rewrite(u, u.Table, "update", tables, execution, dryRun)
+}
+
+// rewrite streams every row of a table through a DELETE or UPDATE, and
+// replaces the file of the table with the result.
+func rewrite(q ast.Query, table *ast.Relation, verb string, tables map[string]*md.Relation, execution *physical.Execution, dryRun bool) (int, error) {
+	relation, err := preprocessor.FindRelation(table, tables)
+	if err != nil {
+		return 0, err
+	}
+	if err := writable(relation, verb); err != nil {
+		return 0, err
+	}
+	if relation.Dialect != nil && relation.Dialect.Comment != 0 {
+		return 0, fmt.Errorf("can not %s %q, its comment lines would be lost", verb, relation.Source)
+	}
+
+	queryLogical, err := preprocessor.Convert(q, tables)
+	if err != nil {
+		return 0, err
+	}
+	rowReader, err := physical.ConvertWith(queryLogical, tables, execution)
+	if err != nil {
+		return 0, err
+	}
+	defer rowReader.Close()
+	modification, ok := rowReader.(physical.Modification)
+	if !ok {
+		return 0, fmt.Errorf("unable to %s %q", verb, relation.Name)
+	}
+
+	// The file keeps its line endings and byte order mark, so only the rows
+	// that changed show up in a diff.
+	style, err := csvfile.DetectStyle(relation.Source)
+	if err != nil {
+		return 0, err
+	}
+	writer := formatter.NewStyledCsvWriter(modification, relation.Dialect, style)
+	if dryRun {
+		_, err := writer.Write(ioutil.Discard)
+		return modification.Affected(), err
+	}
+	info, err := os.Stat(relation.Source)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := writeFile(relation.Source, writer, info.Mode()); err != nil {
+		return 0, err
+	}
+	return modification.Affected(), nil
+}
+
+// endLine adds a line break, in the style of the file, to the end of a file
+// that doesn't have one, so appended rows start on a line of their own.
+func endLine(f *os.File, style *csvfile.Style) error {
+	info, err := f.Stat()
+	if err != nil || info.Size() == 0 {
+		return err
+	}
+	last := make([]byte, 1)
+	if _, err := f.ReadAt(last, info.Size()-1); err != nil {
+		return err
+	}
+	if last[0] == '\n' {
+		return nil
+	}
+	if style.CRLF {
+		_, err = f.Write([]byte("\r\n"))
+	} else {
+		_, err = f.Write([]byte("\n"))
+	}
+	return err
+}
+
+func plan(q *ast.SFW, tables map[string]*md.Relation, execution *physical.Execution) (physical.RowReader, error) {
+	queryLogical, err := preprocessor.Convert(q, tables)
+	if err != nil {
+		return nil, err
+	}
+	return physical.ConvertWith(queryLogical, tables, execution)
+}
+
+// heldWriter counts what is written to a buffer against the memory limit of
+// the execution.
+type heldWriter struct {
+	buf       *bytes.Buffer
+	execution *physical.Execution
+}
+
+func (w *heldWriter) Write(p []byte) (int, error) {
+	if err := w.execution.Allocate(int64(len(p))); err != nil {
+		return 0, err
+	}
+	return w.buf.Write(p)
+}
+
+// writeFile writes a file through a temporary file in the same directory,
+// which is synced and renamed into place once it is complete. That way a
+// partial file is never left behind, and the query can read the file it is
+// replacing.
+func writeFile(path string, writer formatter.Writer, mode os.FileMode) (int, error) {
+	if decompress.CodecForName(path) != decompress.None {
+		return 0, fmt.Errorf("writing compressed files is not supported: %q", path)
+	}
+	f, err := ioutil.TempFile(filepath.Dir(path), "."+filepath.Base(path)+".")
+	if err != nil {
+		return 0, err
+	}
+	defer os.Remove(f.Name())
+
+	count, err := writer.Write(f)
+	if err == nil {
+		err = f.Sync()
+	}
+	if err != nil {
+		f.Close()
+		return 0, err
+	}
+	if err := f.Close(); err != nil {
+		return 0, err
+	}
+	if err := os.Chmod(f.Name(), mode); err != nil {
+		return 0, err
+	}
+	return count, os.Rename(f.Name(), path)
+}
@@ -0,0 +1,504 @@
+package statement
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/jacobsimpson/mtsql/ast"
+	"github.com/jacobsimpson/mtsql/cache"
+	"github.com/jacobsimpson/mtsql/index"
+	"github.com/jacobsimpson/mtsql/lexer"
+	md "github.com/jacobsimpson/mtsql/metadata"
+	"github.com/jacobsimpson/mtsql/parser"
+	"github.com/jacobsimpson/mtsql/physical"
+	"github.com/jacobsimpson/mtsql/preprocessor"
+	"github.com/stretchr/testify/assert"
+)
+
+// inTempDir runs a test in a new working directory containing a small
+// people.csv table.
+func inTempDir(t *testing.T, test func()) {
+	dir, err := ioutil.TempDir("", "mtsql-statement-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	if err := ioutil.WriteFile("people.csv", []byte("name,age\nann,31\nbob,42"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	test()
+}
+
+func parse(t *testing.T, query string) ast.Query {
+	q, err := parser.Parse(lexer.NewFilterWhitespace(strings.NewReader(query)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return q
+}
+
+func readRows(t *testing.T, rowReader physical.RowReader) [][]string {
+	if err := rowReader.Open(); err != nil {
+		t.Fatal(err)
+	}
+	defer rowReader.Close()
+	rows := [][]string{}
+	for {
+		row, err := rowReader.Read()
+		if err == io.EOF {
+			return rows
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		rows = append(rows, row)
+	}
+}
+
+func readFile(t *testing.T, name string) string {
+	b, err := ioutil.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func TestCopy(t *testing.T) {
+	tests := []struct {
+		query    string
+		file     string
+		expected string
+	}{
+		{
+			"COPY (SELECT name FROM people) TO 'out.csv'",
+			"out.csv",
+			"name\nann\nbob\n",
+		},
+		{
+			"COPY (SELECT name, age FROM people) TO 'out.txt' WITH (format csv, delimiter '|', header false)",
+			"out.txt",
+			"ann|31\nbob|42\n",
+		},
+		{
+			"COPY (SELECT name FROM people) TO 'out.ndjson'",
+			"out.ndjson",
+			"{\"name\":\"ann\"}\n{\"name\":\"bob\"}\n",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.query, func(t *testing.T) {
+			inTempDir(t, func() {
+				assert := assert.New(t)
+
+				count, err := Copy(parse(t, test.query).(*ast.Copy), map[string]*md.Relation{}, nil)
+
+				assert.Nil(err)
+				assert.Equal(2, count)
+				assert.Equal(test.expected, readFile(t, test.file))
+			})
+		})
+	}
+}
+
+func TestCopyErrors(t *testing.T) {
+	tests := []struct {
+		query string
+		err   string
+	}{
+		{"COPY (SELECT name FROM people) TO 'out.parquet'", "COPY can not write parquet files"},
+		{"COPY (SELECT name FROM people) TO 'out.csv.gz'", `writing compressed files is not supported: "out.csv.gz"`},
+		{"COPY (SELECT name FROM people) TO 'out' WITH (format xml)", "unsupported format xml"},
+		{"COPY (SELECT name FROM people) TO 'out.json' WITH (header true)", `unknown option "header"`},
+	}
+	for _, test := range tests {
+		t.Run(test.query, func(t *testing.T) {
+			inTempDir(t, func() {
+				assert := assert.New(t)
+
+				_, err := Copy(parse(t, test.query).(*ast.Copy), map[string]*md.Relation{}, nil)
+
+				if assert.Error(err) {
+					assert.Equal(test.err, err.Error())
+				}
+			})
+		})
+	}
+}
+
+func TestCreateTableAs(t *testing.T) {
+	inTempDir(t, func() {
+		assert := assert.New(t)
+		tables := map[string]*md.Relation{}
+
+		count, err := CreateTableAs(parse(t, "CREATE TABLE adults AS SELECT name FROM people WHERE name = 'bob'").(*ast.CreateTableAs), tables, nil)
+
+		assert.Nil(err)
+		assert.Equal(1, count)
+		assert.Equal("name\nbob\n", readFile(t, "adults.csv"))
+		if assert.Contains(tables, "adults") {
+			assert.Equal("adults.csv", tables["adults"].Source)
+			assert.Equal([]*md.Column{{Qualifier: "adults", Name: "name"}}, tables["adults"].Columns)
+		}
+
+		_, err = CreateTableAs(parse(t, "CREATE TABLE people AS SELECT name FROM people").(*ast.CreateTableAs), tables, nil)
+		assert.EqualError(err, `table "people" already exists`)
+	})
+}
+
+func TestCreateIndex(t *testing.T) {
+	inTempDir(t, func() {
+		assert := assert.New(t)
+		tables := map[string]*md.Relation{}
+
+		count, err := CreateIndex(parse(t, "CREATE INDEX by_age ON people (AGE)").(*ast.CreateIndex), tables, nil)
+
+		assert.Nil(err)
+		assert.Equal(2, count)
+		indexes, err := index.List("people.csv")
+		assert.Nil(err)
+		if assert.Len(indexes, 1) {
+			assert.Equal("by_age", indexes[0].Name)
+			assert.Equal("age", indexes[0].Column)
+			assert.True(indexes[0].Numeric)
+		}
+
+		_, err = CreateIndex(parse(t, "CREATE INDEX by_age ON people (age)").(*ast.CreateIndex), tables, nil)
+		assert.EqualError(err, `index "by_age" already exists on "people"`)
+		_, err = CreateIndex(parse(t, "CREATE INDEX by_x ON people (x)").(*ast.CreateIndex), tables, nil)
+		assert.EqualError(err, `table "people" has no column "x"`)
+	})
+}
+
+func TestShowTables(t *testing.T) {
+	inTempDir(t, func() {
+		assert := assert.New(t)
+		assert.Nil(ioutil.WriteFile("events.ndjson", []byte("{}\n"), 0644))
+		tables := map[string]*md.Relation{
+			"wa": {Name: "wa", Type: md.CsvType, Source: "data/wa.csv"},
+		}
+
+		rowReader, err := ShowTables(tables, "")
+
+		assert.Nil(err)
+		assert.Equal([][]string{
+			{"events", "ndjson", "events.ndjson"},
+			{"mtsql_columns", "virtual", ""},
+			{"mtsql_indexes", "virtual", ""},
+			{"mtsql_settings", "virtual", ""},
+			{"mtsql_tables", "virtual", ""},
+			{"people", "csv", "people.csv"},
+			{"wa", "csv", "data/wa.csv"},
+		}, readRows(t, rowReader))
+	})
+}
+
+func TestShowTablesShadowingSystemTable(t *testing.T) {
+	inTempDir(t, func() {
+		assert := assert.New(t)
+		assert.Nil(ioutil.WriteFile("mtsql_indexes.csv", []byte("id\n1\n"), 0644))
+		tables := map[string]*md.Relation{}
+
+		rowReader, err := ShowTables(tables, "")
+		assert.Nil(err)
+		shown := readRows(t, rowReader)
+		assert.Contains(shown, []string{"mtsql_indexes", "csv", "mtsql_indexes.csv"})
+		assert.NotContains(shown, []string{"mtsql_indexes", "virtual", ""})
+
+		// The mtsql_tables system table lists the same tables.
+		o, err := preprocessor.Convert(parse(t, "SELECT name, type, source FROM mtsql_tables"), tables)
+		if !assert.Nil(err) {
+			return
+		}
+		rowReader, err = physical.Convert(o, tables)
+		if !assert.Nil(err) {
+			return
+		}
+		assert.Equal(shown, readRows(t, rowReader))
+	})
+}
+
+func TestDescribe(t *testing.T) {
+	inTempDir(t, func() {
+		assert := assert.New(t)
+		c := cache.New("cache")
+
+		rowReader, err := Describe(parse(t, "DESCRIBE people").(*ast.Describe), map[string]*md.Relation{}, c, nil)
+
+		assert.Nil(err)
+		assert.Equal([][]string{
+			{"name", "string", "0", "ann, bob"},
+			{"age", "integer", "0", "31, 42"},
+		}, readRows(t, rowReader))
+		stats, err := c.Stats()
+		assert.Nil(err)
+		assert.Equal(1, stats.Schemas)
+	})
+}
+
+func TestShowCreateTable(t *testing.T) {
+	inTempDir(t, func() {
+		assert := assert.New(t)
+
+		create, err := ShowCreateTable(parse(t, "SHOW CREATE TABLE read_csv('people.csv', delim=';')").(*ast.ShowCreateTable), map[string]*md.Relation{}, nil, nil)
+
+		assert.Nil(err)
+		assert.Equal(`CREATE TABLE people (
+    "name,age" string
+) WITH (path 'people.csv', format csv, delimiter ';', quote '"', header true, encoding 'utf-8');`, create)
+
+		_, err = ShowCreateTable(parse(t, "SHOW CREATE TABLE mtsql_tables").(*ast.ShowCreateTable), map[string]*md.Relation{}, nil, nil)
+		assert.EqualError(err, "mtsql_tables is a system table, it isn't read from a file")
+	})
+}
+
+func TestInsert(t *testing.T) {
+	inTempDir(t, func() {
+		assert := assert.New(t)
+		tables := map[string]*md.Relation{}
+
+		count, err := Insert(parse(t, "INSERT INTO people SELECT * FROM people").(*ast.Insert), tables, nil)
+
+		assert.Nil(err)
+		assert.Equal(2, count)
+		assert.Equal("name,age\nann,31\nbob,42\nann,31\nbob,42\n", readFile(t, "people.csv"))
+
+		_, err = Insert(parse(t, "INSERT INTO people SELECT name FROM people").(*ast.Insert), tables, nil)
+		assert.EqualError(err, `table "people" has 2 columns, but the query has 1`)
+	})
+}
+
+func TestDelete(t *testing.T) {
+	inTempDir(t, func() {
+		assert := assert.New(t)
+
+		count, err := Delete(parse(t, "DELETE FROM people WHERE name = 'ann'").(*ast.Delete), map[string]*md.Relation{}, nil, false)
+
+		assert.Nil(err)
+		assert.Equal(1, count)
+		assert.Equal("name,age\nbob,42\n", readFile(t, "people.csv"))
+	})
+}
+
+func TestUpdate(t *testing.T) {
+	inTempDir(t, func() {
+		assert := assert.New(t)
+
+		count, err := Update(parse(t, "UPDATE people SET name = UPPER(name), age = 0 WHERE age = 42").(*ast.Update), map[string]*md.Relation{}, nil, false)
+
+		assert.Nil(err)
+		assert.Equal(1, count)
+		assert.Equal("name,age\nann,31\nBOB,0\n", readFile(t, "people.csv"))
+	})
+}
+
+func TestDryRun(t *testing.T) {
+	inTempDir(t, func() {
+		assert := assert.New(t)
+
+		count, err := Delete(parse(t, "DELETE FROM people").(*ast.Delete), map[string]*md.Relation{}, nil, true)
+
+		assert.Nil(err)
+		assert.Equal(2, count)
+		assert.Equal("name,age\nann,31\nbob,42", readFile(t, "people.csv"))
+	})
+}
+
+func TestRewriteKeepsStyle(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    string
+		input    string
+		expected string
+	}{
+		{
+			name:     "CRLF line endings",
+			query:    "UPDATE people SET age = 0 WHERE name = 'bob'",
+			input:    "name,age\r\nann,31\r\nbob,42\r\n",
+			expected: "name,age\r\nann,31\r\nbob,0\r\n",
+		},
+		{
+			name:     "byte order mark",
+			query:    "DELETE FROM people WHERE name = 'ann'",
+			input:    "\xef\xbb\xbfname,age\nann,31\nbob,42\n",
+			expected: "\xef\xbb\xbfname,age\nbob,42\n",
+		},
+		{
+			name:     "both",
+			query:    "UPDATE people SET name = UPPER(name)",
+			input:    "\xef\xbb\xbfname,age\r\nann,31\r\nbob,42",
+			expected: "\xef\xbb\xbfname,age\r\nANN,31\r\nBOB,42\r\n",
+		},
+		{
+			name:     "appended rows",
+			query:    "INSERT INTO people SELECT name, age FROM people WHERE name = 'ann'",
+			input:    "\xef\xbb\xbfname,age\r\nann,31\r\nbob,42",
+			expected: "\xef\xbb\xbfname,age\r\nann,31\r\nbob,42\r\nann,31\r\n",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			inTempDir(t, func() {
+				assert := assert.New(t)
+				if err := ioutil.WriteFile("people.csv", []byte(test.input), 0644); err != nil {
+					t.Fatal(err)
+				}
+				tables := map[string]*md.Relation{}
+
+				var err error
+				switch q := parse(t, test.query).(type) {
+				case *ast.Update:
+					_, err = Update(q, tables, nil, false)
+				case *ast.Delete:
+					_, err = Delete(q, tables, nil, false)
+				case *ast.Insert:
+					_, err = Insert(q, tables, nil)
+				}
+
+				assert.Nil(err)
+				assert.Equal(test.expected, readFile(t, "people.csv"))
+			})
+		})
+	}
+}
+
+func TestRewriteKeepsDialectAndMode(t *testing.T) {
+	inTempDir(t, func() {
+		assert := assert.New(t)
+		if err := ioutil.WriteFile("export.txt", []byte("ann|31\nbob|42\n"), 0600); err != nil {
+			t.Fatal(err)
+		}
+		dialect := md.NewCsvDialect()
+		dialect.Delimiter = '|'
+		dialect.Header = false
+		tables := map[string]*md.Relation{
+			"export": {
+				Name:    "export",
+				Type:    md.CsvType,
+				Source:  "export.txt",
+				Dialect: dialect,
+				Columns: []*md.Column{{Qualifier: "export", Name: "c1"}, {Qualifier: "export", Name: "c2"}},
+			},
+		}
+
+		count, err := Delete(parse(t, "DELETE FROM export WHERE c1 = 'ann'").(*ast.Delete), tables, nil, false)
+
+		assert.Nil(err)
+		assert.Equal(1, count)
+		assert.Equal("bob|42\n", readFile(t, "export.txt"))
+		info, err := os.Stat("export.txt")
+		if assert.Nil(err) {
+			assert.Equal(os.FileMode(0600), info.Mode())
+		}
+	})
+}
+
+func TestRewriteWithCustomQuote(t *testing.T) {
+	inTempDir(t, func() {
+		assert := assert.New(t)
+		if err := ioutil.WriteFile("export.txt", []byte("name;note\n'ann';'it''s; \"ok\"'\nbob;x\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		dialect := md.NewCsvDialect()
+		dialect.Delimiter = ';'
+		dialect.Quote = '\''
+		tables := map[string]*md.Relation{
+			"export": {
+				Name:    "export",
+				Type:    md.CsvType,
+				Source:  "export.txt",
+				Dialect: dialect,
+				Columns: []*md.Column{{Qualifier: "export", Name: "name"}, {Qualifier: "export", Name: "note"}},
+			},
+		}
+
+		count, err := Update(parse(t, "UPDATE export SET note = UPPER(note) WHERE name = 'bob'").(*ast.Update), tables, nil, false)
+
+		assert.Nil(err)
+		assert.Equal(1, count)
+		assert.Equal("name;note\nann;'it''s; \"ok\"'\nbob;X\n", readFile(t, "export.txt"))
+		rowReader, err := physical.NewDialectTableScan("export", "export.txt", dialect)
+		assert.Nil(err)
+		assert.Equal([][]string{{"ann", `it's; "ok"`}, {"bob", "X"}}, readRows(t, rowReader))
+	})
+}
+
+func TestRewriteWithComments(t *testing.T) {
+	inTempDir(t, func() {
+		assert := assert.New(t)
+		dialect := md.NewCsvDialect()
+		dialect.Comment = '#'
+		tables := map[string]*md.Relation{
+			"people": {Name: "people", Type: md.CsvType, Source: "people.csv", Dialect: dialect},
+		}
+
+		_, err := Delete(parse(t, "DELETE FROM people").(*ast.Delete), tables, nil, false)
+
+		if assert.Error(err) {
+			assert.Equal(`can not delete from "people.csv", its comment lines would be lost`, err.Error())
+		}
+		assert.Equal("name,age\nann,31\nbob,42", readFile(t, "people.csv"))
+	})
+}
+
+func TestWriteCompressedWithCsvName(t *testing.T) {
+	inTempDir(t, func() {
+		assert := assert.New(t)
+		var b bytes.Buffer
+		w := gzip.NewWriter(&b)
+		w.Write([]byte("name,age\ncat,7\n"))
+		w.Close()
+		if err := ioutil.WriteFile("packed.csv", b.Bytes(), 0644); err != nil {
+			t.Fatal(err)
+		}
+		tables := map[string]*md.Relation{}
+
+		_, err := Insert(parse(t, "INSERT INTO packed SELECT * FROM people").(*ast.Insert), tables, nil)
+		assert.EqualError(err, `can not insert into compressed file "packed.csv"`)
+		_, err = Delete(parse(t, "DELETE FROM packed").(*ast.Delete), tables, nil, false)
+		assert.EqualError(err, `can not delete from compressed file "packed.csv"`)
+		_, err = Update(parse(t, "UPDATE packed SET age = '8'").(*ast.Update), tables, nil, false)
+		assert.EqualError(err, `can not update compressed file "packed.csv"`)
+
+		assert.Equal(b.String(), readFile(t, "packed.csv"))
+	})
+}
+
+func TestDeleteErrors(t *testing.T) {
+	tests := []struct {
+		query string
+		err   string
+	}{
+		{"DELETE FROM people WHERE height = 1", `no matching name "height"`},
+		{"DELETE FROM 'people.ndjson'", `can only delete from CSV tables, not "people.ndjson"`},
+	}
+	for _, test := range tests {
+		t.Run(test.query, func(t *testing.T) {
+			inTempDir(t, func() {
+				assert := assert.New(t)
+				if err := ioutil.WriteFile("people.ndjson", []byte(`{"name":"ann"}`), 0644); err != nil {
+					t.Fatal(err)
+				}
+
+				_, err := Delete(parse(t, test.query).(*ast.Delete), map[string]*md.Relation{}, nil, false)
+
+				if assert.Error(err) {
+					assert.Equal(test.err, err.Error())
+				}
+			})
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package suggest
+
+import (
+	"sort"
+	"strings"
+)
+
+// Closest returns the candidate that word is most likely a misspelling of,
+// ignoring case, or "" if none of them are close enough.
+func Closest(word string, candidates []string) string {
+	if nearest := Nearest(word, candidates, 1); len(nearest) > 0 {
+		return nearest[0]
+	}
+	return ""
+}
+
+// Nearest returns up to n of the candidates that word could be a misspelling
+// of, ignoring case, closest first.
+func Nearest(word string, candidates []string, n int) []string {
+	limit := len([]rune(word)) / 3
+	if limit < 1 {
+		limit = 1
+	}
+	type match struct {
+		candidate string
+		distance  int
+	}
+	matches := []match{}
+	for _, c := range candidates {
+		if d := Distance(strings.ToLower(word), strings.ToLower(c)); d <= limit {
+			matches = append(matches, match{c, d})
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].distance < matches[j].distance
+	})
+	result := []string{}
+	for i := 0; i < len(matches) && i < n; i++ {
+		result = append(result, matches[i].candidate)
+	}
+	return result
+}
+
+// Distance is the number of single character insertions, deletions,
+// substitutions and transpositions of adjacent characters it takes to turn
+// one string into the other.
+func Distance(a, b string) int {
+	s, t := []rune(a), []rune(b)
+	d := make([][]int, len(s)+1)
+	for i := range d {
+		d[i] = make([]int, len(t)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+	for i := 1; i <= len(s); i++ {
+		for j := 1; j <= len(t); j++ {
+			cost := 1
+			if s[i-1] == t[j-1] {
+				cost = 0
+			}
+			d[i][j] = min(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+cost)
+			if i > 1 && j > 1 && s[i-1] == t[j-2] && s[i-2] == t[j-1] {
+				d[i][j] = min(d[i][j], d[i-2][j-2]+1)
+			}
+		}
+	}
+	return d[len(s)][len(t)]
+}
+
+func min(first int, rest ...int) int {
+	result := first
+	for _, v := range rest {
+		if v < result {
+			result = v
+		}
+	}
+	return result
+}
@@ -0,0 +1,55 @@
+package suggest_test
+
+import (
+	"testing"
+
+	"github.com/jacobsimpson/mtsql/suggest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDistance(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		distance int
+	}{
+		{"", "", 0},
+		{"abc", "abc", 0},
+		{"", "abc", 3},
+		{"City", "Cty", 1},
+		{"FROM", "FORM", 1},
+		{"kitten", "sitting", 3},
+	}
+	for _, test := range tests {
+		t.Run(test.a+"/"+test.b, func(t *testing.T) {
+			assert.Equal(t, test.distance, suggest.Distance(test.a, test.b))
+		})
+	}
+}
+
+func TestClosest(t *testing.T) {
+	tests := []struct {
+		word       string
+		candidates []string
+		expected   string
+	}{
+		{"SELEC", []string{"SELECT", "PROFILE"}, "SELECT"},
+		{"wher", []string{"INNER", "WHERE", "ORDER"}, "WHERE"},
+		{"state", []string{"City", "State"}, "State"},
+		{"Population", []string{"City", "State"}, ""},
+		{"a", []string{}, ""},
+	}
+	for _, test := range tests {
+		t.Run(test.word, func(t *testing.T) {
+			assert.Equal(t, test.expected, suggest.Closest(test.word, test.candidates))
+		})
+	}
+}
+
+func TestNearest(t *testing.T) {
+	assert := assert.New(t)
+
+	candidates := []string{"City", "Stats", "Status", "Lat", "State"}
+
+	assert.Equal([]string{"Stats", "Status", "State"}, suggest.Nearest("stats", candidates, 3))
+	assert.Equal([]string{"Stats"}, suggest.Nearest("stats", candidates, 1))
+}